@@ -0,0 +1,222 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maful/hij/config"
+)
+
+const fileName = "audit.jsonl"
+
+// Action is the kind of operation an entry records
+type Action string
+
+const (
+	ActionDelete        Action = "delete"
+	ActionDeletePackage Action = "delete-package"
+	ActionRestore       Action = "restore"
+)
+
+// Result is the outcome of an audited operation
+type Result string
+
+const (
+	ResultSuccess Result = "success"
+	ResultFailure Result = "failure"
+)
+
+// Entry is a single line of the audit log
+type Entry struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Action      Action    `json:"action"`
+	Actor       string    `json:"actor"`
+	Owner       string    `json:"owner"`
+	PackageType string    `json:"package_type"`
+	Package     string    `json:"package"`
+	VersionID   int       `json:"version_id"`
+	Digest      string    `json:"digest"`
+	Tags        []string  `json:"tags"`
+	Archive     string    `json:"archive,omitempty"`
+	Result      Result    `json:"result"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// NewEntry builds an entry for an attempt, deriving the result from err
+func NewEntry(action Action, err error) Entry {
+	e := Entry{
+		Timestamp: time.Now().UTC(),
+		Action:    action,
+		Result:    ResultSuccess,
+	}
+	if err != nil {
+		e.Result = ResultFailure
+		e.Error = err.Error()
+	}
+	return e
+}
+
+// Log is an append-only JSONL audit log on disk
+type Log struct {
+	path string
+}
+
+// DefaultPath returns the audit log location under the XDG state directory
+func DefaultPath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// Open returns a log backed by the file at path. The file is created on the
+// first Append.
+func Open(path string) *Log {
+	return &Log{path: path}
+}
+
+// OpenDefault returns the log at DefaultPath
+func OpenDefault() (*Log, error) {
+	path, err := DefaultPath()
+	if err != nil {
+		return nil, err
+	}
+	return Open(path), nil
+}
+
+// Path returns the file backing the log
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Append writes e as a new line at the end of the log
+func (l *Log) Append(e Entry) error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return err
+	}
+
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read returns every entry in the log, oldest first. A missing log file is
+// not an error and yields no entries.
+func (l *Log) Read() ([]Entry, error) {
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", l.path, n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Query selects log entries. Zero-valued fields match everything.
+type Query struct {
+	Package string
+	Since   time.Time // inclusive
+	Until   time.Time // exclusive
+	Result  Result
+}
+
+// Match reports whether e satisfies the query
+func (q Query) Match(e Entry) bool {
+	if q.Package != "" && e.Package != q.Package {
+		return false
+	}
+	if !q.Since.IsZero() && e.Timestamp.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Timestamp.Before(q.Until) {
+		return false
+	}
+	if q.Result != "" && e.Result != q.Result {
+		return false
+	}
+	return true
+}
+
+// Filter returns the entries matching q, preserving order
+func Filter(entries []Entry, q Query) []Entry {
+	var matched []Entry
+	for _, e := range entries {
+		if q.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+	return matched
+}
+
+var csvHeader = []string{
+	"timestamp", "action", "actor", "owner", "package_type", "package",
+	"version_id", "digest", "tags", "archive", "result", "error",
+}
+
+// WriteCSV writes entries as CSV with a header row. Tags are joined with
+// spaces so each entry stays on a single record.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{
+			e.Timestamp.Format(time.RFC3339),
+			string(e.Action),
+			e.Actor,
+			e.Owner,
+			e.PackageType,
+			e.Package,
+			strconv.Itoa(e.VersionID),
+			e.Digest,
+			strings.Join(e.Tags, " "),
+			e.Archive,
+			string(e.Result),
+			e.Error,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,120 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLog_AppendAndRead(t *testing.T) {
+	log := Open(filepath.Join(t.TempDir(), "nested", "audit.jsonl"))
+
+	first := NewEntry(ActionDelete, nil)
+	first.Package = "app"
+	first.VersionID = 1
+	first.Tags = []string{"v1.0", "latest"}
+
+	second := NewEntry(ActionDelete, errors.New("resource not found"))
+	second.Package = "app"
+	second.VersionID = 2
+
+	for _, e := range []Entry{first, second} {
+		if err := log.Append(e); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+	}
+
+	entries, err := log.Read()
+	if err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("entry count = %d, want 2", len(entries))
+	}
+	if entries[0].Result != ResultSuccess || entries[0].Error != "" {
+		t.Errorf("first entry = %+v, want success without error", entries[0])
+	}
+	if entries[1].Result != ResultFailure || entries[1].Error != "resource not found" {
+		t.Errorf("second entry = %+v, want failure with error", entries[1])
+	}
+	if len(entries[0].Tags) != 2 || entries[0].Tags[1] != "latest" {
+		t.Errorf("tags = %v, want [v1.0 latest]", entries[0].Tags)
+	}
+}
+
+func TestLog_ReadMissingFile(t *testing.T) {
+	log := Open(filepath.Join(t.TempDir(), "missing.jsonl"))
+
+	entries, err := log.Read()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("entry count = %d, want 0", len(entries))
+	}
+}
+
+func TestFilter(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 12, 0, 0, 0, time.UTC) }
+	entries := []Entry{
+		{Timestamp: day(1), Package: "app", Result: ResultSuccess},
+		{Timestamp: day(2), Package: "web", Result: ResultFailure},
+		{Timestamp: day(3), Package: "app", Result: ResultFailure},
+	}
+
+	tests := []struct {
+		name      string
+		query     Query
+		wantCount int
+	}{
+		{name: "empty query matches all", query: Query{}, wantCount: 3},
+		{name: "by package", query: Query{Package: "app"}, wantCount: 2},
+		{name: "by result", query: Query{Result: ResultFailure}, wantCount: 2},
+		{name: "since is inclusive", query: Query{Since: day(2)}, wantCount: 2},
+		{name: "until is exclusive", query: Query{Until: day(2)}, wantCount: 1},
+		{name: "combined", query: Query{Package: "app", Result: ResultFailure, Since: day(2)}, wantCount: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Filter(entries, tt.query); len(got) != tt.wantCount {
+				t.Errorf("match count = %d, want %d", len(got), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	entries := []Entry{{
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Action:    ActionDelete,
+		Actor:     "octocat",
+		Owner:     "octocat",
+		Package:   "app",
+		VersionID: 42,
+		Digest:    "sha256:abc",
+		Tags:      []string{"v1", "latest"},
+		Result:    ResultFailure,
+		Error:     "access denied, try again",
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, entries); err != nil {
+		t.Fatalf("WriteCSV() error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("line count = %d, want 2", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "timestamp,action,actor") {
+		t.Errorf("header = %q", lines[0])
+	}
+	want := `2024-01-02T03:04:05Z,delete,octocat,octocat,,app,42,sha256:abc,v1 latest,,failure,"access denied, try again"`
+	if lines[1] != want {
+		t.Errorf("record = %q, want %q", lines[1], want)
+	}
+}
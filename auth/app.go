@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+// NewAppClient builds a client that authenticates as the GitHub App
+// configured in cfg. The private key is read from app.private_key, or from
+// the keychain when no file is configured.
+func NewAppClient(cfg config.Config) (*github.Client, error) {
+	if cfg.App.ID == 0 || cfg.App.Org == "" {
+		return nil, fmt.Errorf("app.id and app.org must be set to authenticate as a GitHub App")
+	}
+
+	var data []byte
+	if path := cfg.AppKeyPath(); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read app private key: %w", err)
+		}
+		data = b
+	} else {
+		key, err := config.GetAppKey()
+		if err != nil {
+			return nil, fmt.Errorf("no app private key found. Set app.private_key or run hij app-key FILE: %w", err)
+		}
+		data = []byte(key)
+	}
+	key, err := github.ParsePrivateKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	client := github.NewAppClient(&github.AppAuth{
+		AppID:          cfg.App.ID,
+		Key:            key,
+		Org:            cfg.App.Org,
+		InstallationID: cfg.App.InstallationID,
+	})
+	client.SetBaseURL(cfg.APIURL())
+	client.SetTimeout(cfg.HTTPTimeout())
+	return client, nil
+}
@@ -0,0 +1,20 @@
+package auth
+
+import (
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+// NewTokenClient builds a client for the configured host and owner that
+// authenticates with token. Tokens of token_command are replaced by running
+// it again when the API rejects them.
+func NewTokenClient(cfg config.Config, token string) *github.Client {
+	client := github.NewClient(token)
+	client.SetBaseURL(cfg.APIURL())
+	client.SetOrg(cfg.Owner)
+	client.SetTimeout(cfg.HTTPTimeout())
+	if cfg.TokenCommand != "" {
+		client.SetRefresh(cfg.RefreshToken)
+	}
+	return client
+}
@@ -0,0 +1,164 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/maful/hij/config"
+)
+
+// DeviceFlow signs in with the OAuth device authorization grant: the user
+// enters a short code in the browser while hij polls for the token
+type DeviceFlow struct {
+	ClientID      string
+	Scopes        []string
+	DeviceCodeURL string
+	TokenURL      string
+	HTTPClient    *http.Client // nil for http.DefaultClient
+
+	second time.Duration // length of the intervals the server gives in seconds, shortened in tests
+}
+
+// DeviceCode is what the user needs to authorize hij in the browser
+type DeviceCode struct {
+	DeviceCode      string `json:"device_code"`
+	UserCode        string `json:"user_code"`
+	VerificationURI string `json:"verification_uri"`
+	ExpiresIn       int    `json:"expires_in"` // seconds
+	Interval        int    `json:"interval"`   // seconds between polls
+}
+
+// tokenResponse is the answer to a poll, either a token or an error code
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	Interval         int    `json:"interval"`
+}
+
+// Start requests a device and user code
+func (f *DeviceFlow) Start(ctx context.Context) (*DeviceCode, error) {
+	if f.ClientID == "" {
+		return nil, fmt.Errorf("oauth.client_id is not set, register an OAuth app with device flow enabled and configure its client ID")
+	}
+	form := url.Values{"client_id": {f.ClientID}, "scope": {strings.Join(f.Scopes, " ")}}
+	var code DeviceCode
+	if err := f.post(ctx, f.DeviceCodeURL, form, &code); err != nil {
+		return nil, fmt.Errorf("failed to start device login: %w", err)
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return nil, fmt.Errorf("failed to start device login: no device code in the response")
+	}
+	if code.Interval <= 0 {
+		code.Interval = 5
+	}
+	return &code, nil
+}
+
+// Poll waits until the user authorizes the code and returns the access
+// token. It gives up when the code expires, access is denied or ctx is done.
+func (f *DeviceFlow) Poll(ctx context.Context, code *DeviceCode) (string, error) {
+	second := f.second
+	if second == 0 {
+		second = time.Second
+	}
+	interval := time.Duration(code.Interval) * second
+	var deadline <-chan time.Time
+	if code.ExpiresIn > 0 {
+		timer := time.NewTimer(time.Duration(code.ExpiresIn) * second)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	form := url.Values{
+		"client_id":   {f.ClientID},
+		"device_code": {code.DeviceCode},
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-deadline:
+			return "", fmt.Errorf("the code expired before it was entered, try again")
+		case <-time.After(interval):
+		}
+
+		var res tokenResponse
+		if err := f.post(ctx, f.TokenURL, form, &res); err != nil {
+			return "", err
+		}
+		switch res.Error {
+		case "":
+			if res.AccessToken == "" {
+				return "", fmt.Errorf("no access token in the response")
+			}
+			return res.AccessToken, nil
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * second
+			if res.Interval > 0 {
+				interval = time.Duration(res.Interval) * second
+			}
+		case "expired_token":
+			return "", fmt.Errorf("the code expired before it was entered, try again")
+		case "access_denied":
+			return "", fmt.Errorf("authorization was denied")
+		default:
+			if res.ErrorDescription != "" {
+				return "", fmt.Errorf("%s: %s", res.Error, res.ErrorDescription)
+			}
+			return "", fmt.Errorf("device login failed: %s", res.Error)
+		}
+	}
+}
+
+// post sends form to endpoint and decodes the JSON answer into out
+func (f *DeviceFlow) post(ctx context.Context, endpoint string, form url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	client := f.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	// #nosec G704 -- The endpoints come from the user's own config.
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 {
+		var res tokenResponse
+		if json.Unmarshal(body, &res) == nil && res.Error != "" {
+			return fmt.Errorf("%s (status %d)", res.Error, resp.StatusCode)
+		}
+		return fmt.Errorf("status %d from %s", resp.StatusCode, endpoint)
+	}
+	return json.Unmarshal(body, out)
+}
+
+// NewDeviceFlow returns the device flow configured in cfg
+func NewDeviceFlow(cfg config.Config) *DeviceFlow {
+	return &DeviceFlow{
+		ClientID:      cfg.OAuth.ClientID,
+		Scopes:        cfg.OAuth.Scopes,
+		DeviceCodeURL: cfg.DeviceCodeURL(),
+		TokenURL:      cfg.TokenURL(),
+	}
+}
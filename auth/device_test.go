@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// standIn is a local stand-in for the OAuth endpoints that answers polls
+// with the given responses in turn
+func standIn(t *testing.T, polls ...map[string]any) (*DeviceFlow, *int) {
+	t.Helper()
+	count := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/device/code", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("client_id") != "client" || r.FormValue("scope") != "read:packages delete:packages" {
+			t.Errorf("device code request form = %v", r.Form)
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"device_code": "dev", "user_code": "ABCD-1234", "verification_uri": "https://github.com/login/device",
+			"expires_in": 900, "interval": 1,
+		})
+	})
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("device_code") != "dev" || r.Header.Get("Accept") != "application/json" {
+			t.Errorf("poll = %v, accept %q", r.Form, r.Header.Get("Accept"))
+		}
+		json.NewEncoder(w).Encode(polls[min(count, len(polls)-1)])
+		count++
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return &DeviceFlow{
+		ClientID:      "client",
+		Scopes:        []string{"read:packages", "delete:packages"},
+		DeviceCodeURL: server.URL + "/login/device/code",
+		TokenURL:      server.URL + "/login/oauth/access_token",
+		second:        time.Millisecond,
+	}, &count
+}
+
+func TestDeviceFlow(t *testing.T) {
+	f, polls := standIn(t,
+		map[string]any{"error": "authorization_pending"},
+		map[string]any{"error": "slow_down", "interval": 2},
+		map[string]any{"access_token": "gho_token", "token_type": "bearer"},
+	)
+
+	code, err := f.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	if code.UserCode != "ABCD-1234" || code.VerificationURI != "https://github.com/login/device" {
+		t.Errorf("code = %+v", code)
+	}
+
+	token, err := f.Poll(context.Background(), code)
+	if err != nil {
+		t.Fatalf("Poll() error: %v", err)
+	}
+	if token != "gho_token" || *polls != 3 {
+		t.Errorf("token = %q after %d polls, want gho_token after 3", token, *polls)
+	}
+}
+
+func TestDeviceFlow_Errors(t *testing.T) {
+	tests := []struct {
+		response map[string]any
+		want     string
+	}{
+		{response: map[string]any{"error": "access_denied"}, want: "denied"},
+		{response: map[string]any{"error": "expired_token"}, want: "expired"},
+		{response: map[string]any{"error": "device_flow_disabled", "error_description": "Device Flow must be explicitly enabled"}, want: "device_flow_disabled: Device Flow must be explicitly enabled"},
+	}
+	for _, tt := range tests {
+		f, _ := standIn(t, tt.response)
+		code, err := f.Start(context.Background())
+		if err != nil {
+			t.Fatalf("Start() error: %v", err)
+		}
+		if _, err := f.Poll(context.Background(), code); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Poll() error = %v, want containing %q", err, tt.want)
+		}
+	}
+}
+
+func TestDeviceFlow_Cancel(t *testing.T) {
+	f, _ := standIn(t, map[string]any{"error": "authorization_pending"})
+	code, err := f.Start(context.Background())
+	if err != nil {
+		t.Fatalf("Start() error: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := f.Poll(ctx, code); err != context.Canceled {
+		t.Errorf("Poll() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestDeviceFlow_NoClientID(t *testing.T) {
+	f := &DeviceFlow{}
+	if _, err := f.Start(context.Background()); err == nil || !strings.Contains(err.Error(), "client_id") {
+		t.Errorf("Start() error = %v, want a missing client_id error", err)
+	}
+}
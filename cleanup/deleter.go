@@ -0,0 +1,108 @@
+package cleanup
+
+import (
+	"fmt"
+
+	"github.com/maful/hij/audit"
+	"github.com/maful/hij/github"
+	"github.com/maful/hij/policy"
+	"github.com/maful/hij/registry"
+)
+
+// Deleter removes package versions one at a time. Every attempt is checked
+// against the protected tags and Blocked, optionally archived first, and
+// recorded in the audit log.
+type Deleter struct {
+	Client      *github.Client
+	Actor       string
+	Owner       string
+	PackageType string
+	Package     string
+	Protected   []policy.TagPattern                 // tags that are never deleted
+	Archiver    *registry.Archiver                  // nil to skip archiving
+	AuditLog    *audit.Log                          // nil to skip auditing
+	Blocked     func(v github.PackageVersion) error // explains why v must not be deleted, nil to allow all
+}
+
+// Result is the outcome of deleting a single version
+type Result struct {
+	Archive  string // where the version was archived, if it was
+	Err      error  // why the version was not deleted
+	AuditErr error  // set when the attempt could not be recorded
+}
+
+// Delete removes v unless it is blocked or could not be archived
+func (d *Deleter) Delete(v github.PackageVersion) Result {
+	var res Result
+	res.Err = d.delete(v, &res.Archive)
+	res.AuditErr = d.record(v, res.Archive, res.Err)
+	return res
+}
+
+func (d *Deleter) delete(v github.PackageVersion, archive *string) error {
+	if err := policy.CheckTags(d.Protected, v); err != nil {
+		return fmt.Errorf("%s not deleted: %w", v.Name, err)
+	}
+	if d.Blocked != nil {
+		if err := d.Blocked(v); err != nil {
+			return fmt.Errorf("%s not deleted: %w", v.Name, err)
+		}
+	}
+
+	if d.Archiver != nil {
+		path, err := d.Archiver.Archive(registry.Repository(d.Owner, d.Package), v.Name, v.Tags())
+		if err != nil {
+			// Never delete what we failed to archive
+			return fmt.Errorf("archiving %s failed, not deleted: %w", v.Name, err)
+		}
+		*archive = path
+	}
+
+	return d.Client.DeletePackageVersion(d.PackageType, d.Package, v.ID)
+}
+
+func (d *Deleter) record(v github.PackageVersion, archive string, err error) error {
+	if d.AuditLog == nil {
+		return nil
+	}
+	e := audit.NewEntry(audit.ActionDelete, err)
+	e.Actor = d.Actor
+	e.Owner = d.Owner
+	e.PackageType = d.PackageType
+	e.Package = d.Package
+	e.VersionID = v.ID
+	e.Digest = v.Name
+	e.Tags = v.Tags()
+	e.Archive = archive
+	return d.AuditLog.Append(e)
+}
+
+// DeletePackage removes the whole package unless one of its versions is
+// protected or blocked. Archiving is not supported for whole packages.
+func (d *Deleter) DeletePackage(versions []github.PackageVersion) Result {
+	var res Result
+	res.Err = d.deletePackage(versions)
+	if d.AuditLog != nil {
+		e := audit.NewEntry(audit.ActionDeletePackage, res.Err)
+		e.Actor = d.Actor
+		e.Owner = d.Owner
+		e.PackageType = d.PackageType
+		e.Package = d.Package
+		res.AuditErr = d.AuditLog.Append(e)
+	}
+	return res
+}
+
+func (d *Deleter) deletePackage(versions []github.PackageVersion) error {
+	for _, v := range versions {
+		if err := policy.CheckTags(d.Protected, v); err != nil {
+			return fmt.Errorf("%s not deleted, version %s: %w", d.Package, v.Name, err)
+		}
+		if d.Blocked != nil {
+			if err := d.Blocked(v); err != nil {
+				return fmt.Errorf("%s not deleted, version %s: %w", d.Package, v.Name, err)
+			}
+		}
+	}
+	return d.Client.DeletePackage(d.PackageType, d.Package)
+}
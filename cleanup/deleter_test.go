@@ -0,0 +1,136 @@
+package cleanup
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maful/hij/audit"
+	"github.com/maful/hij/github"
+	"github.com/maful/hij/policy"
+)
+
+func TestDeleter_Delete(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" {
+			t.Errorf("method = %q, want DELETE", r.Method)
+		}
+		deleted = append(deleted, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := github.NewClient("test-token")
+	client.SetBaseURL(server.URL)
+	log := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+
+	d := &Deleter{
+		Client:      client,
+		Actor:       "octo",
+		Owner:       "octo",
+		PackageType: "container",
+		Package:     "app",
+		AuditLog:    log,
+		Blocked: func(v github.PackageVersion) error {
+			if v.ID == 2 {
+				return errors.New("in use")
+			}
+			return nil
+		},
+	}
+
+	allowed := d.Delete(github.PackageVersion{ID: 1, Name: "sha256:aaa"})
+	if allowed.Err != nil || allowed.AuditErr != nil {
+		t.Errorf("Delete(1) = %+v, want success", allowed)
+	}
+	blocked := d.Delete(github.PackageVersion{ID: 2, Name: "sha256:bbb"})
+	if blocked.Err == nil || !strings.Contains(blocked.Err.Error(), "in use") {
+		t.Errorf("Delete(2) error = %v, want blocked", blocked.Err)
+	}
+
+	if len(deleted) != 1 || deleted[0] != "/user/packages/container/app/versions/1" {
+		t.Errorf("deleted = %v, want only version 1", deleted)
+	}
+
+	entries, err := log.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("audit entries = %d, want 2", len(entries))
+	}
+	if entries[0].Result != audit.ResultSuccess || entries[0].Digest != "sha256:aaa" || entries[0].Actor != "octo" {
+		t.Errorf("first entry = %+v", entries[0])
+	}
+	if entries[1].Result != audit.ResultFailure || entries[1].VersionID != 2 {
+		t.Errorf("second entry = %+v", entries[1])
+	}
+}
+
+func TestDeleter_DeleteProtectedTag(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("protected version must not reach the API, got %s %s", r.Method, r.URL.Path)
+	}))
+	defer server.Close()
+
+	client := github.NewClient("test-token")
+	client.SetBaseURL(server.URL)
+	protected, err := policy.CompileTagPatterns([]string{"prod-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Deleter{Client: client, PackageType: "container", Package: "app", Protected: protected}
+	v := github.PackageVersion{ID: 1, Name: "sha256:aaa"}
+	v.Metadata.Container.Tags = []string{"prod-eu"}
+
+	res := d.Delete(v)
+	if res.Err == nil || !strings.Contains(res.Err.Error(), `tag "prod-eu" is protected`) {
+		t.Errorf("Delete() error = %v, want protected tag rejection", res.Err)
+	}
+}
+
+func TestDeleter_DeletePackage(t *testing.T) {
+	var deleted []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deleted = append(deleted, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := github.NewClient("test-token")
+	client.SetBaseURL(server.URL)
+	log := audit.Open(filepath.Join(t.TempDir(), "audit.jsonl"))
+	protected, err := policy.CompileTagPatterns([]string{"prod-*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Deleter{Client: client, Actor: "octo", Owner: "octo", PackageType: "container", Package: "app", Protected: protected, AuditLog: log}
+	prod := github.PackageVersion{ID: 2, Name: "sha256:bbb"}
+	prod.Metadata.Container.Tags = []string{"prod-eu"}
+
+	res := d.DeletePackage([]github.PackageVersion{{ID: 1, Name: "sha256:aaa"}, prod})
+	if res.Err == nil || !strings.Contains(res.Err.Error(), "version sha256:bbb") {
+		t.Errorf("DeletePackage() error = %v, want the protected version named", res.Err)
+	}
+	res = d.DeletePackage([]github.PackageVersion{{ID: 1, Name: "sha256:aaa"}})
+	if res.Err != nil || res.AuditErr != nil {
+		t.Errorf("DeletePackage() = %+v, want success", res)
+	}
+
+	if len(deleted) != 1 || deleted[0] != "DELETE /user/packages/container/app" {
+		t.Errorf("requests = %v, want the package deleted once", deleted)
+	}
+	entries, err := log.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Result != audit.ResultFailure || entries[1].Action != audit.ActionDeletePackage || entries[1].Result != audit.ResultSuccess {
+		t.Errorf("audit entries = %+v", entries)
+	}
+}
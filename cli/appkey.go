@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/maful/hij/config"
+)
+
+// AppKey implements `hij app-key`, storing a GitHub App private key next to
+// the saved tokens so app.private_key can be left out of the config
+func AppKey(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	fs := flag.NewFlagSet("app-key", flag.ContinueOnError)
+	fs.Int64Var(&cfg.App.ID, "app-id", cfg.App.ID, "GitHub App ID, checked against the key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: hij app-key [--app-id ID] FILE")
+	}
+	return saveAppKey(cfg, fs.Arg(0))
+}
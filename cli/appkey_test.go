@@ -0,0 +1,50 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maful/hij/config"
+)
+
+func TestAppKey(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HIJ_TOKEN_STORE", "file")
+	t.Setenv("HIJ_TOKEN_PASSPHRASE", "secret")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	path := filepath.Join(dir, "app.pem")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	stdout = &out
+	t.Cleanup(func() { stdout = os.Stdout })
+
+	if err := AppKey(nil); err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Errorf("AppKey() error = %v, want usage", err)
+	}
+	if err := AppKey([]string{filepath.Join(dir, "missing.pem")}); err == nil {
+		t.Error("expected an error for a missing key file")
+	}
+	if err := AppKey([]string{path}); err != nil {
+		t.Fatalf("AppKey() error: %v", err)
+	}
+	if saved, err := config.GetAppKey(); err != nil || saved != string(data) {
+		t.Errorf("saved key = %q, %v, want the PEM file", saved, err)
+	}
+	if !strings.Contains(out.String(), "Saved the private key") {
+		t.Errorf("output = %q", out.String())
+	}
+}
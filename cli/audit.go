@@ -0,0 +1,125 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maful/hij/audit"
+	"github.com/maful/hij/config"
+)
+
+// Audit implements `hij audit`, querying and exporting the local audit log
+func Audit(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	loc, err := cfg.Location()
+	if err != nil {
+		return err
+	}
+
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	pkg := fs.String("package", "", "only show entries for this package")
+	since := fs.String("since", "", "only show entries on or after this date (YYYY-MM-DD)")
+	until := fs.String("until", "", "only show entries on or before this date (YYYY-MM-DD)")
+	result := fs.String("result", "", "only show entries with this result (success or failure)")
+	format := fs.String("format", "table", "output format: table, csv or json")
+	output := fs.String("output", "", "write to this file instead of stdout")
+	logPath := fs.String("log", "", "audit log file (defaults to the XDG state directory)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	q := audit.Query{Package: *pkg, Result: audit.Result(*result)}
+	if q.Result != "" && q.Result != audit.ResultSuccess && q.Result != audit.ResultFailure {
+		return fmt.Errorf("invalid --result %q, expected success or failure", *result)
+	}
+	if q.Since, err = parseDate("since", *since, loc); err != nil {
+		return err
+	}
+	if q.Until, err = parseDate("until", *until, loc); err != nil {
+		return err
+	}
+	if !q.Until.IsZero() {
+		// --until names a whole day
+		q.Until = q.Until.AddDate(0, 0, 1)
+	}
+
+	log, err := openAuditLog(*logPath)
+	if err != nil {
+		return err
+	}
+	entries, err := log.Read()
+	if err != nil {
+		return err
+	}
+	entries = audit.Filter(entries, q)
+	for i := range entries {
+		// Exports show the times in the location --since and --until are read in
+		entries[i].Timestamp = entries[i].Timestamp.In(loc)
+	}
+
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch *format {
+	case "table":
+		return writeAuditTable(w, entries, loc)
+	case "csv":
+		return audit.WriteCSV(w, entries)
+	case "json":
+		enc := json.NewEncoder(w)
+		for _, e := range entries {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("invalid --format %q, expected table, csv or json", *format)
+	}
+}
+
+func openAuditLog(path string) (*audit.Log, error) {
+	if path != "" {
+		return audit.Open(path), nil
+	}
+	return audit.OpenDefault()
+}
+
+func writeAuditTable(w io.Writer, entries []audit.Entry, loc *time.Location) error {
+	if len(entries) == 0 {
+		_, err := fmt.Fprintln(w, "No audit entries found.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tACTION\tACTOR\tPACKAGE\tVERSION\tTAGS\tRESULT")
+	for _, e := range entries {
+		result := string(e.Result)
+		if e.Error != "" {
+			result += ": " + e.Error
+		}
+		tags := strings.Join(e.Tags, ",")
+		if tags == "" {
+			tags = "<untagged>"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			e.Timestamp.In(loc).Format(time.DateTime), e.Action, e.Actor, e.Package, e.VersionID, tags, result)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maful/hij/audit"
+)
+
+func TestAudit_ExportCSV(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "audit.jsonl")
+	log := audit.Open(logPath)
+
+	entries := []audit.Entry{
+		{Timestamp: time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC), Action: audit.ActionDelete, Package: "app", VersionID: 1, Result: audit.ResultSuccess},
+		{Timestamp: time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC), Action: audit.ActionDelete, Package: "app", VersionID: 2, Result: audit.ResultFailure},
+		{Timestamp: time.Date(2024, 1, 20, 9, 0, 0, 0, time.UTC), Action: audit.ActionDelete, Package: "web", VersionID: 3, Result: audit.ResultSuccess},
+	}
+	for _, e := range entries {
+		if err := log.Append(e); err != nil {
+			t.Fatalf("Append() error: %v", err)
+		}
+	}
+
+	out := filepath.Join(dir, "out.csv")
+	args := []string{"--log", logPath, "--package", "app", "--since", "2024-01-15", "--until", "2024-01-20", "--format", "csv", "--output", out}
+	if err := Audit(args); err != nil {
+		t.Fatalf("Audit() error: %v", err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatalf("reading output: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("line count = %d, want header plus 1 record:\n%s", len(lines), data)
+	}
+	if !strings.Contains(lines[1], ",app,2,") {
+		t.Errorf("record = %q, want version 2 of app", lines[1])
+	}
+}
+
+func TestAudit_DatesInTimezone(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HIJ_FILTERS_TIMEZONE", "Asia/Tokyo")
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "audit.jsonl")
+	log := audit.Open(logPath)
+	// 2024-01-19 in UTC, but already 2024-01-20 in Tokyo
+	e := audit.Entry{Timestamp: time.Date(2024, 1, 19, 20, 0, 0, 0, time.UTC), Action: audit.ActionDelete, Package: "app", VersionID: 1, Result: audit.ResultSuccess}
+	if err := log.Append(e); err != nil {
+		t.Fatalf("Append() error: %v", err)
+	}
+
+	for format, want := range map[string]string{
+		"table": "2024-01-20 05:00:00",
+		"csv":   "2024-01-20T05:00:00+09:00",
+	} {
+		out := filepath.Join(dir, "out."+format)
+		if err := Audit([]string{"--log", logPath, "--since", "2024-01-20", "--until", "2024-01-20", "--format", format, "--output", out}); err != nil {
+			t.Fatalf("Audit() error: %v", err)
+		}
+		if data, _ := os.ReadFile(out); !strings.Contains(string(data), want) {
+			t.Errorf("%s output = %q, want the entry at %s", format, data, want)
+		}
+	}
+}
+
+func TestAudit_InvalidFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+	}{
+		{name: "bad result", args: []string{"--result", "maybe"}},
+		{name: "bad date", args: []string{"--since", "yesterday"}},
+		{name: "bad format", args: []string{"--format", "xml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"--log", filepath.Join(t.TempDir(), "audit.jsonl")}, tt.args...)
+			if err := Audit(args); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
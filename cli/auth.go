@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maful/hij/auth"
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+// requiredScopes are the scopes a classic token needs to manage packages
+var requiredScopes = []string{"read:packages", "delete:packages"}
+
+// Auth implements `hij auth status|logout|rotate`
+func Auth(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: hij auth status|logout|rotate")
+	}
+	switch args[0] {
+	case "status":
+		return authStatus(args[1:])
+	case "logout":
+		return authLogout(args[1:])
+	case "rotate":
+		return authRotate(args[1:])
+	}
+	return fmt.Errorf("usage: hij auth status|logout|rotate")
+}
+
+// authStatus shows which token hij uses and what it allows
+func authStatus(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	fs := flag.NewFlagSet("auth status", flag.ContinueOnError)
+	authFlags(fs, &cfg)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: hij auth status")
+	}
+
+	var client *github.Client
+	source := fmt.Sprintf("GitHub App %d on %s", cfg.App.ID, cfg.App.Org)
+	if cfg.Auth == "app" {
+		if client, err = auth.NewAppClient(cfg); err != nil {
+			return err
+		}
+	} else {
+		var token string
+		if token, source, err = cfg.Token(); err != nil {
+			return err
+		}
+		if token == "" {
+			return fmt.Errorf("not logged in to %s. Set HIJ_GITHUB_TOKEN or GH_TOKEN, log in with gh, or run hij login", cfg.Host)
+		}
+		client = auth.NewTokenClient(cfg, token)
+	}
+
+	info, err := client.GetTokenInfo()
+	if err != nil {
+		return fmt.Errorf("the token from %s does not work: %w", source, err)
+	}
+	writeAuthStatus(stdout, cfg, source, config.DescribeTokenStore(), info, time.Now())
+	return nil
+}
+
+// writeAuthStatus prints the host, profile, token source, where tokens are
+// saved, login, scopes and expiration, flagging missing scopes and tokens
+// that expire soon
+func writeAuthStatus(out io.Writer, cfg config.Config, source, store string, info *github.TokenInfo, now time.Time) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Host:\t%s\n", cfg.Host)
+	if cfg.Profile != "" {
+		fmt.Fprintf(w, "Profile:\t%s\n", cfg.Profile)
+	}
+	fmt.Fprintf(w, "Token:\t%s\n", source)
+	fmt.Fprintf(w, "Store:\t%s\n", store)
+	fmt.Fprintf(w, "Login:\t%s\n", info.Login)
+
+	switch {
+	case info.Scopes != nil:
+		scopes := strings.Join(info.Scopes, ", ")
+		for _, scope := range requiredScopes {
+			if !slices.Contains(info.Scopes, scope) {
+				scopes += " (missing " + scope + ")"
+			}
+		}
+		fmt.Fprintf(w, "Scopes:\t%s\n", scopes)
+	case cfg.Auth == "app":
+		fmt.Fprintf(w, "Scopes:\tpermissions of the app installation\n")
+	default:
+		fmt.Fprintf(w, "Scopes:\tnot reported, fine-grained token\n")
+	}
+
+	if info.ExpiresAt == nil {
+		fmt.Fprintf(w, "Expires:\tnever\n")
+	} else {
+		expires := info.ExpiresAt.Local().Format("2006-01-02 15:04")
+		left := info.ExpiresAt.Sub(now)
+		switch {
+		case left <= 0:
+			expires += " (expired)"
+		case left < github.ExpiryWarning:
+			expires += fmt.Sprintf(" (in %s, rotate it soon)", left.Round(time.Hour))
+		}
+		fmt.Fprintf(w, "Expires:\t%s\n", expires)
+	}
+	w.Flush()
+}
+
+// authLogout removes the saved token of the active profile from the keychain
+// or the encrypted token file
+func authLogout(args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: hij auth logout")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if err := config.DeleteToken(cfg.Profile); err != nil {
+		return fmt.Errorf("failed to remove token: %w", err)
+	}
+	fmt.Fprintf(stdout, "✓ Removed the saved token from the %s\n", config.TokenStore())
+	if _, source, _ := cfg.Token(); source != "" {
+		fmt.Fprintf(stdout, "hij still finds a token in %s\n", source)
+	}
+	return nil
+}
+
+// authRotate replaces the saved token with a new one, which is checked
+// first so a broken token never replaces a working one
+func authRotate(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	fs := flag.NewFlagSet("auth rotate", flag.ContinueOnError)
+	otherUser := fs.Bool("allow-other-user", false, "allow a token of a different account than the saved one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: hij auth rotate [--allow-other-user]")
+	}
+
+	token := promptSecret("New token: ")
+	if token == "" {
+		return fmt.Errorf("token cannot be empty")
+	}
+	// Both tokens are checked as they are, never replaced by token_command's
+	cfg.TokenCommand = ""
+	info, err := auth.NewTokenClient(cfg, token).GetTokenInfo()
+	if err != nil {
+		return fmt.Errorf("the new token does not work: %w", err)
+	}
+	for _, scope := range requiredScopes {
+		if info.Scopes != nil && !slices.Contains(info.Scopes, scope) {
+			return fmt.Errorf("the new token lacks the %s scope", scope)
+		}
+	}
+
+	old, err := config.SavedToken(cfg.Profile)
+	if err != nil && !errors.Is(err, config.ErrTokenNotFound) {
+		return fmt.Errorf("failed to read the saved token: %w", err)
+	}
+	if old != "" && !*otherUser {
+		// A saved token that no longer works can be replaced by any account's
+		if user, err := auth.NewTokenClient(cfg, old).GetAuthenticatedUser(); err == nil && user.Login != info.Login {
+			return fmt.Errorf("the new token belongs to %s, not %s. Pass --allow-other-user to switch accounts", info.Login, user.Login)
+		}
+	}
+
+	if err := config.SaveToken(cfg.Profile, token); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	fmt.Fprintf(stdout, "✓ Replaced the saved token, now logged in to %s as %s\n", cfg.Host, info.Login)
+	return nil
+}
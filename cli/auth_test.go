@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+func TestWriteAuthStatus(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	soon := now.Add(3 * 24 * time.Hour)
+	cfg := config.Default()
+	cfg.Profile = "work"
+
+	tests := []struct {
+		name string
+		info github.TokenInfo
+		want []string
+	}{
+		{
+			name: "classic token missing a scope",
+			info: github.TokenInfo{Login: "octo", Scopes: []string{"read:packages", "repo"}},
+			want: []string{"Profile:  work", "Login:    octo", "(missing delete:packages)", "Expires:  never"},
+		},
+		{
+			name: "fine-grained token expiring soon",
+			info: github.TokenInfo{Login: "octo", ExpiresAt: &soon},
+			want: []string{"not reported, fine-grained token", "(in 72h0m0s, rotate it soon)"},
+		},
+		{
+			name: "expired",
+			info: github.TokenInfo{Login: "octo", ExpiresAt: &now},
+			want: []string{"(expired)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeAuthStatus(&buf, cfg, config.SourceKeychain, "system keychain", &tt.info, now)
+			out := buf.String()
+			if !strings.Contains(out, "Host:     github.com") || !strings.Contains(out, "Token:    keychain") ||
+				!strings.Contains(out, "Store:    system keychain") {
+				t.Errorf("output should show the host and token source:\n%s", out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output does not contain %q:\n%s", want, out)
+				}
+			}
+		})
+	}
+}
+
+func TestAuth_Usage(t *testing.T) {
+	for _, args := range [][]string{nil, {"whoami"}, {"logout", "extra"}} {
+		if err := Auth(args); err == nil || !strings.Contains(err.Error(), "usage") {
+			t.Errorf("Auth(%v) error = %v, want usage", args, err)
+		}
+	}
+}
+
+func TestPromptSecret_NotATerminal(t *testing.T) {
+	var out bytes.Buffer
+	stdin, stdout = strings.NewReader(" ghp_new \n"), &out
+	t.Cleanup(func() { stdin, stdout = os.Stdin, os.Stdout })
+
+	if got := promptSecret("New token: "); got != "ghp_new" || out.String() != "New token: " {
+		t.Errorf("promptSecret() = %q, output %q", got, out.String())
+	}
+}
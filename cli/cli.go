@@ -0,0 +1,152 @@
+// Package cli implements hij's non-interactive subcommands.
+package cli
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/maful/hij/auth"
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+	"golang.org/x/term"
+)
+
+// Standard streams, replaced in tests
+var (
+	stdin  io.Reader = os.Stdin
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+// GlobalFlags parses the flags given before the subcommand, such as
+// --profile, and returns the remaining arguments. The settings are passed on
+// with config.SetFlag so every config.Load applies them.
+func GlobalFlags(args []string) ([]string, error) {
+	fs := flag.NewFlagSet("hij", flag.ContinueOnError)
+	setting := func(name, key, usage string) {
+		fs.Func(name, usage, func(value string) error {
+			return config.SetFlag("--"+name, key, value)
+		})
+	}
+	setting("profile", "profile", "use the named profile of the config file")
+	setting("owner", "owner", "manage the packages of this organization")
+	setting("package-type", "package_type", "manage packages of this type: container, npm, maven, rubygems, docker or nuget")
+	fs.Func("set", "override a setting of the config file, as `KEY=VALUE` such as ui.sort_order=oldest", func(value string) error {
+		key, value, ok := strings.Cut(value, "=")
+		if !ok {
+			return fmt.Errorf("expected KEY=VALUE")
+		}
+		return config.SetFlag("--set", key, value)
+	})
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	return fs.Args(), nil
+}
+
+// parseArgs parses flags that may appear before or after positional
+// arguments and returns the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// prompt asks a question on stdout and returns the trimmed answer
+func prompt(question string) string {
+	fmt.Fprint(stdout, question)
+	answer, _ := bufio.NewReader(stdin).ReadString('\n')
+	return strings.TrimSpace(answer)
+}
+
+// promptSecret asks for a secret like prompt, without echoing the answer
+// when stdin is a terminal
+func promptSecret(question string) string {
+	f, ok := stdin.(*os.File)
+	if !ok || !term.IsTerminal(int(f.Fd())) {
+		return prompt(question)
+	}
+	fmt.Fprint(stdout, question)
+	secret, _ := term.ReadPassword(int(f.Fd()))
+	fmt.Fprintln(stdout)
+	return strings.TrimSpace(string(secret))
+}
+
+// newClient builds an API client for the configured host from the stored
+// token, or as the configured GitHub App, and resolves the login deletions
+// are recorded under.
+func newClient(cfg config.Config) (*github.Client, string, error) {
+	var client *github.Client
+	switch cfg.Auth {
+	case "app":
+		c, err := auth.NewAppClient(cfg)
+		if err != nil {
+			return nil, "", err
+		}
+		client = c
+	case "token":
+		token, _, err := cfg.Token()
+		if err != nil {
+			return nil, "", err
+		}
+		if token == "" && cfg.TokenSource != "" {
+			return nil, "", fmt.Errorf("no GitHub token found in token_source %s", cfg.TokenSource)
+		}
+		if token == "" {
+			return nil, "", fmt.Errorf("no GitHub token found. Set HIJ_GITHUB_TOKEN or GH_TOKEN, log in with gh, or run hij login")
+		}
+		client = auth.NewTokenClient(cfg, token)
+	default:
+		return nil, "", fmt.Errorf("invalid --auth %q, expected token or app", cfg.Auth)
+	}
+
+	login, err := client.Actor()
+	if err != nil {
+		return nil, "", err
+	}
+	return client, login, nil
+}
+
+// ownerOf returns the account whose packages client manages: the
+// organization of a GitHub App, otherwise the authenticated user
+func ownerOf(client *github.Client, login string) string {
+	if org := client.Org(); org != "" {
+		return org
+	}
+	return login
+}
+
+// authFlags registers the flags that choose how to authenticate, overriding
+// the auth and app settings of cfg
+func authFlags(fs *flag.FlagSet, cfg *config.Config) {
+	fs.StringVar(&cfg.Auth, "auth", cfg.Auth, "authenticate with a token or as a GitHub App: token or app")
+	fs.Int64Var(&cfg.App.ID, "app-id", cfg.App.ID, "GitHub App ID, with --auth app")
+	fs.StringVar(&cfg.App.PrivateKey, "app-key", cfg.App.PrivateKey, "GitHub App private key PEM file, with --auth app")
+	fs.StringVar(&cfg.App.Org, "org", cfg.App.Org, "organization the GitHub App is installed on, with --auth app")
+}
+
+// parseDate parses a YYYY-MM-DD date flag in loc. An empty value yields the
+// zero time.
+func parseDate(name, value string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --%s date %q, expected YYYY-MM-DD", name, value)
+	}
+	return t, nil
+}
@@ -0,0 +1,36 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/maful/hij/config"
+)
+
+// Config implements `hij config show`, printing the effective configuration
+func Config(args []string) error {
+	if len(args) != 1 || args[0] != "show" {
+		return fmt.Errorf("usage: hij config show")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	settings, err := cfg.Settings()
+	if err != nil {
+		return err
+	}
+	writeSettings(stdout, settings)
+	return nil
+}
+
+// writeSettings prints each setting with its value and where it came from
+func writeSettings(out io.Writer, settings []config.Setting) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SETTING\tVALUE\tSOURCE")
+	for _, s := range settings {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+	}
+	w.Flush()
+}
@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/maful/hij/config"
+)
+
+func TestWriteSettings(t *testing.T) {
+	var buf bytes.Buffer
+	writeSettings(&buf, []config.Setting{
+		{Key: "owner", Value: "acme", Source: "/repo/.hij.yaml"},
+		{Key: "ui.sort_order", Value: "oldest", Source: "env HIJ_UI_SORT_ORDER"},
+	})
+	want := "SETTING        VALUE   SOURCE\n" +
+		"owner          acme    /repo/.hij.yaml\n" +
+		"ui.sort_order  oldest  env HIJ_UI_SORT_ORDER\n"
+	if buf.String() != want {
+		t.Errorf("output =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestConfig_Usage(t *testing.T) {
+	for _, args := range [][]string{nil, {"edit"}, {"show", "extra"}} {
+		if err := Config(args); err == nil || !strings.Contains(err.Error(), "usage") {
+			t.Errorf("Config(%v) error = %v, want usage", args, err)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/maful/hij/audit"
+	"github.com/maful/hij/cleanup"
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/filter"
+	"github.com/maful/hij/github"
+	"github.com/maful/hij/lifecycle"
+	"github.com/maful/hij/policy"
+	"github.com/maful/hij/refs"
+	"github.com/maful/hij/registry"
+)
+
+// Delete implements `hij delete <package> --filter EXPR`, deleting matching
+// versions without the TUI. The same guardrails, protections, archiving and
+// auditing apply as in the interactive confirm flow.
+func Delete(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	expr := fs.String("filter", "", "select versions matching this filter, e.g. \"older 30\" or \"keep 10\"")
+	dryRun := fs.Bool("dry-run", false, "show what would be deleted without deleting anything")
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	confirm := fs.String("confirm", "", "package name, required with --yes when the selection exceeds guardrails.confirm_threshold")
+	archive := fs.Bool("archive", cfg.Archive.Enabled, "archive each version to an OCI layout before deleting it")
+	allowInUse := fs.Bool("allow-in-use", false, "also delete versions referenced by deployment manifests")
+	timestamp := fs.String("timestamp", cfg.Filters.Timestamp, "timestamp compared by age, older, before, after and between: created or updated")
+	authFlags(fs, &cfg)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || *expr == "" {
+		return fmt.Errorf("usage: hij delete <package> --filter EXPR [--dry-run] [--yes]")
+	}
+	pkg := positional[0]
+
+	loc, err := cfg.Location()
+	if err != nil {
+		return err
+	}
+	field, err := filter.ParseTimeField(*timestamp)
+	if err != nil {
+		return err
+	}
+	opts := filter.Options{Now: time.Now().In(loc), Field: field}
+	match, err := filter.ParseWith(*expr, opts)
+	if err != nil {
+		return err
+	}
+
+	protected, err := policy.CompileTagPatterns(cfg.ProtectedTagsFor(pkg))
+	if err != nil {
+		return fmt.Errorf("protected_tags: %w", err)
+	}
+
+	var index refs.Index
+	if paths := cfg.ReferencePaths(); len(paths) > 0 {
+		found, err := refs.Scan(paths)
+		if err != nil {
+			return fmt.Errorf("failed to scan references: %w", err)
+		}
+		index = refs.NewIndex(found)
+	}
+
+	client, login, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+	owner := ownerOf(client, login)
+	versions, err := client.ListPackageVersions(cfg.PackageType, pkg)
+	if err != nil {
+		return err
+	}
+
+	var repo string // the repository pkg is linked to, when one is needed
+	if match.NeedsLifecycle() || cfg.Protect.Releases || cfg.Protect.Deployments {
+		p, err := client.GetPackage(cfg.PackageType, pkg)
+		if err != nil {
+			return err
+		}
+		if p.Repository != nil {
+			repo = p.Repository.FullName
+		}
+	}
+
+	var releases *policy.Releases
+	var releasesErr error // the selection is still shown, but nothing is deleted
+	if repo != "" {
+		if releases, err = policy.LoadReleases(client, repo, cfg.Protect); err != nil {
+			releasesErr = fmt.Errorf("failed to load releases and deployments of %s, so the versions backing them cannot be protected: %w. "+
+				"Turn protect.releases and protect.deployments off for tokens without access to the repository", repo, err)
+			fmt.Fprintf(stderr, "Warning: %v\n", releasesErr)
+		}
+	}
+
+	if match.NeedsLifecycle() {
+		if opts.Lifecycle, err = loadLifecycle(client, cfg, pkg, repo, versions); err != nil {
+			return err
+		}
+		if match, err = filter.ParseWith(*expr, opts); err != nil {
+			return err
+		}
+	}
+
+	blocked := func(v github.PackageVersion) error {
+		if err := policy.CheckTags(protected, v); err != nil {
+			return err
+		}
+		if err := releases.Check(v); err != nil {
+			return err
+		}
+		if used := index.Lookup(owner, pkg, v); len(used) > 0 && !*allowInUse {
+			return fmt.Errorf("in use by %s", used[0])
+		}
+		return nil
+	}
+
+	for _, v := range match.Ignored(versions) {
+		fmt.Fprintf(stdout, "Ignoring %s (%s): no semver tag\n", v.Name, v.TagsString())
+	}
+	selected, skipped := planDeletion(match.Apply(versions), blocked)
+	for _, msg := range skipped {
+		fmt.Fprintln(stdout, "Skipping "+msg)
+	}
+	if len(selected) == 0 {
+		fmt.Fprintln(stdout, "Nothing to delete.")
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "%d of %d version(s) of %s selected:\n", len(selected), len(versions), pkg)
+	writeVersionTable(selected, loc)
+
+	ids := make(map[int]struct{}, len(selected))
+	for _, v := range selected {
+		ids[v.ID] = struct{}{}
+	}
+	if err := policy.CheckGuardrails(cfg.Guardrails, pkg, versions, ids); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		fmt.Fprintln(stdout, "Dry run, nothing deleted.")
+		return nil
+	}
+	if releasesErr != nil {
+		return releasesErr
+	}
+
+	if policy.RequiresTypedConfirmation(cfg.Guardrails, len(selected)) {
+		name := *confirm
+		if name == "" && !*yes {
+			name = prompt(fmt.Sprintf("Type %s to confirm: ", pkg))
+		}
+		if name != pkg {
+			return fmt.Errorf("deleting %d versions requires confirming the package name (--confirm %s)", len(selected), pkg)
+		}
+	} else if !*yes {
+		if answer := prompt(fmt.Sprintf("Delete %d version(s)? [y/N]: ", len(selected))); answer != "y" && answer != "Y" {
+			fmt.Fprintln(stdout, "Cancelled.")
+			return nil
+		}
+	}
+
+	d := &cleanup.Deleter{
+		Client:      client,
+		Actor:       login,
+		Owner:       owner,
+		PackageType: cfg.PackageType,
+		Package:     pkg,
+		Protected:   protected,
+		Blocked:     blocked,
+	}
+	if log, err := audit.OpenDefault(); err == nil {
+		d.AuditLog = log
+	}
+	if *archive {
+		if cfg.PackageType != "container" {
+			return fmt.Errorf("only container images can be archived, pass --archive=false")
+		}
+		dir, err := cfg.ArchiveDir()
+		if err != nil {
+			return fmt.Errorf("cannot resolve archive directory: %w", err)
+		}
+		d.Archiver = registry.NewArchiver(registry.NewClient(login, client.Token()), dir, cfg.Archive.Format == "tar")
+	}
+
+	failed := 0
+	for _, v := range selected {
+		res := d.Delete(v)
+		switch {
+		case res.Err != nil:
+			failed++
+			fmt.Fprintf(stdout, "✗ %s: %v\n", v.Name, res.Err)
+		case res.Archive != "":
+			fmt.Fprintf(stdout, "✓ %s (archived to %s)\n", v.Name, res.Archive)
+		default:
+			fmt.Fprintf(stdout, "✓ %s\n", v.Name)
+		}
+		if res.AuditErr != nil {
+			return fmt.Errorf("failed to write audit log: %w", res.AuditErr)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d deletions failed", failed, len(selected))
+	}
+	fmt.Fprintf(stdout, "Successfully deleted %d version(s)\n", len(selected))
+	return nil
+}
+
+// loadLifecycle looks up the pull requests and branches named by the tags of
+// versions in repo, the repository pkg is linked to
+func loadLifecycle(client *github.Client, cfg config.Config, pkg, repo string, versions []github.PackageVersion) (*lifecycle.Status, error) {
+	patterns, err := lifecycle.CompilePatterns(cfg.Lifecycle)
+	if err != nil {
+		return nil, fmt.Errorf("lifecycle: %w", err)
+	}
+	if repo == "" {
+		return nil, fmt.Errorf("pr-closed and branch-deleted need %s to be linked to a repository", pkg)
+	}
+	return lifecycle.Load(client, repo, patterns, versions)
+}
+
+// planDeletion splits matched versions into those to delete and a
+// description of each one that is blocked
+func planDeletion(matched []github.PackageVersion, blocked func(github.PackageVersion) error) ([]github.PackageVersion, []string) {
+	var selected []github.PackageVersion
+	var skipped []string
+	for _, v := range matched {
+		if err := blocked(v); err != nil {
+			skipped = append(skipped, fmt.Sprintf("%s (%s): %v", v.Name, v.TagsString(), err))
+			continue
+		}
+		selected = append(selected, v)
+	}
+	return selected, skipped
+}
+
+func writeVersionTable(versions []github.PackageVersion, loc *time.Location) {
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	for _, v := range versions {
+		fmt.Fprintf(tw, "  %d\t%s\t%s\t%s\n", v.ID, v.Name, strings.Join(v.Tags(), ","), v.CreatedAt.In(loc).Format(time.DateOnly))
+	}
+	tw.Flush()
+}
@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"testing"
+
+	"github.com/maful/hij/github"
+)
+
+func TestParseArgs_Interleaved(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	expr := fs.String("filter", "", "")
+	dryRun := fs.Bool("dry-run", false, "")
+
+	positional, err := parseArgs(fs, []string{"--dry-run", "app", "--filter", "older 30"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(positional) != 1 || positional[0] != "app" {
+		t.Errorf("positional = %v, want [app]", positional)
+	}
+	if *expr != "older 30" || !*dryRun {
+		t.Errorf("filter = %q, dry-run = %v", *expr, *dryRun)
+	}
+}
+
+func TestPlanDeletion(t *testing.T) {
+	matched := []github.PackageVersion{{ID: 1, Name: "sha256:a"}, {ID: 2, Name: "sha256:b"}, {ID: 3, Name: "sha256:c"}}
+	blocked := func(v github.PackageVersion) error {
+		if v.ID == 2 {
+			return errors.New("in use by deploy.yaml:3")
+		}
+		return nil
+	}
+
+	selected, skipped := planDeletion(matched, blocked)
+
+	if len(selected) != 2 || selected[0].ID != 1 || selected[1].ID != 3 {
+		t.Errorf("selected = %v, want versions 1 and 3", selected)
+	}
+	if len(skipped) != 1 || skipped[0] != "sha256:b (<untagged>): in use by deploy.yaml:3" {
+		t.Errorf("skipped = %v", skipped)
+	}
+}
@@ -0,0 +1,102 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/maful/hij/auth"
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+// Login implements `hij login`, signing in with the OAuth device flow and
+// saving the token to the keychain. With --app-key it stores the private
+// key of a GitHub App instead.
+func Login(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	clientID := fs.String("client-id", cfg.OAuth.ClientID, "client ID of an OAuth app with device flow enabled")
+	scopes := fs.String("scopes", strings.Join(cfg.OAuth.Scopes, ","), "comma-separated scopes to request")
+	appKey := fs.String("app-key", "", "save this GitHub App private key PEM file to the keychain instead")
+	fs.Int64Var(&cfg.App.ID, "app-id", cfg.App.ID, "GitHub App ID, checked against the key given with --app-key")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("usage: hij login [--client-id ID] [--scopes read:packages,delete:packages] | hij login --app-key FILE [--app-id ID]")
+	}
+	if *appKey != "" {
+		return saveAppKey(cfg, *appKey)
+	}
+
+	flow := auth.NewDeviceFlow(cfg)
+	flow.ClientID = *clientID
+	flow.Scopes = strings.Split(*scopes, ",")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	code, err := flow.Start(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "First copy your one-time code: %s\n", code.UserCode)
+	fmt.Fprintf(stdout, "Then open %s in your browser and enter it.\n", code.VerificationURI)
+	fmt.Fprintln(stdout, "Waiting for authorization...")
+
+	token, err := flow.Poll(ctx, code)
+	if err != nil {
+		return err
+	}
+	client := github.NewClient(token)
+	client.SetBaseURL(cfg.APIURL())
+	user, err := client.GetAuthenticatedUser()
+	if err != nil {
+		return err
+	}
+	if err := config.SaveToken(cfg.Profile, token); err != nil {
+		return fmt.Errorf("failed to save token: %w", err)
+	}
+	if cfg.Profile != "" {
+		fmt.Fprintf(stdout, "✓ Logged in to %s as %s (profile %s)\n", cfg.Host, user.Login, cfg.Profile)
+		return nil
+	}
+	fmt.Fprintf(stdout, "✓ Logged in to %s as %s\n", cfg.Host, user.Login)
+	return nil
+}
+
+// saveAppKey stores the GitHub App private key at path in the keychain,
+// after checking it signs in as the configured app when its ID is known
+func saveAppKey(cfg config.Config, path string) error {
+	data, err := os.ReadFile(config.ExpandHome(path))
+	if err != nil {
+		return fmt.Errorf("failed to read app private key: %w", err)
+	}
+	key, err := github.ParsePrivateKey(data)
+	if err != nil {
+		return err
+	}
+
+	name := "GitHub App"
+	if cfg.App.ID != 0 {
+		client := github.NewAppClient(&github.AppAuth{AppID: cfg.App.ID, Key: key, Org: cfg.App.Org})
+		client.SetBaseURL(cfg.APIURL())
+		app, err := client.GetApp()
+		if err != nil {
+			return err
+		}
+		name = app.Name
+	}
+	if err := config.SaveAppKey(string(data)); err != nil {
+		return fmt.Errorf("failed to save app private key: %w", err)
+	}
+	fmt.Fprintf(stdout, "✓ Saved the private key of %s to the %s\n", name, config.TokenStore())
+	return nil
+}
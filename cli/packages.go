@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/maful/hij/audit"
+	"github.com/maful/hij/cleanup"
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+	"github.com/maful/hij/orphan"
+	"github.com/maful/hij/policy"
+	"github.com/maful/hij/refs"
+)
+
+// Packages implements `hij packages orphans`
+func Packages(args []string) error {
+	if len(args) == 0 || args[0] != "orphans" {
+		return fmt.Errorf("usage: hij packages orphans [--delete] [--confirm NAME,...] [--yes]")
+	}
+	return orphans(args[1:])
+}
+
+// orphans lists packages whose repository is deleted, archived or was never
+// linked, and with --delete removes them one at a time. Every package must
+// be confirmed by typing its name or listing it in --confirm.
+func orphans(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	fs := flag.NewFlagSet("packages orphans", flag.ContinueOnError)
+	del := fs.Bool("delete", false, "offer to delete each orphaned package")
+	confirm := fs.String("confirm", "", "comma-separated package names to delete without asking")
+	yes := fs.Bool("yes", false, "do not ask, only delete packages listed in --confirm")
+	allowInUse := fs.Bool("allow-in-use", false, "also delete packages with versions referenced by deployment manifests")
+	authFlags(fs, &cfg)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return fmt.Errorf("usage: hij packages orphans [--delete] [--confirm NAME,...] [--yes]")
+	}
+
+	client, login, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+	packages, err := client.ListPackages(cfg.PackageType)
+	if err != nil {
+		return err
+	}
+	found, err := orphan.Find(client, packages)
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		fmt.Fprintln(stdout, "No orphaned packages.")
+		return nil
+	}
+	writeOrphanTable(found)
+	if !*del {
+		return nil
+	}
+
+	var index refs.Index
+	if paths := cfg.ReferencePaths(); len(paths) > 0 {
+		found, err := refs.Scan(paths)
+		if err != nil {
+			return fmt.Errorf("failed to scan references: %w", err)
+		}
+		index = refs.NewIndex(found)
+	}
+	confirmed := make(map[string]bool)
+	for _, name := range strings.Split(*confirm, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			confirmed[name] = true
+		}
+	}
+
+	deleted, failed := 0, 0
+	for _, o := range found {
+		name := o.Package.Name
+		versions, err := client.ListPackageVersions(cfg.PackageType, name)
+		if err != nil {
+			failed++
+			fmt.Fprintf(stdout, "✗ %s: %v\n", name, err)
+			continue
+		}
+		d, err := packageDeleter(cfg, client, login, o, index, *allowInUse)
+		if err != nil {
+			return err
+		}
+		if err := policy.CheckPackageGuardrails(cfg.Guardrails, name, versions, d.Blocked); err != nil {
+			fmt.Fprintf(stdout, "Skipping %v\n", err)
+			continue
+		}
+
+		if !confirmed[name] {
+			if *yes {
+				fmt.Fprintf(stdout, "Skipping %s, not listed in --confirm\n", name)
+				continue
+			}
+			if prompt(fmt.Sprintf("Type %s to delete it with its %d version(s), or press enter to skip: ", name, len(versions))) != name {
+				fmt.Fprintf(stdout, "Skipped %s\n", name)
+				continue
+			}
+		}
+
+		res := d.DeletePackage(versions)
+		if res.AuditErr != nil {
+			return fmt.Errorf("failed to write audit log: %w", res.AuditErr)
+		}
+		if res.Err != nil {
+			failed++
+			fmt.Fprintf(stdout, "✗ %s: %v\n", name, res.Err)
+			continue
+		}
+		deleted++
+		fmt.Fprintf(stdout, "✓ %s\n", name)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d package deletions failed", failed, deleted+failed)
+	}
+	fmt.Fprintf(stdout, "Deleted %d package(s)\n", deleted)
+	return nil
+}
+
+// packageDeleter builds the deletion pipeline for an orphaned package with
+// the same protections as version deletion: protected tags, references in
+// deployment manifests, and releases and deployments of archived
+// repositories, which can still be looked up
+func packageDeleter(cfg config.Config, client *github.Client, login string, o orphan.Orphan, index refs.Index, allowInUse bool) (*cleanup.Deleter, error) {
+	name, owner := o.Package.Name, ownerOf(client, login)
+	protected, err := policy.CompileTagPatterns(cfg.ProtectedTagsFor(name))
+	if err != nil {
+		return nil, fmt.Errorf("protected_tags: %w", err)
+	}
+	var releases *policy.Releases
+	if o.Reason == orphan.Archived {
+		if releases, err = policy.LoadReleases(client, o.Package.Repository.FullName, cfg.Protect); err != nil {
+			return nil, fmt.Errorf("failed to load releases and deployments: %w", err)
+		}
+	}
+
+	d := &cleanup.Deleter{
+		Client:      client,
+		Actor:       login,
+		Owner:       owner,
+		PackageType: cfg.PackageType,
+		Package:     name,
+		Protected:   protected,
+		Blocked: func(v github.PackageVersion) error {
+			if err := policy.CheckTags(protected, v); err != nil {
+				return err
+			}
+			if err := releases.Check(v); err != nil {
+				return err
+			}
+			if used := index.Lookup(owner, name, v); len(used) > 0 && !allowInUse {
+				return fmt.Errorf("in use by %s", used[0])
+			}
+			return nil
+		},
+	}
+	if log, err := audit.OpenDefault(); err == nil {
+		d.AuditLog = log
+	}
+	return d, nil
+}
+
+func writeOrphanTable(orphans []orphan.Orphan) {
+	tw := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PACKAGE\tVERSIONS\tREPOSITORY\tREASON")
+	for _, o := range orphans {
+		repo := "-"
+		if o.Package.Repository != nil {
+			repo = o.Package.Repository.FullName
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", o.Package.Name, o.Package.VersionCount, repo, o.Reason)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/maful/hij/github"
+	"github.com/maful/hij/orphan"
+)
+
+func TestWriteOrphanTable(t *testing.T) {
+	var buf bytes.Buffer
+	defer func(w io.Writer) { stdout = w }(stdout)
+	stdout = &buf
+
+	linked := github.Package{Name: "legacy", VersionCount: 12}
+	linked.Repository = &struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		FullName string `json:"full_name"`
+	}{FullName: "octo/legacy"}
+	writeOrphanTable([]orphan.Orphan{
+		{Package: linked, Reason: orphan.Archived},
+		{Package: github.Package{Name: "scratch", VersionCount: 3}, Reason: orphan.Unlinked},
+	})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("output = %q, want a header and two rows", buf.String())
+	}
+	if fields := strings.Fields(lines[1]); fields[0] != "legacy" || fields[1] != "12" || fields[2] != "octo/legacy" {
+		t.Errorf("row = %q", lines[1])
+	}
+	if fields := strings.Fields(lines[2]); fields[0] != "scratch" || fields[2] != "-" {
+		t.Errorf("row = %q, want a dash for the missing repository", lines[2])
+	}
+}
+
+func TestPackages_Usage(t *testing.T) {
+	for _, args := range [][]string{nil, {"list"}, {"orphans", "extra"}} {
+		if err := Packages(args); err == nil || !strings.Contains(err.Error(), "usage") {
+			t.Errorf("Packages(%v) error = %v, want usage", args, err)
+		}
+	}
+}
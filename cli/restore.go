@@ -0,0 +1,70 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+
+	"github.com/maful/hij/audit"
+	"github.com/maful/hij/config"
+)
+
+// Restore implements `hij restore <package> <version-id>`, bringing back a
+// version deleted within GitHub's 30 day restore window
+func Restore(args []string) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	authFlags(fs, &cfg)
+	positional, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return fmt.Errorf("usage: hij restore <package> <version-id>")
+	}
+	pkg := positional[0]
+	versionID, err := strconv.Atoi(positional[1])
+	if err != nil {
+		return fmt.Errorf("invalid version id %q", positional[1])
+	}
+
+	client, login, err := newClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	restoreErr := client.RestorePackageVersion(cfg.PackageType, pkg, versionID)
+
+	e := audit.NewEntry(audit.ActionRestore, restoreErr)
+	e.Actor = login
+	e.Owner = ownerOf(client, login)
+	e.PackageType = cfg.PackageType
+	e.Package = pkg
+	e.VersionID = versionID
+	return reportRestore(pkg, versionID, restoreErr, recordAudit(e))
+}
+
+// recordAudit appends e to the default audit log
+func recordAudit(e audit.Entry) error {
+	log, err := audit.OpenDefault()
+	if err != nil {
+		return err
+	}
+	return log.Append(e)
+}
+
+// reportRestore tells whether the restore worked, then warns when it could
+// not be recorded, without hiding the outcome of the restore itself
+func reportRestore(pkg string, versionID int, restoreErr, auditErr error) error {
+	if restoreErr == nil {
+		fmt.Fprintf(stdout, "Restored version %d of %s\n", versionID, pkg)
+	}
+	if auditErr != nil {
+		fmt.Fprintf(stderr, "Warning: the restore was not recorded in the audit log: %v\n", auditErr)
+	}
+	return restoreErr
+}
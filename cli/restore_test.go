@@ -0,0 +1,48 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maful/hij/audit"
+)
+
+func TestReportRestore(t *testing.T) {
+	var out, errOut bytes.Buffer
+	stdout, stderr = &out, &errOut
+	t.Cleanup(func() { stdout, stderr = os.Stdout, os.Stderr })
+
+	err := reportRestore("app", 7, nil, errors.New("disk full"))
+	if err != nil {
+		t.Errorf("a restore that worked should not fail, got %v", err)
+	}
+	if !strings.Contains(out.String(), "Restored version 7 of app") || !strings.Contains(errOut.String(), "disk full") {
+		t.Errorf("stdout = %q, stderr = %q, want the restore reported and the audit failure warned about", out.String(), errOut.String())
+	}
+
+	out.Reset()
+	errOut.Reset()
+	restoreErr := errors.New("restore window passed")
+	if err := reportRestore("app", 7, restoreErr, errors.New("disk full")); err != restoreErr {
+		t.Errorf("error = %v, want the restore error kept", err)
+	}
+	if out.Len() != 0 || !strings.Contains(errOut.String(), "disk full") {
+		t.Errorf("stdout = %q, stderr = %q", out.String(), errOut.String())
+	}
+}
+
+func TestRecordAudit_Unavailable(t *testing.T) {
+	// A file where the state directory should be
+	file := filepath.Join(t.TempDir(), "state")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("XDG_STATE_HOME", file)
+	if err := recordAudit(audit.NewEntry(audit.ActionRestore, nil)); err == nil {
+		t.Error("expected an error when the audit log cannot be written")
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tokenCommandTimeout bounds how long a secret manager may take to answer
+const tokenCommandTimeout = time.Minute
+
+// commandTokens caches the token each command printed for the rest of the
+// session, so the secret manager is asked once
+var commandTokens = struct {
+	sync.Mutex
+	tokens map[string]string
+}{tokens: make(map[string]string)}
+
+// commandToken returns the cached token of the active token_command,
+// running it the first time
+func (c Config) commandToken() (string, error) {
+	commandTokens.Lock()
+	defer commandTokens.Unlock()
+	key := c.commandKey()
+	if token, ok := commandTokens.tokens[key]; ok {
+		return token, nil
+	}
+	token, err := runTokenCommand(c.TokenCommand, c.host(), c.Profile)
+	if err != nil {
+		return "", err
+	}
+	commandTokens.tokens[key] = token
+	return token, nil
+}
+
+// RefreshToken runs token_command again and caches its new token, used when
+// the API rejects the cached one
+func (c Config) RefreshToken() (string, error) {
+	if c.TokenCommand == "" {
+		return "", fmt.Errorf("no token_command configured")
+	}
+	token, err := runTokenCommand(c.TokenCommand, c.host(), c.Profile)
+	if err != nil {
+		return "", err
+	}
+	commandTokens.Lock()
+	commandTokens.tokens[c.commandKey()] = token
+	commandTokens.Unlock()
+	return token, nil
+}
+
+func (c Config) commandKey() string {
+	return c.host() + "\x00" + c.Profile + "\x00" + c.TokenCommand
+}
+
+// runTokenCommand runs command with the shell. The command prints the token
+// on the first line of its standard output and exits with status 0; it can
+// tell hosts and profiles apart by HIJ_TOKEN_HOST and HIJ_TOKEN_PROFILE, named
+// apart from the HIJ_ setting overrides.
+func runTokenCommand(command, host, profile string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), tokenCommandTimeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		// #nosec G204 -- token_command is the user's own, from config.yaml, the environment or a flag; .hij.yaml cannot set it.
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		// #nosec G204 -- token_command is the user's own, from config.yaml, the environment or a flag; .hij.yaml cannot set it.
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), "HIJ_TOKEN_HOST="+host, "HIJ_TOKEN_PROFILE="+profile)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("token_command failed: %w: %s", err, msg)
+		}
+		return "", fmt.Errorf("token_command failed: %w", err)
+	}
+
+	token, _, _ := strings.Cut(strings.TrimSpace(stdout.String()), "\n")
+	if token = strings.TrimSpace(token); token == "" {
+		return "", fmt.Errorf("token_command printed no token")
+	}
+	return token, nil
+}
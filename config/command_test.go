@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfig_Token_Command(t *testing.T) {
+	isolateTokens(t)
+	t.Setenv("GH_TOKEN", "ghp_env")
+	count := filepath.Join(t.TempDir(), "count")
+	cfg := Config{
+		Host:         "github.com",
+		Profile:      "work",
+		TokenCommand: `echo run >> ` + count + `; echo "tok-$HIJ_TOKEN_PROFILE-$(wc -l < ` + count + ` | tr -d ' ')"; echo ignored`,
+	}
+
+	token, source, err := cfg.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "tok-work-1" || source != SourceTokenCommand {
+		t.Errorf("Token() = %q, %q, want the command's first line", token, source)
+	}
+	if token, _, _ = cfg.Token(); token != "tok-work-1" {
+		t.Errorf("second Token() = %q, want the cached token", token)
+	}
+
+	if token, err = cfg.RefreshToken(); err != nil || token != "tok-work-2" {
+		t.Errorf("RefreshToken() = %q, %v, want the command run again", token, err)
+	}
+	if token, _, _ = cfg.Token(); token != "tok-work-2" {
+		t.Errorf("Token() after refresh = %q, want the new token", token)
+	}
+	data, _ := os.ReadFile(count)
+	if runs := strings.Count(string(data), "run"); runs != 2 {
+		t.Errorf("command ran %d times, want 2", runs)
+	}
+}
+
+func TestConfig_Token_CommandFails(t *testing.T) {
+	isolateTokens(t)
+	cfg := Config{Host: "github.com", TokenCommand: "echo 'vault is sealed' >&2; exit 2"}
+	if _, _, err := cfg.Token(); err == nil || !strings.Contains(err.Error(), "vault is sealed") {
+		t.Errorf("Token() error = %v, want the command's stderr", err)
+	}
+
+	cfg.TokenCommand = "true"
+	if _, _, err := cfg.Token(); err == nil || !strings.Contains(err.Error(), "no token") {
+		t.Errorf("Token() error = %v, want no token printed", err)
+	}
+}
@@ -0,0 +1,483 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const configFileName = "config.yaml"
+
+// Config holds user preferences read from config.yaml and the repository's
+// .hij.yaml, with environment and flag overrides
+type Config struct {
+	// Host is the GitHub host to sign in to and call: github.com or the
+	// hostname of a GitHub Enterprise Server
+	Host  string `yaml:"host"`
+	OAuth OAuth  `yaml:"oauth"`
+	// Auth is "token" to use a personal or OAuth token, or "app" to
+	// authenticate as the installation of App on App.Org
+	Auth string `yaml:"auth"`
+	App  App    `yaml:"app"`
+	// Owner is the organization whose packages are managed, empty for the
+	// authenticated user's own
+	Owner       string `yaml:"owner"`
+	PackageType string `yaml:"package_type"` // container, npm, maven, rubygems, docker or nuget
+	// TokenSource is where the token is read from: "keychain", "gh" or
+	// "env:NAME", empty to check every source in turn
+	TokenSource string `yaml:"token_source"`
+	// TokenCommand is run with the shell to print the token, such as
+	// `op read op://dev/github/token`. It takes precedence over TokenSource.
+	TokenCommand string `yaml:"token_command"`
+
+	// Profile is the active profile: the one chosen with --profile or
+	// HIJ_PROFILE, or else this default. Profiles override the settings above.
+	Profile  string             `yaml:"profile"`
+	Profiles map[string]Profile `yaml:"profiles"`
+
+	Archive    Archive    `yaml:"archive"`
+	References References `yaml:"references"`
+	Guardrails Guardrails `yaml:"guardrails"`
+	Filters    Filters    `yaml:"filters"`
+	Lifecycle  Lifecycle  `yaml:"lifecycle"`
+	Protect    Protect    `yaml:"protect"`
+	UI         UI         `yaml:"ui"`
+	HTTP       HTTP       `yaml:"http"`
+
+	// ProtectedTags are glob or /regex/ patterns of tags that are never deleted
+	ProtectedTags []string                 `yaml:"protected_tags"`
+	Packages      map[string]PackageConfig `yaml:"packages"`
+
+	sources map[string]string // setting to where its value came from, see Settings
+}
+
+// Profile is a named account, such as a personal one, an organization's bot
+// or a GitHub Enterprise Server. Empty fields keep the top-level setting.
+type Profile struct {
+	Host         string `yaml:"host"`
+	Owner        string `yaml:"owner"`
+	PackageType  string `yaml:"package_type"`
+	TokenSource  string `yaml:"token_source"`
+	TokenCommand string `yaml:"token_command"`
+}
+
+// PackageConfig overrides settings for a single package
+type PackageConfig struct {
+	// ProtectedTags replaces the global list when set; an empty list
+	// disables tag protection for the package
+	ProtectedTags []string `yaml:"protected_tags"`
+}
+
+// Archive controls copying images to an OCI image layout before deletion
+type Archive struct {
+	Enabled bool   `yaml:"enabled"`
+	Dir     string `yaml:"dir"`
+	Format  string `yaml:"format"` // "layout" or "tar"
+}
+
+// References lists local directories scanned for deployment manifests that
+// reference images, so versions still in use are protected from deletion
+type References struct {
+	Paths []string `yaml:"paths"`
+}
+
+// Guardrails limit how much of a package a single deletion may remove
+type Guardrails struct {
+	MaxPercent       int  `yaml:"max_percent"`       // refuse deleting more than this share of versions, 0 disables
+	MaxVersions      int  `yaml:"max_versions"`      // refuse deleting more than this many versions, 0 disables
+	KeepLastTagged   bool `yaml:"keep_last_tagged"`  // never delete the last remaining tagged version
+	ConfirmThreshold int  `yaml:"confirm_threshold"` // require typing the package name above this many versions, 0 disables
+}
+
+// Filters controls how filter expressions interpret times and where named
+// filters are stored
+type Filters struct {
+	Timezone  string `yaml:"timezone"`   // IANA zone such as Europe/Berlin used to parse dates and display times, empty for local time
+	Timestamp string `yaml:"timestamp"`  // "created" or "updated", compared by age, older, before, after and between
+	SavedFile string `yaml:"saved_file"` // named filters, defaults to filters.yaml in the config directory
+}
+
+// OAuth configures `hij login`, which signs in with the OAuth device flow.
+// The endpoints default to those of Host and can point at a local stand-in.
+type OAuth struct {
+	ClientID      string   `yaml:"client_id"`       // OAuth app with device flow enabled
+	Scopes        []string `yaml:"scopes"`          // default: read:packages, delete:packages
+	DeviceCodeURL string   `yaml:"device_code_url"` // default: https://HOST/login/device/code
+	TokenURL      string   `yaml:"token_url"`       // default: https://HOST/login/oauth/access_token
+}
+
+// App is the GitHub App hij authenticates as when Auth is "app", for
+// unattended cleanup of an organization's packages
+type App struct {
+	ID             int64  `yaml:"id"`
+	PrivateKey     string `yaml:"private_key"`     // PEM file, empty to read the key from the keychain
+	Org            string `yaml:"org"`             // organization whose packages are managed
+	InstallationID int64  `yaml:"installation_id"` // looked up from Org when 0
+}
+
+// Lifecycle maps tags to the pull requests and branches they were built
+// from, so images of closed pull requests and deleted branches can be found.
+// Each pattern is a regex whose first capture group is the pull request
+// number or branch name; an empty pattern disables the lookup.
+type Lifecycle struct {
+	PullRequestTags string `yaml:"pull_request_tags"`
+	BranchTags      string `yaml:"branch_tags"`
+}
+
+// Protect looks up the repository a package is linked to and protects the
+// versions backing its published releases and active deployments
+type Protect struct {
+	Releases    bool `yaml:"releases"`
+	Deployments bool `yaml:"deployments"`
+}
+
+// UI holds the defaults of the interactive mode
+type UI struct {
+	SortOrder         string `yaml:"sort_order"`         // versions order: "newest" or "oldest"
+	PackageSort       string `yaml:"package_sort"`       // "name", "updated" or "versions"
+	FilterPlaceholder string `yaml:"filter_placeholder"` // example shown in the empty filter input
+	OldAfter          string `yaml:"old_after"`          // age at which versions are shown as old, such as 30d or 12h
+}
+
+// HTTP configures requests to the GitHub API
+type HTTP struct {
+	Timeout string `yaml:"timeout"` // per request, such as 30s or 2m
+}
+
+// Default returns the configuration used when no config file exists
+func Default() Config {
+	return Config{
+		Host:        "github.com",
+		Auth:        "token",
+		PackageType: "container",
+		OAuth:       OAuth{Scopes: []string{"read:packages", "delete:packages"}},
+		Archive:     Archive{Format: "layout"},
+		Filters:     Filters{Timestamp: "created"},
+		Protect:     Protect{Releases: true, Deployments: true},
+		Lifecycle: Lifecycle{
+			PullRequestTags: `^pr-(\d+)$`,
+			BranchTags:      `^branch-(.+)$`,
+		},
+		Guardrails: Guardrails{
+			KeepLastTagged:   true,
+			ConfirmThreshold: 10,
+		},
+		UI: UI{
+			SortOrder:         "newest",
+			PackageSort:       "name",
+			FilterPlaceholder: `untagged or (tag~"pr-*" and age>30d)`,
+			OldAfter:          "30d",
+		},
+		HTTP: HTTP{Timeout: "30s"},
+	}
+}
+
+// ConfigPath returns the location of the user's config file
+func ConfigPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFileName), nil
+}
+
+// Load reads the user's config file and the repository's .hij.yaml,
+// returning defaults when they are missing, and applies the default profile
+func Load() (Config, error) {
+	return LoadProfile("")
+}
+
+// LoadProfile reads the user's config file and the repository's .hij.yaml
+// and applies the named profile, or the default one when name is empty
+func LoadProfile(name string) (Config, error) {
+	path, err := ConfigPath()
+	if err != nil {
+		return Default(), err
+	}
+	layers := []layer{{path: path}}
+	if local := findLocalConfig(); local != "" {
+		layers = append(layers, layer{path: local, local: true})
+	}
+	return load(layers, name)
+}
+
+// LoadFile reads the config file at path on top of the defaults and
+// applies the default profile
+func LoadFile(path string) (Config, error) {
+	return loadFile(path, "")
+}
+
+func loadFile(path, profile string) (Config, error) {
+	return load([]layer{{path: path}}, profile)
+}
+
+// useProfile applies the settings of the named profile, or of the default
+// profile when name is empty
+func (c *Config) useProfile(name string) error {
+	if name == "" {
+		name = c.Profile
+	}
+	if name == "" {
+		return nil
+	}
+	p, ok := c.Profiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q", name)
+	}
+	c.Profile = name
+	for _, s := range []struct {
+		key, value string
+		field      *string
+	}{
+		{"host", p.Host, &c.Host},
+		{"owner", p.Owner, &c.Owner},
+		{"package_type", p.PackageType, &c.PackageType},
+		{"token_source", p.TokenSource, &c.TokenSource},
+		{"token_command", p.TokenCommand, &c.TokenCommand},
+	} {
+		if s.value != "" {
+			*s.field = s.value
+			c.setSource(s.key, "profile "+name)
+		}
+	}
+	return nil
+}
+
+// ProfileNames returns the names of the configured profiles in order
+func (c Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (c Config) validate() error {
+	if c.Archive.Format != "layout" && c.Archive.Format != "tar" {
+		return fmt.Errorf("archive.format must be \"layout\" or \"tar\", got %q", c.Archive.Format)
+	}
+	if c.Guardrails.MaxPercent < 0 || c.Guardrails.MaxPercent > 100 {
+		return fmt.Errorf("guardrails.max_percent must be between 0 and 100, got %d", c.Guardrails.MaxPercent)
+	}
+	if c.Guardrails.MaxVersions < 0 || c.Guardrails.ConfirmThreshold < 0 {
+		return fmt.Errorf("guardrails.max_versions and guardrails.confirm_threshold must not be negative")
+	}
+	if _, err := c.Location(); err != nil {
+		return err
+	}
+	if err := checkAccount("", c.PackageType, c.TokenSource); err != nil {
+		return err
+	}
+	for name, p := range c.Profiles {
+		if err := checkAccount("profiles."+name+".", p.PackageType, p.TokenSource); err != nil {
+			return err
+		}
+	}
+	if c.Auth != "token" && c.Auth != "app" {
+		return fmt.Errorf("auth must be \"token\" or \"app\", got %q", c.Auth)
+	}
+	if t := c.Filters.Timestamp; t != "created" && t != "updated" {
+		return fmt.Errorf("filters.timestamp must be \"created\" or \"updated\", got %q", t)
+	}
+	if o := c.UI.SortOrder; o != "newest" && o != "oldest" {
+		return fmt.Errorf("ui.sort_order must be \"newest\" or \"oldest\", got %q", o)
+	}
+	if s := c.UI.PackageSort; s != "name" && s != "updated" && s != "versions" {
+		return fmt.Errorf("ui.package_sort must be \"name\", \"updated\" or \"versions\", got %q", s)
+	}
+	if d, err := parseDuration(c.UI.OldAfter); err != nil || d <= 0 {
+		return fmt.Errorf("ui.old_after must be a duration such as 30d or 12h, got %q", c.UI.OldAfter)
+	}
+	if d, err := parseDuration(c.HTTP.Timeout); err != nil || d <= 0 {
+		return fmt.Errorf("http.timeout must be a duration such as 30s or 2m, got %q", c.HTTP.Timeout)
+	}
+	if err := checkTagPatterns("protected_tags", c.ProtectedTags); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(c.Packages))
+	for name := range c.Packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := checkTagPatterns("packages."+name+".protected_tags", c.Packages[name].ProtectedTags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkTagPatterns reports the first invalid pattern of the setting key
+func checkTagPatterns(key string, patterns []string) error {
+	for _, p := range patterns {
+		if _, err := ParseTagPattern(p); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// ParseTagPattern checks a protected tag pattern, returning the regular
+// expression of a /regex/ pattern and nil for a glob
+func ParseTagPattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag regex %s: %w", pattern, err)
+		}
+		return re, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid tag glob %q: %w", pattern, err)
+	}
+	return nil, nil
+}
+
+// packageTypes are the package types of the GitHub Packages API
+var packageTypes = []string{"container", "npm", "maven", "rubygems", "docker", "nuget"}
+
+// checkAccount validates the package type and token source of the
+// top-level settings or of a profile, whose keys start with prefix
+func checkAccount(prefix, packageType, tokenSource string) error {
+	if packageType != "" && !slices.Contains(packageTypes, packageType) {
+		return fmt.Errorf("%spackage_type must be one of %s, got %q", prefix, strings.Join(packageTypes, ", "), packageType)
+	}
+	switch {
+	case tokenSource == "", tokenSource == "keychain", tokenSource == "gh":
+	case strings.HasPrefix(tokenSource, "env:") && len(tokenSource) > len("env:"):
+	default:
+		return fmt.Errorf("%stoken_source must be \"keychain\", \"gh\" or \"env:NAME\", got %q", prefix, tokenSource)
+	}
+	return nil
+}
+
+// APIURL returns the REST API root of Host
+func (c Config) APIURL() string {
+	if host := c.host(); host != "github.com" {
+		return "https://" + host + "/api/v3"
+	}
+	return "https://api.github.com"
+}
+
+// DeviceCodeURL returns the endpoint that starts the OAuth device flow
+func (c Config) DeviceCodeURL() string {
+	if c.OAuth.DeviceCodeURL != "" {
+		return c.OAuth.DeviceCodeURL
+	}
+	return "https://" + c.host() + "/login/device/code"
+}
+
+// TokenURL returns the endpoint polled for the OAuth access token
+func (c Config) TokenURL() string {
+	if c.OAuth.TokenURL != "" {
+		return c.OAuth.TokenURL
+	}
+	return "https://" + c.host() + "/login/oauth/access_token"
+}
+
+func (c Config) host() string {
+	if c.Host == "" {
+		return "github.com"
+	}
+	return c.Host
+}
+
+// Location returns the timezone used to parse and display times
+func (c Config) Location() (*time.Location, error) {
+	if c.Filters.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.Filters.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("filters.timezone: %w", err)
+	}
+	return loc, nil
+}
+
+// OldAfter returns the age at which versions are shown as old
+func (c Config) OldAfter() time.Duration {
+	if d, err := parseDuration(c.UI.OldAfter); err == nil && d > 0 {
+		return d
+	}
+	return 30 * 24 * time.Hour
+}
+
+// HTTPTimeout returns how long a request to the GitHub API may take
+func (c Config) HTTPTimeout() time.Duration {
+	if d, err := parseDuration(c.HTTP.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return 30 * time.Second
+}
+
+// parseDuration parses a Go duration such as 90s or 12h, or a number of
+// days such as 30d
+func parseDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// AppKeyPath returns the PEM file of the GitHub App's private key, empty
+// when the key is kept in the keychain
+func (c Config) AppKeyPath() string {
+	if c.App.PrivateKey == "" {
+		return ""
+	}
+	return ExpandHome(c.App.PrivateKey)
+}
+
+// SavedFiltersPath returns the file named filters are saved to
+func (c Config) SavedFiltersPath() (string, error) {
+	if c.Filters.SavedFile != "" {
+		return ExpandHome(c.Filters.SavedFile), nil
+	}
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "filters.yaml"), nil
+}
+
+// ArchiveDir returns the directory archives are written to, defaulting to
+// an archive folder in the XDG data directory
+func (c Config) ArchiveDir() (string, error) {
+	if c.Archive.Dir != "" {
+		return ExpandHome(c.Archive.Dir), nil
+	}
+	dir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "archive"), nil
+}
+
+// ReferencePaths returns the directories to scan for image references
+func (c Config) ReferencePaths() []string {
+	paths := make([]string, len(c.References.Paths))
+	for i, p := range c.References.Paths {
+		paths[i] = ExpandHome(p)
+	}
+	return paths
+}
+
+// ProtectedTagsFor returns the protected tag patterns for a package, taking
+// per-package overrides into account
+func (c Config) ProtectedTagsFor(packageName string) []string {
+	if pkg, ok := c.Packages[packageName]; ok && pkg.ProtectedTags != nil {
+		return pkg.ProtectedTags
+	}
+	return c.ProtectedTags
+}
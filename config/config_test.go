@@ -0,0 +1,265 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile_Missing(t *testing.T) {
+	cfg, err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Archive.Enabled {
+		t.Error("archive should be disabled by default")
+	}
+	if cfg.Archive.Format != "layout" {
+		t.Errorf("archive format = %q, want %q", cfg.Archive.Format, "layout")
+	}
+}
+
+func TestLoadFile_Archive(t *testing.T) {
+	path := writeConfig(t, "archive:\n  enabled: true\n  dir: /srv/archive\n  format: tar\n")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Archive.Enabled || cfg.Archive.Format != "tar" {
+		t.Errorf("archive = %+v", cfg.Archive)
+	}
+	dir, err := cfg.ArchiveDir()
+	if err != nil || dir != "/srv/archive" {
+		t.Errorf("ArchiveDir() = %q, %v", dir, err)
+	}
+}
+
+func TestLoadFile_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "bad yaml", content: "archive: [\n"},
+		{name: "bad archive format", content: "archive:\n  format: zip\n"},
+		{name: "bad max percent", content: "guardrails:\n  max_percent: 150\n"},
+		{name: "negative max versions", content: "guardrails:\n  max_versions: -1\n"},
+		{name: "unknown timezone", content: "filters:\n  timezone: Mars/Olympus\n"},
+		{name: "bad timestamp", content: "filters:\n  timestamp: pushed\n"},
+		{name: "unknown auth", content: "auth: password\n"},
+		{name: "bad package type", content: "package_type: pypi\n"},
+		{name: "bad profile token source", content: "profiles:\n  work:\n    token_source: vault\n"},
+		{name: "unknown default profile", content: "profile: work\n"},
+		{name: "bad sort order", content: "ui:\n  sort_order: random\n"},
+		{name: "bad old after", content: "ui:\n  old_after: a month\n"},
+		{name: "zero timeout", content: "http:\n  timeout: 0s\n"},
+		{name: "bad protected tag glob", content: "protected_tags: [\"v[\"]\n"},
+		{name: "bad package protected tag regex", content: "packages:\n  app:\n    protected_tags: [\"/(/\"]\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadFile(writeConfig(t, tt.content)); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestConfig_ArchiveDirDefault(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", "/tmp/data")
+
+	dir, err := Default().ArchiveDir()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join("/tmp/data", "hij", "archive"); dir != want {
+		t.Errorf("ArchiveDir() = %q, want %q", dir, want)
+	}
+}
+
+func TestLoadFile_References(t *testing.T) {
+	t.Setenv("HOME", "/home/tester")
+	path := writeConfig(t, "references:\n  paths:\n    - ~/deploy\n    - ./k8s\n")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	paths := cfg.ReferencePaths()
+	if len(paths) != 2 || paths[0] != filepath.Join("/home/tester", "deploy") || paths[1] != "./k8s" {
+		t.Errorf("ReferencePaths() = %v", paths)
+	}
+}
+
+func TestLoadFile_Guardrails(t *testing.T) {
+	path := writeConfig(t, "guardrails:\n  max_percent: 50\n  keep_last_tagged: false\n")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	g := cfg.Guardrails
+	if g.MaxPercent != 50 || g.KeepLastTagged {
+		t.Errorf("guardrails = %+v", g)
+	}
+	// Unset keys keep their defaults
+	if g.ConfirmThreshold != 10 {
+		t.Errorf("confirm threshold = %d, want default 10", g.ConfirmThreshold)
+	}
+}
+
+func TestLoadFile_Filters(t *testing.T) {
+	cfg, err := LoadFile(writeConfig(t, "filters:\n  timezone: UTC\n  timestamp: updated\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Filters.Timestamp != "updated" {
+		t.Errorf("timestamp = %q, want updated", cfg.Filters.Timestamp)
+	}
+	loc, err := cfg.Location()
+	if err != nil || loc != time.UTC {
+		t.Errorf("Location() = %v, %v, want UTC", loc, err)
+	}
+
+	if loc, _ := Default().Location(); loc != time.Local {
+		t.Errorf("default location = %v, want local time", loc)
+	}
+}
+
+func TestConfig_ProtectedTagsFor(t *testing.T) {
+	path := writeConfig(t, `protected_tags: [latest, "v*"]
+packages:
+  web:
+    protected_tags: [stable]
+  scratch:
+    protected_tags: []
+  api: {}
+`)
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		pkg  string
+		want []string
+	}{
+		{pkg: "app", want: []string{"latest", "v*"}},
+		{pkg: "api", want: []string{"latest", "v*"}},
+		{pkg: "web", want: []string{"stable"}},
+		{pkg: "scratch", want: []string{}},
+	}
+	for _, tt := range tests {
+		got := cfg.ProtectedTagsFor(tt.pkg)
+		if len(got) != len(tt.want) {
+			t.Errorf("ProtectedTagsFor(%q) = %v, want %v", tt.pkg, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("ProtectedTagsFor(%q) = %v, want %v", tt.pkg, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestConfig_Endpoints(t *testing.T) {
+	cfg := Default()
+	if got := cfg.APIURL(); got != "https://api.github.com" {
+		t.Errorf("APIURL() = %q", got)
+	}
+	if got := cfg.DeviceCodeURL(); got != "https://github.com/login/device/code" {
+		t.Errorf("DeviceCodeURL() = %q", got)
+	}
+
+	path := writeConfig(t, "host: ghe.example.com\noauth:\n  client_id: abc\n  token_url: http://127.0.0.1:8080/token\n")
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := cfg.APIURL(); got != "https://ghe.example.com/api/v3" {
+		t.Errorf("APIURL() = %q", got)
+	}
+	if got := cfg.DeviceCodeURL(); got != "https://ghe.example.com/login/device/code" {
+		t.Errorf("DeviceCodeURL() = %q", got)
+	}
+	if got := cfg.TokenURL(); got != "http://127.0.0.1:8080/token" {
+		t.Errorf("TokenURL() = %q, want the configured stand-in", got)
+	}
+	if cfg.OAuth.ClientID != "abc" || len(cfg.OAuth.Scopes) != 2 {
+		t.Errorf("oauth = %+v, want the default scopes kept", cfg.OAuth)
+	}
+}
+
+func TestLoadFile_App(t *testing.T) {
+	path := writeConfig(t, "auth: app\napp:\n  id: 42\n  private_key: /etc/hij/app.pem\n  org: acme\n")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Auth != "app" || cfg.App.ID != 42 || cfg.App.Org != "acme" || cfg.AppKeyPath() != "/etc/hij/app.pem" {
+		t.Errorf("auth = %q, app = %+v", cfg.Auth, cfg.App)
+	}
+	if Default().Auth != "token" {
+		t.Errorf("default auth = %q, want token", Default().Auth)
+	}
+}
+
+func TestLoadFile_Profiles(t *testing.T) {
+	path := writeConfig(t, `owner: octo
+profile: personal
+profiles:
+  personal: {}
+  work:
+    owner: acme
+    package_type: npm
+    token_source: env:ACME_TOKEN
+  ghes:
+    host: ghe.example.com
+`)
+	t.Setenv("HIJ_PROFILE", "")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Profile != "personal" || cfg.Owner != "octo" || cfg.PackageType != "container" || cfg.Host != "github.com" {
+		t.Errorf("default profile = %q, owner %q, type %q, host %q", cfg.Profile, cfg.Owner, cfg.PackageType, cfg.Host)
+	}
+	if names := cfg.ProfileNames(); len(names) != 3 || names[0] != "ghes" || names[2] != "work" {
+		t.Errorf("ProfileNames() = %v", names)
+	}
+
+	t.Setenv("HIJ_PROFILE", "work")
+	cfg, err = LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Profile != "work" || cfg.Owner != "acme" || cfg.PackageType != "npm" {
+		t.Errorf("work profile = %q, owner %q, type %q", cfg.Profile, cfg.Owner, cfg.PackageType)
+	}
+	t.Setenv("ACME_TOKEN", "ghp_acme")
+	if token, source, _ := cfg.Token(); token != "ghp_acme" || source != "ACME_TOKEN" {
+		t.Errorf("Token() = %q, %q", token, source)
+	}
+
+	if cfg, err = loadFile(path, "ghes"); err != nil || cfg.APIURL() != "https://ghe.example.com/api/v3" || cfg.Owner != "octo" {
+		t.Errorf("ghes profile: API %q, owner %q, err %v", cfg.APIURL(), cfg.Owner, err)
+	}
+	if _, err := loadFile(path, "missing"); err == nil {
+		t.Error("expected an error for an unknown profile")
+	}
+}
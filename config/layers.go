@@ -0,0 +1,305 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// localConfigName is the repository's config file, read on top of the user's
+const localConfigName = ".hij.yaml"
+
+// localForbidden are the settings a repository's .hij.yaml cannot change,
+// since they decide where the token is sent, run commands, whose packages
+// are deleted or where files are written
+var localForbidden = []string{"host", "oauth", "auth", "app", "token_source", "token_command", "profile", "profiles", "owner", "archive.dir", "filters.saved_file"}
+
+// layer is a config file read on top of the defaults
+type layer struct {
+	path  string
+	local bool // a repository's .hij.yaml
+}
+
+// override is a setting given in the environment or on the command line
+type override struct {
+	key, value string
+	source     string // "env NAME" or "flag --NAME"
+}
+
+// flagOverrides are the settings given as global flags, see SetFlag
+var flagOverrides []override
+
+// SetFlag overrides the setting key, such as owner or ui.sort_order, for
+// every later Load. flag is the flag it was given with, shown as its source.
+func SetFlag(flag, key, value string) error {
+	if !slices.Contains(settingKeys(), key) {
+		return fmt.Errorf("unknown setting %q", key)
+	}
+	flagOverrides = append(flagOverrides, override{key: key, value: value, source: "flag " + flag})
+	return nil
+}
+
+// findLocalConfig looks for .hij.yaml in the working directory and its
+// parents, up to the root of the git repository
+func findLocalConfig() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		path := filepath.Join(dir, localConfigName)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// load builds the configuration from, in increasing precedence, the
+// defaults, the layers, the chosen profile, environment variables named
+// HIJ_ plus the setting in upper case, such as HIJ_UI_SORT_ORDER, and flags
+func load(layers []layer, profile string) (Config, error) {
+	cfg := Default()
+	cfg.sources = make(map[string]string)
+	for _, l := range layers {
+		data, err := os.ReadFile(l.path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return Default(), err
+		}
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return Default(), fmt.Errorf("%s: %w", l.path, err)
+		}
+		if len(doc.Content) == 0 {
+			continue // empty file
+		}
+		var keys []string
+		flatten(doc.Content[0], "", func(key string, _ *yaml.Node) { keys = append(keys, key) })
+		before := cfg
+		if l.local {
+			for _, key := range keys {
+				if forbidden(key) {
+					return Default(), fmt.Errorf("%s: %s can only be set in %s", l.path, key, configFileName)
+				}
+			}
+			before.Packages = maps.Clone(cfg.Packages) // decoding adds to the map
+		}
+		if err := doc.Decode(&cfg); err != nil {
+			return Default(), fmt.Errorf("%s: %w", l.path, err)
+		}
+		if l.local {
+			if err := cfg.onlyTighten(before); err != nil {
+				return Default(), fmt.Errorf("%s: %w", l.path, err)
+			}
+		}
+		for _, key := range keys {
+			cfg.sources[key] = l.path
+		}
+	}
+
+	overrides := append(envOverrides(), flagOverrides...)
+	for _, o := range overrides {
+		if err := cfg.apply(o); err != nil {
+			return Default(), err
+		}
+	}
+	if err := cfg.validate(); err != nil {
+		return cfg, err
+	}
+	if err := cfg.useProfile(profile); err != nil {
+		return cfg, err
+	}
+	// Overrides win over the profile, except for choosing it
+	for _, o := range overrides {
+		if o.key != "profile" {
+			if err := cfg.apply(o); err != nil {
+				return cfg, err
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// forbidden reports whether key is a setting .hij.yaml cannot change
+func forbidden(key string) bool {
+	for _, f := range localForbidden {
+		if key == f || strings.HasPrefix(key, f+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// onlyTighten keeps a repository's .hij.yaml from weakening the protections
+// configured before it: protections cannot be turned off and limits can only
+// be lowered, while protected tags and manifest paths are added to the ones
+// already set
+func (c *Config) onlyTighten(before Config) error {
+	for _, p := range []struct {
+		key     string
+		was, is bool
+	}{
+		{"protect.releases", before.Protect.Releases, c.Protect.Releases},
+		{"protect.deployments", before.Protect.Deployments, c.Protect.Deployments},
+		{"guardrails.keep_last_tagged", before.Guardrails.KeepLastTagged, c.Guardrails.KeepLastTagged},
+		{"archive.enabled", before.Archive.Enabled, c.Archive.Enabled},
+	} {
+		if p.was && !p.is {
+			return fmt.Errorf("%s can only be turned off in %s", p.key, configFileName)
+		}
+	}
+	for _, l := range []struct {
+		key     string
+		was, is int
+	}{
+		{"guardrails.max_percent", before.Guardrails.MaxPercent, c.Guardrails.MaxPercent},
+		{"guardrails.max_versions", before.Guardrails.MaxVersions, c.Guardrails.MaxVersions},
+		{"guardrails.confirm_threshold", before.Guardrails.ConfirmThreshold, c.Guardrails.ConfirmThreshold},
+	} {
+		// 0 turns a limit off, the loosest of all
+		if l.was != 0 && (l.is == 0 || l.is > l.was) {
+			return fmt.Errorf("%s can only be lowered from %d here, raise or turn it off in %s", l.key, l.was, configFileName)
+		}
+	}
+
+	c.ProtectedTags = union(before.ProtectedTags, c.ProtectedTags)
+	c.References.Paths = union(before.References.Paths, c.References.Paths)
+	for name, pkg := range c.Packages {
+		switch old := before.Packages[name].ProtectedTags; {
+		case old != nil:
+			pkg.ProtectedTags = union(old, pkg.ProtectedTags)
+		case pkg.ProtectedTags != nil:
+			// The package's own list replaces the global one, so it keeps
+			// the global patterns it protected with until now
+			pkg.ProtectedTags = union(c.ProtectedTags, pkg.ProtectedTags)
+		}
+		c.Packages[name] = pkg
+	}
+	return nil
+}
+
+// union returns a copy of a followed by the values of b missing from a
+func union(a, b []string) []string {
+	out := slices.Clone(a)
+	for _, v := range b {
+		if !slices.Contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// envOverrides returns the settings given as HIJ_ environment variables
+func envOverrides() []override {
+	var overrides []override
+	for _, key := range settingKeys() {
+		name := "HIJ_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+		if value, ok := os.LookupEnv(name); ok && value != "" {
+			overrides = append(overrides, override{key: key, value: value, source: "env " + name})
+		}
+	}
+	return overrides
+}
+
+// apply sets the setting of o, decoding its value like the config file would
+func (c *Config) apply(o override) error {
+	node := &yaml.Node{Kind: yaml.ScalarNode, Value: o.value}
+	parts := strings.Split(o.key, ".")
+	for i := len(parts) - 1; i >= 0; i-- {
+		node = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: parts[i]},
+			node,
+		}}
+	}
+	if err := node.Decode(c); err != nil {
+		return fmt.Errorf("%s: invalid %s %q", o.source, o.key, o.value)
+	}
+	c.setSource(o.key, o.source)
+	return nil
+}
+
+func (c *Config) setSource(key, source string) {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	c.sources[key] = source
+}
+
+// settingKeys returns the settings that take a single value, which can be
+// overridden by environment variables and flags
+func settingKeys() []string {
+	var doc yaml.Node
+	if err := doc.Encode(Default()); err != nil {
+		return nil
+	}
+	var keys []string
+	flatten(&doc, "", func(key string, value *yaml.Node) {
+		if value.Kind == yaml.ScalarNode {
+			keys = append(keys, key)
+		}
+	})
+	return keys
+}
+
+// flatten calls fn with the dotted key and value of every setting in node
+func flatten(node *yaml.Node, prefix string, fn func(key string, value *yaml.Node)) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := prefix+node.Content[i].Value, node.Content[i+1]
+		if value.Kind == yaml.MappingNode && len(value.Content) > 0 {
+			flatten(value, key+".", fn)
+			continue
+		}
+		fn(key, value)
+	}
+}
+
+// Setting is a value of the effective configuration
+type Setting struct {
+	Key    string // such as guardrails.max_percent
+	Value  string
+	Source string // "default", a config file, "profile NAME", "env NAME" or "flag --NAME"
+}
+
+// Settings returns every value of the configuration and where it came from
+func (c Config) Settings() ([]Setting, error) {
+	var doc yaml.Node
+	if err := doc.Encode(c); err != nil {
+		return nil, err
+	}
+	var settings []Setting
+	var err error
+	flatten(&doc, "", func(key string, value *yaml.Node) {
+		s := Setting{Key: key, Value: value.Value, Source: c.sources[key]}
+		if s.Source == "" {
+			s.Source = "default"
+		}
+		switch {
+		case value.Kind != yaml.ScalarNode:
+			value.Style = yaml.FlowStyle
+			var out []byte
+			if out, err = yaml.Marshal(value); err == nil {
+				s.Value = strings.TrimSpace(string(out))
+			}
+		case value.Value == "":
+			s.Value = `""`
+		}
+		settings = append(settings, s)
+	})
+	return settings, err
+}
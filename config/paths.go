@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const appDirName = "hij"
+
+// StateDir returns the directory hij keeps persistent state in, such as the
+// audit log. It follows the XDG base directory spec on every platform:
+// $XDG_STATE_HOME/hij, falling back to ~/.local/state/hij.
+func StateDir() (string, error) {
+	return xdgDir("XDG_STATE_HOME", ".local", "state")
+}
+
+// ConfigDir returns the directory holding hij's configuration files:
+// $XDG_CONFIG_HOME/hij, falling back to ~/.config/hij.
+func ConfigDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// DataDir returns the directory for user data such as image archives:
+// $XDG_DATA_HOME/hij, falling back to ~/.local/share/hij.
+func DataDir() (string, error) {
+	return xdgDir("XDG_DATA_HOME", ".local", "share")
+}
+
+// ExpandHome replaces a leading ~ in path with the user's home directory
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// xdgDir resolves an XDG base directory from envVar, or from the given path
+// elements below the user's home directory when the variable is unset.
+func xdgDir(envVar string, fallback ...string) (string, error) {
+	if dir := os.Getenv(envVar); dir != "" {
+		return filepath.Join(dir, appDirName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append(append([]string{home}, fallback...), appDirName)...), nil
+}
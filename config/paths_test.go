@@ -0,0 +1,51 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStateDir_FromEnvVar(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "/tmp/state")
+
+	dir, err := StateDir()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join("/tmp/state", "hij"); dir != want {
+		t.Errorf("dir = %q, want %q", dir, want)
+	}
+}
+
+func TestStateDir_Fallback(t *testing.T) {
+	t.Setenv("XDG_STATE_HOME", "")
+	t.Setenv("HOME", "/home/tester")
+
+	dir, err := StateDir()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := filepath.Join("/home/tester", ".local", "state", "hij"); dir != want {
+		t.Errorf("dir = %q, want %q", dir, want)
+	}
+}
+
+func TestExpandHome(t *testing.T) {
+	t.Setenv("HOME", "/home/tester")
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "~", want: "/home/tester"},
+		{input: "~/archive", want: filepath.Join("/home/tester", "archive")},
+		{input: "/abs/path", want: "/abs/path"},
+		{input: "~other/path", want: "~other/path"},
+	}
+
+	for _, tt := range tests {
+		if got := ExpandHome(tt.input); got != tt.want {
+			t.Errorf("ExpandHome(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	tokenFileName = "tokens.enc"
+	passphraseEnv = "HIJ_TOKEN_PASSPHRASE"
+	storeEnv      = "HIJ_TOKEN_STORE"
+)
+
+// SourceTokenFile is the encrypted file saved tokens are kept in when no
+// keychain is available
+const SourceTokenFile = "encrypted file"
+
+// secretStore keeps saved tokens and keys. Missing items are reported as
+// keyring.ErrNotFound by every store.
+type secretStore interface {
+	Get(item string) (string, error)
+	Set(item, secret string) error
+	Delete(item string) error
+}
+
+type keyringStore struct{}
+
+func (keyringStore) Get(item string) (string, error) { return keyring.Get(keyringService, item) }
+func (keyringStore) Set(item, secret string) error   { return keyring.Set(keyringService, item, secret) }
+func (keyringStore) Delete(item string) error        { return keyring.Delete(keyringService, item) }
+
+// store returns where tokens are saved: the system keychain, or the
+// encrypted file when there is none, such as over SSH or on build machines
+// without a Secret Service. HIJ_TOKEN_STORE=keychain or file forces one.
+func store() secretStore {
+	switch os.Getenv(storeEnv) {
+	case "file":
+		return fileStore{}
+	case "keychain":
+		return keyringStore{}
+	}
+	if keyringAvailable() {
+		return keyringStore{}
+	}
+	return fileStore{}
+}
+
+// keyringAvailable reports whether the system keychain answers at all. It
+// asks once per process, since a missing Secret Service can take a while to
+// time out.
+var keyringAvailable = sync.OnceValue(probeKeyring)
+
+// probeKeyring asks the keychain for the token, a missing item being a
+// valid answer
+func probeKeyring() bool {
+	_, err := keyring.Get(keyringService, keyringUser)
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
+
+// TokenStore returns where saved tokens are kept, SourceKeychain or
+// SourceTokenFile
+func TokenStore() string {
+	if _, ok := store().(fileStore); ok {
+		return SourceTokenFile
+	}
+	return SourceKeychain
+}
+
+// DescribeTokenStore explains where saved tokens are kept and, for the
+// encrypted file, where it is and what its key is derived from
+func DescribeTokenStore() string {
+	if TokenStore() == SourceKeychain {
+		return "system keychain"
+	}
+	path, err := TokenFilePath()
+	if err != nil {
+		return SourceTokenFile
+	}
+	key := "machine key"
+	if os.Getenv(passphraseEnv) != "" {
+		key = "passphrase from " + passphraseEnv
+	}
+	return fmt.Sprintf("%s %s (%s)", SourceTokenFile, path, key)
+}
+
+// TokenFilePath returns the encrypted token file: tokens.enc in ConfigDir
+func TokenFilePath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, tokenFileName), nil
+}
+
+// fileStore keeps secrets in a file encrypted with AES-256-GCM. The key is
+// derived with scrypt from HIJ_TOKEN_PASSPHRASE, or from the machine ID and
+// user name when no passphrase is set, which only protects copies of the
+// file taken to another machine.
+type fileStore struct{}
+
+// tokenFile is the encrypted file on disk. Salt and nonce are renewed on
+// every write.
+type tokenFile struct {
+	Version int    `json:"version"`
+	Key     string `json:"key"` // "passphrase" or "machine"
+	Salt    []byte `json:"salt"`
+	Nonce   []byte `json:"nonce"`
+	Data    []byte `json:"data"`
+}
+
+func (fileStore) Get(item string) (string, error) {
+	secrets, err := readTokenFile()
+	if err != nil {
+		return "", err
+	}
+	secret, ok := secrets[item]
+	if !ok {
+		return "", keyring.ErrNotFound
+	}
+	return secret, nil
+}
+
+func (fileStore) Set(item, secret string) error {
+	secrets, err := readTokenFile()
+	if err != nil {
+		return err
+	}
+	secrets[item] = secret
+	return writeTokenFile(secrets)
+}
+
+func (fileStore) Delete(item string) error {
+	secrets, err := readTokenFile()
+	if err != nil {
+		return err
+	}
+	if _, ok := secrets[item]; !ok {
+		return keyring.ErrNotFound
+	}
+	delete(secrets, item)
+	return writeTokenFile(secrets)
+}
+
+// readTokenFile decrypts the token file, returning no secrets when it does
+// not exist yet
+func readTokenFile() (map[string]string, error) {
+	path, err := TokenFilePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return make(map[string]string), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f tokenFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid token file %s: %w", path, err)
+	}
+	if f.Version != 1 {
+		return nil, fmt.Errorf("token file %s has unsupported version %d", path, f.Version)
+	}
+	secret, err := fileSecret(f.Key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt %s: %w", path, err)
+	}
+	aead, err := fileCipher(secret, f.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := aead.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		if f.Key == "passphrase" {
+			return nil, fmt.Errorf("cannot decrypt %s: wrong %s", path, passphraseEnv)
+		}
+		return nil, fmt.Errorf("cannot decrypt %s: it was written on another machine or by another user", path)
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("invalid token file %s: %w", path, err)
+	}
+	return secrets, nil
+}
+
+// writeTokenFile encrypts secrets with the passphrase if one is set, and
+// replaces the token file atomically
+func writeTokenFile(secrets map[string]string) error {
+	path, err := TokenFilePath()
+	if err != nil {
+		return err
+	}
+	f := tokenFile{Version: 1, Key: "machine", Salt: make([]byte, 16)}
+	if os.Getenv(passphraseEnv) != "" {
+		f.Key = "passphrase"
+	}
+	secret, err := fileSecret(f.Key)
+	if err != nil {
+		return err
+	}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	aead, err := fileCipher(secret, f.Salt)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	f.Data = aead.Seal(nil, f.Nonce, plain, nil)
+
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// fileSecret returns what the key of the token file is derived from
+func fileSecret(kind string) (string, error) {
+	if kind == "passphrase" {
+		passphrase := os.Getenv(passphraseEnv)
+		if passphrase == "" {
+			return "", fmt.Errorf("the token file is encrypted with a passphrase, set %s", passphraseEnv)
+		}
+		return passphrase, nil
+	}
+	return machineKey()
+}
+
+// fileCipher derives the AES-256-GCM cipher of the token file from secret
+func fileCipher(secret string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(secret), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// machineKey identifies this machine and user, replaced in tests
+var machineKey = func() (string, error) {
+	var id string
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if data, err := os.ReadFile(path); err == nil {
+			id = strings.TrimSpace(string(data))
+			break
+		}
+	}
+	if id == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return "", fmt.Errorf("no machine ID to derive the key from, set %s", passphraseEnv)
+		}
+		id = host
+	}
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	return "hij\x00" + id + "\x00" + u.Uid, nil
+}
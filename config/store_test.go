@@ -0,0 +1,115 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// useMachine replaces the machine key the token file is encrypted with
+func useMachine(t *testing.T, key string) {
+	t.Helper()
+	orig := machineKey
+	machineKey = func() (string, error) { return key, nil }
+	t.Cleanup(func() { machineKey = orig })
+}
+
+// mockKeyring replaces the system keychain with one in memory, or with one
+// failing with err, and forgets whether the real one was available
+func mockKeyring(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		keyring.MockInitWithError(err)
+	} else {
+		keyring.MockInit()
+	}
+	keyringAvailable = sync.OnceValue(probeKeyring)
+	t.Cleanup(func() { keyringAvailable = sync.OnceValue(probeKeyring) })
+}
+
+func TestTokenStore_FallsBackToFile(t *testing.T) {
+	isolateTokens(t)
+	useMachine(t, "machine-a")
+	mockKeyring(t, errors.New("The name org.freedesktop.secrets was not provided"))
+
+	if store := TokenStore(); store != SourceTokenFile {
+		t.Fatalf("TokenStore() = %q, want the file without a keychain", store)
+	}
+	if err := SaveToken("work", "ghp_work"); err != nil {
+		t.Fatalf("SaveToken() error = %v", err)
+	}
+	if token, source := GetToken("github.com", "work"); token != "ghp_work" || source != SourceTokenFile {
+		t.Errorf("GetToken() = %q, %q, want the saved token from the file", token, source)
+	}
+
+	path, _ := TokenFilePath()
+	if desc := DescribeTokenStore(); !strings.Contains(desc, path) || !strings.Contains(desc, "machine key") {
+		t.Errorf("DescribeTokenStore() = %q, want the path and the key", desc)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("token file mode = %v, want 0600", info.Mode().Perm())
+	}
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "ghp_work") {
+		t.Error("token file should not contain the token in plain text")
+	}
+
+	useMachine(t, "machine-b")
+	if _, err := SavedToken("work"); err == nil || !strings.Contains(err.Error(), "another machine") {
+		t.Errorf("SavedToken() error = %v, want the file unreadable elsewhere", err)
+	}
+}
+
+func TestFileStore_Passphrase(t *testing.T) {
+	isolateTokens(t)
+	t.Setenv(storeEnv, "file")
+	t.Setenv(passphraseEnv, "correct horse")
+
+	if err := SaveToken("", "ghp_one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveAppKey("PEM"); err != nil {
+		t.Fatal(err)
+	}
+	if token, err := SavedToken(""); err != nil || token != "ghp_one" {
+		t.Errorf("SavedToken() = %q, %v", token, err)
+	}
+	if err := DeleteToken(""); err != nil {
+		t.Fatalf("DeleteToken() error = %v", err)
+	}
+	if _, err := SavedToken(""); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("SavedToken() error = %v, want ErrTokenNotFound", err)
+	}
+	if key, err := GetAppKey(); err != nil || key != "PEM" {
+		t.Errorf("GetAppKey() = %q, %v, want the key kept", key, err)
+	}
+
+	t.Setenv(passphraseEnv, "wrong")
+	if _, err := GetAppKey(); err == nil || !strings.Contains(err.Error(), "wrong "+passphraseEnv) {
+		t.Errorf("GetAppKey() error = %v, want a wrong passphrase", err)
+	}
+	t.Setenv(passphraseEnv, "")
+	if _, err := GetAppKey(); err == nil || !strings.Contains(err.Error(), "set "+passphraseEnv) {
+		t.Errorf("GetAppKey() error = %v, want the passphrase asked for", err)
+	}
+}
+
+func TestTokenStore_ProbesKeychainOnce(t *testing.T) {
+	isolateTokens(t)
+	mockKeyring(t, errors.New("The name org.freedesktop.secrets was not provided"))
+
+	if store := TokenStore(); store != SourceTokenFile {
+		t.Fatalf("TokenStore() = %q, want the file without a keychain", store)
+	}
+	keyring.MockInit() // the keychain would answer now, but is not asked again
+	if store := TokenStore(); store != SourceTokenFile {
+		t.Errorf("TokenStore() = %q, want the first answer kept", store)
+	}
+}
@@ -1,39 +1,226 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
 
 	"github.com/zalando/go-keyring"
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	envVarName     = "HIJ_GITHUB_TOKEN"
 	keyringService = "hij"
 	keyringUser    = "github-token"
+	keyringAppKey  = "github-app-key"
 )
 
-// GetToken retrieves the token from environment variable or keychain.
-// Returns the token and its source ("env", "keychain", or "" if not found).
-func GetToken() (string, string) {
-	// Check environment variable first
+// Token sources besides environment variables, which are reported by name
+const (
+	SourceKeychain     = "keychain"
+	SourceGHHosts      = "gh hosts.yml"
+	SourceGHAuthToken  = "gh auth token"
+	SourceTokenCommand = "token_command"
+)
+
+// Token returns the token of the active profile and where it was found,
+// honouring TokenCommand and TokenSource. Only a failing token_command
+// returns an error; a missing token is reported as "".
+func (c Config) Token() (string, string, error) {
+	if c.TokenCommand != "" {
+		token, err := c.commandToken()
+		if err != nil {
+			return "", "", err
+		}
+		return token, SourceTokenCommand, nil
+	}
+
+	host := c.host()
+	switch src := c.TokenSource; {
+	case src == "keychain":
+		if token, err := store().Get(keyringUserFor(c.Profile)); err == nil && token != "" {
+			return token, TokenStore(), nil
+		}
+	case src == "gh":
+		if token := ghHostsToken(host); token != "" {
+			return token, SourceGHHosts, nil
+		}
+		if token, err := ghAuthToken(host); err == nil && token != "" {
+			return token, SourceGHAuthToken, nil
+		}
+	case strings.HasPrefix(src, "env:"):
+		name := strings.TrimPrefix(src, "env:")
+		if token := os.Getenv(name); token != "" {
+			return token, name, nil
+		}
+	default:
+		token, source := GetToken(host, c.Profile)
+		return token, source, nil
+	}
+	return "", "", nil
+}
+
+// GetToken retrieves the token for host, checking in order:
+//
+//  1. the HIJ_GITHUB_TOKEN environment variable
+//  2. the system keychain item of profile, or the encrypted token file
+//     when there is no keychain
+//  3. GH_TOKEN and GITHUB_TOKEN, or GH_ENTERPRISE_TOKEN and
+//     GITHUB_ENTERPRISE_TOKEN for hosts other than github.com, as the gh CLI
+//     does. They come after the saved token since shells and CI jobs often
+//     export them for other tools.
+//  4. the host's entry in the gh CLI's hosts.yml
+//  5. the output of `gh auth token`, for gh versions keeping tokens in the keychain
+//
+// Returns the token and its source, the name of the environment variable or
+// one of the Source constants, or "" if not found.
+func GetToken(host, profile string) (string, string) {
+	if host == "" {
+		host = "github.com"
+	}
+
 	if token := os.Getenv(envVarName); token != "" {
-		return token, "env"
+		return token, envVarName
+	}
+
+	if token, err := store().Get(keyringUserFor(profile)); err == nil && token != "" {
+		return token, TokenStore()
 	}
 
-	// Check keychain
-	if token, err := keyring.Get(keyringService, keyringUser); err == nil && token != "" {
-		return token, "keychain"
+	envVars := []string{"GH_TOKEN", "GITHUB_TOKEN"}
+	if host != "github.com" {
+		envVars = []string{"GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"}
+	}
+	for _, name := range envVars {
+		if token := os.Getenv(name); token != "" {
+			return token, name
+		}
+	}
+
+	if token := ghHostsToken(host); token != "" {
+		return token, SourceGHHosts
+	}
+
+	if token, err := ghAuthToken(host); err == nil && token != "" {
+		return token, SourceGHAuthToken
 	}
 
 	return "", ""
 }
 
-// SaveToken saves the token to the system keychain.
-func SaveToken(token string) error {
-	return keyring.Set(keyringService, keyringUser, token)
+// ghHost is an entry of the gh CLI's hosts.yml. Older versions keep the
+// token at the top level, newer ones per user unless it is in the keychain.
+type ghHost struct {
+	OAuthToken string `yaml:"oauth_token"`
+	User       string `yaml:"user"`
+	Users      map[string]struct {
+		OAuthToken string `yaml:"oauth_token"`
+	} `yaml:"users"`
+}
+
+// ghHostsToken returns the token the gh CLI stored in plain text for host
+func ghHostsToken(host string) string {
+	dir, err := ghConfigDir()
+	if err != nil {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "hosts.yml"))
+	if err != nil {
+		return ""
+	}
+	var hosts map[string]ghHost
+	if err := yaml.Unmarshal(data, &hosts); err != nil {
+		return ""
+	}
+	h := hosts[host]
+	if h.OAuthToken != "" {
+		return h.OAuthToken
+	}
+	return h.Users[h.User].OAuthToken
+}
+
+// ghConfigDir returns the gh CLI's configuration directory
+func ghConfigDir() (string, error) {
+	if dir := os.Getenv("GH_CONFIG_DIR"); dir != "" {
+		return dir, nil
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "gh"), nil
+	}
+	if dir := os.Getenv("AppData"); runtime.GOOS == "windows" && dir != "" {
+		return filepath.Join(dir, "GitHub CLI"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", "gh"), nil
+}
+
+// ghAuthToken asks the gh CLI for its token for host, replaced in tests
+var ghAuthToken = func(host string) (string, error) {
+	if _, err := exec.LookPath("gh"); err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "gh", "auth", "token", "--hostname", host).Output()
+	if err != nil {
+		return "", errors.New("gh is not logged in to " + host)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// keyringUserFor returns the keychain item holding the token of profile
+func keyringUserFor(profile string) string {
+	if profile == "" {
+		return keyringUser
+	}
+	return keyringUser + "/" + profile
+}
+
+// ErrTokenNotFound is returned when no token of the profile is saved
+var ErrTokenNotFound = errors.New("no token saved")
+
+// SavedToken returns the token of profile saved in the system keychain or
+// the encrypted token file.
+func SavedToken(profile string) (string, error) {
+	token, err := store().Get(keyringUserFor(profile))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrTokenNotFound
+	}
+	return token, err
+}
+
+// SaveToken saves the token of profile to the system keychain, or the
+// encrypted token file when there is no keychain, replacing any token saved
+// before.
+func SaveToken(profile, token string) error {
+	return store().Set(keyringUserFor(profile), token)
+}
+
+// DeleteToken removes the saved token of profile.
+func DeleteToken(profile string) error {
+	err := store().Delete(keyringUserFor(profile))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return ErrTokenNotFound
+	}
+	return err
+}
+
+// GetAppKey retrieves the GitHub App private key from the system keychain
+// or the encrypted token file.
+func GetAppKey() (string, error) {
+	return store().Get(keyringAppKey)
 }
 
-// DeleteToken removes the token from the system keychain.
-func DeleteToken() error {
-	return keyring.Delete(keyringService, keyringUser)
+// SaveAppKey saves the PEM-encoded GitHub App private key next to the tokens.
+func SaveAppKey(pem string) error {
+	return store().Set(keyringAppKey, pem)
 }
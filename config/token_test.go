@@ -1,41 +1,123 @@
 package config
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
+// isolateTokens clears every token source and replaces the keychain, the
+// token file and the gh CLI with stand-ins
+func isolateTokens(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{envVarName, "GH_TOKEN", "GITHUB_TOKEN", "GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN"} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("GH_CONFIG_DIR", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv(storeEnv, "")
+	t.Setenv(passphraseEnv, "")
+	mockKeyring(t, nil)
+
+	orig := ghAuthToken
+	ghAuthToken = func(host string) (string, error) { return "", errors.New("gh is not installed") }
+	t.Cleanup(func() { ghAuthToken = orig })
+}
+
 func TestGetToken_FromEnvVar(t *testing.T) {
-	// Set up
+	isolateTokens(t)
 	const testToken = "ghp_test_token_123"
-	os.Setenv("HIJ_GITHUB_TOKEN", testToken)
-	defer os.Unsetenv("HIJ_GITHUB_TOKEN")
+	t.Setenv("HIJ_GITHUB_TOKEN", testToken)
+	t.Setenv("GH_TOKEN", "ghp_gh")
 
-	// Test
-	token, source := GetToken()
+	token, source := GetToken("github.com", "")
 
-	// Verify
 	if token != testToken {
 		t.Errorf("token = %q, want %q", token, testToken)
 	}
-	if source != "env" {
-		t.Errorf("source = %q, want %q", source, "env")
+	if source != "HIJ_GITHUB_TOKEN" {
+		t.Errorf("source = %q, want %q", source, "HIJ_GITHUB_TOKEN")
 	}
 }
 
 func TestGetToken_NotSet(t *testing.T) {
-	// Ensure env var is not set
-	os.Unsetenv("HIJ_GITHUB_TOKEN")
+	isolateTokens(t)
+
+	if token, source := GetToken("github.com", ""); token != "" || source != "" {
+		t.Errorf("GetToken() = %q, %q, want no token", token, source)
+	}
+}
 
-	// Test
-	token, source := GetToken()
+func TestGetToken_Precedence(t *testing.T) {
+	isolateTokens(t)
+	hosts := "github.com:\n    user: octo\n    oauth_token: gho_hosts\n" +
+		"ghe.example.com:\n    user: octo\n    users:\n        octo:\n            oauth_token: gho_ghe\n"
+	if err := os.WriteFile(filepath.Join(os.Getenv("GH_CONFIG_DIR"), "hosts.yml"), []byte(hosts), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ghAuthToken = func(host string) (string, error) { return "gho_cli_" + host, nil }
+
+	check := func(host, wantToken, wantSource string) {
+		t.Helper()
+		if token, source := GetToken(host, ""); token != wantToken || source != wantSource {
+			t.Errorf("GetToken(%q) = %q, %q, want %q, %q", host, token, source, wantToken, wantSource)
+		}
+	}
+
+	check("github.com", "gho_hosts", SourceGHHosts)
+	check("ghe.example.com", "gho_ghe", SourceGHHosts)
+	check("other.example.com", "gho_cli_other.example.com", SourceGHAuthToken)
+
+	if err := SaveToken("", "ghp_keychain"); err != nil {
+		t.Fatal(err)
+	}
+	check("github.com", "ghp_keychain", SourceKeychain)
 
-	// When running in test environment without keychain access,
-	// we expect empty values if no token is configured
-	// Note: We cannot test keychain in unit tests as it requires system access
-	if source == "env" {
-		t.Error("source should not be 'env' when HIJ_GITHUB_TOKEN is not set")
+	// The saved token beats the variables gh reads
+	t.Setenv("GITHUB_TOKEN", "ghp_github")
+	t.Setenv("GH_TOKEN", "ghp_gh")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "ghp_ghe")
+	check("github.com", "ghp_keychain", SourceKeychain)
+	check("ghe.example.com", "ghp_keychain", SourceKeychain)
+	t.Setenv(envVarName, "ghp_hij")
+	check("github.com", "ghp_hij", envVarName)
+	t.Setenv(envVarName, "")
+
+	if err := DeleteToken(""); err != nil {
+		t.Fatal(err)
+	}
+	check("github.com", "ghp_gh", "GH_TOKEN")
+	t.Setenv("GH_TOKEN", "")
+	check("github.com", "ghp_github", "GITHUB_TOKEN")
+
+	// Enterprise hosts use their own variables
+	check("ghe.example.com", "ghp_ghe", "GH_ENTERPRISE_TOKEN")
+}
+
+func TestConfig_Token_ProfileKeychain(t *testing.T) {
+	isolateTokens(t)
+	if err := SaveToken("work", "ghp_work"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveToken("", "ghp_default"); err != nil {
+		t.Fatal(err)
+	}
+
+	work := Config{Host: "github.com", Profile: "work", TokenSource: "keychain"}
+	if token, source, _ := work.Token(); token != "ghp_work" || source != SourceKeychain {
+		t.Errorf("work Token() = %q, %q", token, source)
+	}
+	if token, _, _ := (Config{Host: "github.com"}).Token(); token != "ghp_default" {
+		t.Errorf("default Token() = %q", token)
+	}
+
+	// A keychain-only profile ignores the environment
+	t.Setenv("GH_TOKEN", "ghp_gh")
+	if err := DeleteToken("work"); err != nil {
+		t.Fatal(err)
+	}
+	if token, _, _ := work.Token(); token != "" {
+		t.Errorf("Token() after DeleteToken = %q, want none", token)
 	}
-	// Token should be empty or from keychain (if configured on the system)
-	_ = token // avoid unused variable warning
 }
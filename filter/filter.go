@@ -0,0 +1,556 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maful/hij/github"
+	"github.com/maful/hij/policy"
+)
+
+// Expr is a parsed filter expression that can be evaluated against package
+// versions
+type Expr interface {
+	Match(v github.PackageVersion) bool
+	String() string
+}
+
+// ParseError reports a syntax error at a byte offset of the filter input
+type ParseError struct {
+	Pos int
+	Msg string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("col %d: %s", e.Pos+1, e.Msg)
+}
+
+type andExpr struct{ left, right Expr }
+
+func (e andExpr) Match(v github.PackageVersion) bool { return e.left.Match(v) && e.right.Match(v) }
+func (e andExpr) String() string                     { return "(" + e.left.String() + " and " + e.right.String() + ")" }
+
+type orExpr struct{ left, right Expr }
+
+func (e orExpr) Match(v github.PackageVersion) bool { return e.left.Match(v) || e.right.Match(v) }
+func (e orExpr) String() string                     { return "(" + e.left.String() + " or " + e.right.String() + ")" }
+
+type notExpr struct{ expr Expr }
+
+func (e notExpr) Match(v github.PackageVersion) bool { return !e.expr.Match(v) }
+func (e notExpr) String() string                     { return "not " + e.expr.String() }
+
+// predicate is a leaf of the expression tree
+type predicate struct {
+	text  string
+	match func(v github.PackageVersion) bool
+}
+
+func (p predicate) Match(v github.PackageVersion) bool { return p.match(v) }
+func (p predicate) String() string                     { return p.text }
+
+// Filter is a parsed filter: a pipeline of stages separated by |, each
+// narrowing the versions passed on by the previous one
+type Filter struct {
+	stages    []stage
+	semver    bool // whether any stage compares semantic versions
+	lifecycle bool // whether any predicate needs a Lifecycle
+}
+
+// stage selects a subset of versions. Expression stages match versions one
+// at a time, while selectors such as keep look at the whole set.
+type stage interface {
+	Select(versions []github.PackageVersion) []github.PackageVersion
+	String() string
+}
+
+// exprStage adapts an expression to a pipeline stage
+type exprStage struct{ expr Expr }
+
+func (s exprStage) Select(versions []github.PackageVersion) []github.PackageVersion {
+	var matched []github.PackageVersion
+	for _, v := range versions {
+		if s.expr.Match(v) {
+			matched = append(matched, v)
+		}
+	}
+	return matched
+}
+
+func (s exprStage) String() string { return s.expr.String() }
+
+// Apply returns the versions selected by every stage, preserving order
+func (f *Filter) Apply(versions []github.PackageVersion) []github.PackageVersion {
+	for _, s := range f.stages {
+		versions = s.Select(versions)
+	}
+	return versions
+}
+
+// Ignored returns the versions that semver predicates and selectors leave
+// untouched because none of their tags is a semantic version. It is empty
+// for filters that do not use semver.
+func (f *Filter) Ignored(versions []github.PackageVersion) []github.PackageVersion {
+	if !f.semver {
+		return nil
+	}
+	var ignored []github.PackageVersion
+	for _, v := range versions {
+		if _, ok := versionOf(v); !ok {
+			ignored = append(ignored, v)
+		}
+	}
+	return ignored
+}
+
+// NeedsLifecycle reports whether the filter uses pr-closed or
+// branch-deleted, which match nothing unless parsed with a Lifecycle
+func (f *Filter) NeedsLifecycle() bool {
+	return f.lifecycle
+}
+
+func (f *Filter) String() string {
+	parts := make([]string, len(f.stages))
+	for i, s := range f.stages {
+		parts[i] = s.String()
+	}
+	return strings.Join(parts, " | ")
+}
+
+// Keywords are the words that start a predicate, selector or combinator,
+// offered for completion
+var Keywords = []string{
+	"untagged", "tagged", "older", "before", "after", "between",
+	"tag", "name", "digest", "age", "created", "updated",
+	"semver", "prerelease", "pr-closed", "branch-deleted", "keep", "superseded",
+	"and", "or", "not",
+}
+
+// Parse compiles a filter. Predicates are combined with `and`, `or`, `not`
+// and parentheses:
+//
+//	untagged, tagged
+//	tag=latest  tag!=latest  tag~"pr-*"  tag^=sha-
+//	name=sha256:…  name^=sha256:ab  name~"sha256:ab*"
+//	age>30d  age<=12h  age>3mo
+//	created<2024-01-01  updated>=2024-01-01T12:00  created=last-month
+//	older 30  older 2w  before 2024-01-01  after yesterday
+//	between 2024-01-01 2024-03-31
+//	semver<2.0.0  semver>=1.4.0  prerelease  semver
+//	pr-closed  branch-deleted
+//
+// Stages separated by | narrow the result further, and selectors look at
+// the whole set rather than one version at a time:
+//
+//	keep 5                    all but the 5 newest versions
+//	keep 3 by "^(\w+)-"       all but the 3 newest of each tag prefix
+//	keep 1 per minor          all but the latest patch of each minor
+//	superseded                prereleases older than the latest stable
+//	tag~"pr-*" | keep 10
+//
+// A leading colon is ignored since the : key activates filter mode. Relative
+// predicates are evaluated against now and dates are parsed in its location.
+func Parse(input string, now time.Time) (*Filter, error) {
+	return ParseWith(input, Options{Now: now})
+}
+
+// Options control how a filter is parsed
+type Options struct {
+	// Now anchors relative predicates, and dates are parsed in its location
+	Now time.Time
+	// Field is the timestamp compared by age, older, before, after and between
+	Field TimeField
+	// Lifecycle answers pr-closed and branch-deleted, nil when not loaded
+	Lifecycle Lifecycle
+}
+
+// ParseWith compiles a filter like Parse using opts
+func ParseWith(input string, opts Options) (*Filter, error) {
+	// Blank out the leading colon so error positions still match the input
+	if i := strings.IndexFunc(input, func(r rune) bool { return r != ' ' && r != '\t' }); i >= 0 && input[i] == ':' {
+		input = input[:i] + " " + input[i+1:]
+	}
+
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	if tokens[0].kind == tokEOF {
+		return nil, &ParseError{Pos: 0, Msg: "empty filter"}
+	}
+
+	p := &parser{tokens: tokens, now: opts.Now, field: opts.Field, lifecycle: opts.Lifecycle}
+	f := &Filter{}
+	for {
+		s, err := p.parseStage()
+		if err != nil {
+			return nil, err
+		}
+		f.stages = append(f.stages, s)
+
+		t := p.next()
+		if t.kind == tokEOF {
+			f.semver = p.semver
+			f.lifecycle = p.usesLifecycle
+			return f, nil
+		}
+		if t.kind != tokPipe {
+			return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("unexpected %s, expected and, or, | or end of filter", t.describe())}
+		}
+	}
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	now    time.Time
+	field  TimeField
+	semver bool
+
+	lifecycle     Lifecycle
+	usesLifecycle bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given keyword, consuming it
+// if so
+func (p *parser) keyword(kw string) bool {
+	if t := p.peek(); t.kind == tokWord && strings.EqualFold(t.text, kw) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// parseStage parses a selector or an expression
+func (p *parser) parseStage() (stage, error) {
+	if t := p.peek(); t.kind == tokWord {
+		switch strings.ToLower(t.text) {
+		case "keep":
+			return p.parseKeep()
+		case "superseded":
+			p.next()
+			p.semver = true
+			return supersededStage{}, nil
+		}
+	}
+	expr, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	return exprStage{expr}, nil
+}
+
+func (p *parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andExpr{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Expr, error) {
+	if p.keyword("not") {
+		expr, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{expr}, nil
+	}
+
+	if t := p.peek(); t.kind == tokLParen {
+		p.next()
+		expr, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokRParen {
+			return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("expected ), got %s", t.describe())}
+		}
+		return expr, nil
+	}
+
+	return p.parsePredicate()
+}
+
+// value consumes a word or quoted string operand
+func (p *parser) value(what string) (token, error) {
+	t := p.next()
+	if t.kind != tokWord && t.kind != tokString {
+		return t, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("expected %s, got %s", what, t.describe())}
+	}
+	return t, nil
+}
+
+func (p *parser) parsePredicate() (Expr, error) {
+	t := p.next()
+	if t.kind != tokWord {
+		return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("expected a filter, got %s", t.describe())}
+	}
+
+	name := strings.ToLower(t.text)
+	switch name {
+	case "untagged":
+		return predicate{text: name, match: func(v github.PackageVersion) bool { return len(v.Tags()) == 0 }}, nil
+	case "tagged":
+		return predicate{text: name, match: func(v github.PackageVersion) bool { return len(v.Tags()) > 0 }}, nil
+	case "older":
+		n, err := p.value("age")
+		if err != nil {
+			return nil, err
+		}
+		if _, err := strconv.Atoi(n.text); err == nil {
+			// A bare number is a number of days
+			n.text += "d"
+		}
+		cutoff, err := p.parseAgo(n)
+		if err != nil {
+			return nil, err
+		}
+		field := p.field
+		return predicate{text: "older " + n.text, match: func(v github.PackageVersion) bool { return field.Of(v).Before(cutoff) }}, nil
+	case "before", "after":
+		d, err := p.value("date")
+		if err != nil {
+			return nil, err
+		}
+		period, err := p.parsePeriod(d)
+		if err != nil {
+			return nil, err
+		}
+		op := "<"
+		if name == "after" {
+			op = ">"
+		}
+		cmp := comparePeriod(op, period)
+		field := p.field
+		return predicate{text: name + " " + quote(d), match: func(v github.PackageVersion) bool { return cmp(field.Of(v)) }}, nil
+	case "between":
+		from, err := p.value("start date")
+		if err != nil {
+			return nil, err
+		}
+		to, err := p.value("end date")
+		if err != nil {
+			return nil, err
+		}
+		start, err := p.parsePeriod(from)
+		if err != nil {
+			return nil, err
+		}
+		end, err := p.parsePeriod(to)
+		if err != nil {
+			return nil, err
+		}
+		if end.end.Before(start.start) {
+			return nil, &ParseError{Pos: to.pos, Msg: fmt.Sprintf("end date %q is before the start date", to.text)}
+		}
+		// Both ends are inclusive
+		r := period{start.start, end.end}
+		field := p.field
+		return predicate{text: "between " + quote(from) + " " + quote(to), match: func(v github.PackageVersion) bool {
+			t := field.Of(v)
+			return !t.Before(r.start) && t.Before(r.end)
+		}}, nil
+	case "semver", "prerelease":
+		if op := p.peek(); name == "semver" && op.kind == tokOp {
+			p.next()
+			val, err := p.value("version")
+			if err != nil {
+				return nil, err
+			}
+			return p.semverComparison(op, val)
+		}
+		p.semver = true
+		if name == "prerelease" {
+			return predicate{text: name, match: func(v github.PackageVersion) bool {
+				sv, ok := versionOf(v)
+				return ok && len(sv.Pre) > 0
+			}}, nil
+		}
+		return predicate{text: name, match: func(v github.PackageVersion) bool {
+			_, ok := versionOf(v)
+			return ok
+		}}, nil
+	case "pr-closed", "branch-deleted":
+		return p.lifecyclePredicate(name), nil
+	case "tag", "name", "digest", "age", "created", "updated":
+		op := p.next()
+		if op.kind != tokOp {
+			return nil, &ParseError{Pos: op.pos, Msg: fmt.Sprintf("expected an operator after %s, got %s", name, op.describe())}
+		}
+		val, err := p.value("value")
+		if err != nil {
+			return nil, err
+		}
+		return p.comparison(name, op, val)
+	}
+
+	return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("unknown filter %s", t.describe())}
+}
+
+// comparison builds a `field op value` predicate
+func (p *parser) comparison(field string, op, val token) (Expr, error) {
+	text := field + op.text + quote(val)
+	unsupported := &ParseError{Pos: op.pos, Msg: fmt.Sprintf("operator %s is not supported for %s", op.text, field)}
+
+	switch field {
+	case "tag":
+		match, err := stringMatcher(op, val)
+		if err != nil {
+			return nil, err
+		}
+		if match == nil {
+			return nil, unsupported
+		}
+		if op.text == "!=" {
+			// No tag equals the value
+			return predicate{text: text, match: func(v github.PackageVersion) bool {
+				for _, tag := range v.Tags() {
+					if tag == val.text {
+						return false
+					}
+				}
+				return true
+			}}, nil
+		}
+		return predicate{text: text, match: func(v github.PackageVersion) bool {
+			for _, tag := range v.Tags() {
+				if match(tag) {
+					return true
+				}
+			}
+			return false
+		}}, nil
+
+	case "name", "digest":
+		match, err := stringMatcher(op, val)
+		if err != nil {
+			return nil, err
+		}
+		if match == nil {
+			return nil, unsupported
+		}
+		return predicate{text: text, match: func(v github.PackageVersion) bool { return match(v.Name) }}, nil
+
+	case "age":
+		cutoff, err := p.parseAgo(val)
+		if err != nil {
+			return nil, err
+		}
+		// A greater age means an earlier time
+		cmp := compareTime(invertOp(op.text), cutoff)
+		if cmp == nil {
+			return nil, unsupported
+		}
+		field := p.field
+		return predicate{text: text, match: func(v github.PackageVersion) bool { return cmp(field.Of(v)) }}, nil
+
+	case "created", "updated":
+		period, err := p.parsePeriod(val)
+		if err != nil {
+			return nil, err
+		}
+		cmp := comparePeriod(op.text, period)
+		if cmp == nil {
+			return nil, unsupported
+		}
+		if field == "updated" {
+			return predicate{text: text, match: func(v github.PackageVersion) bool { return cmp(v.UpdatedAt) }}, nil
+		}
+		return predicate{text: text, match: func(v github.PackageVersion) bool { return cmp(v.CreatedAt) }}, nil
+	}
+	return nil, unsupported
+}
+
+// stringMatcher returns a matcher for string operators, or nil if op does
+// not apply to strings
+func stringMatcher(op, val token) (func(string) bool, error) {
+	switch op.text {
+	case "=":
+		return func(s string) bool { return s == val.text }, nil
+	case "!=":
+		return func(s string) bool { return s != val.text }, nil
+	case "^=":
+		return func(s string) bool { return strings.HasPrefix(s, val.text) }, nil
+	case "~":
+		pattern, err := policy.CompileTagPattern(val.text)
+		if err != nil {
+			return nil, &ParseError{Pos: val.pos, Msg: err.Error()}
+		}
+		return pattern.Match, nil
+	}
+	return nil, nil
+}
+
+// compareTime returns a function testing `t op ref`, or nil if op is not a
+// comparison
+func compareTime(op string, ref time.Time) func(time.Time) bool {
+	switch op {
+	case "<":
+		return func(t time.Time) bool { return t.Before(ref) }
+	case "<=":
+		return func(t time.Time) bool { return !t.After(ref) }
+	case ">":
+		return func(t time.Time) bool { return t.After(ref) }
+	case ">=":
+		return func(t time.Time) bool { return !t.Before(ref) }
+	}
+	return nil
+}
+
+func invertOp(op string) string {
+	switch op {
+	case "<":
+		return ">"
+	case "<=":
+		return ">="
+	case ">":
+		return "<"
+	case ">=":
+		return "<="
+	}
+	return op
+}
+
+func quote(t token) string {
+	if t.kind == tokString {
+		return strconv.Quote(t.text)
+	}
+	return t.text
+}
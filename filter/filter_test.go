@@ -0,0 +1,172 @@
+package filter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maful/hij/github"
+)
+
+var testNow = time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+
+func version(id int, name string, created time.Time, tags ...string) github.PackageVersion {
+	v := github.PackageVersion{ID: id, Name: name, CreatedAt: created, UpdatedAt: created.Add(24 * time.Hour)}
+	v.Metadata.Container.Tags = tags
+	return v
+}
+
+func testVersions() []github.PackageVersion {
+	return []github.PackageVersion{
+		version(1, "sha256:aa11", testNow.Add(-5*24*time.Hour), "latest", "v2.0.0"),
+		version(2, "sha256:ab22", testNow.Add(-15*24*time.Hour), "pr-12"),
+		version(3, "sha256:bb33", time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)),
+		version(4, "sha256:cc44", time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), "pr-7", "main-1"),
+	}
+}
+
+func TestParse_Match(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantIDs []int
+	}{
+		// Legacy commands
+		{expr: "older 10", wantIDs: []int{2, 3, 4}},
+		{expr: ":older 10", wantIDs: []int{2, 3, 4}},
+		{expr: "before 2024-06-01", wantIDs: []int{3, 4}},
+		{expr: ":before 2024-01-15T12:00", wantIDs: []int{3, 4}},
+		{expr: ":before 2024-01-15T09:00", wantIDs: []int{4}},
+
+		// Predicates
+		{expr: "untagged", wantIDs: []int{3}},
+		{expr: "tagged", wantIDs: []int{1, 2, 4}},
+		{expr: `tag~"pr-*"`, wantIDs: []int{2, 4}},
+		{expr: `tag~"/^v\\d+/"`, wantIDs: []int{1}},
+		{expr: "tag=latest", wantIDs: []int{1}},
+		{expr: "tag!=latest", wantIDs: []int{2, 3, 4}},
+		{expr: "tag^=main-", wantIDs: []int{4}},
+		{expr: "name^=sha256:a", wantIDs: []int{1, 2}},
+		{expr: "digest=sha256:bb33", wantIDs: []int{3}},
+		{expr: "age>30d", wantIDs: []int{3, 4}},
+		{expr: "age<2w", wantIDs: []int{1}},
+		{expr: "age>=120h", wantIDs: []int{1, 2, 3, 4}},
+		{expr: "created<2024-01-01", wantIDs: []int{4}},
+		{expr: "updated>=2024-01-16T10:00", wantIDs: []int{1, 2, 3}},
+
+		// Combinators
+		{expr: `tag~"pr-*" and age>30d`, wantIDs: []int{4}},
+		{expr: "untagged or tag=latest", wantIDs: []int{1, 3}},
+		{expr: "not tagged", wantIDs: []int{3}},
+		{expr: `not (untagged or tag~"pr-*")`, wantIDs: []int{1}},
+		{expr: "untagged or tag=latest and age>30d", wantIDs: []int{3}},
+		{expr: "(untagged or tag=latest) and age<30d", wantIDs: []int{1}},
+		{expr: "TAGGED AND NOT tag=latest", wantIDs: []int{2, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Parse(tt.expr, testNow)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			got := expr.Apply(testVersions())
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("matched %v, want IDs %v", ids(got), tt.wantIDs)
+			}
+			for i, v := range got {
+				if v.ID != tt.wantIDs[i] {
+					t.Fatalf("matched %v, want IDs %v", ids(got), tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Precedence(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: "untagged or tagged and age>1d", want: "(untagged or (tagged and age>1d))"},
+		{expr: "not untagged and tagged", want: "(not untagged and tagged)"},
+		{expr: `(untagged or tag~"pr-*") and age>1d`, want: `((untagged or tag~"pr-*") and age>1d)`},
+		{expr: `tagged|keep 3 BY "^(\w+)-"`, want: `tagged | keep 3 by "^(\\w+)-"`},
+	}
+
+	for _, tt := range tests {
+		expr, err := Parse(tt.expr, testNow)
+		if err != nil {
+			t.Fatalf("Parse(%q) error: %v", tt.expr, err)
+		}
+		if got := expr.String(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantPos int
+	}{
+		{expr: "", wantPos: 0},
+		{expr: "invalid filter", wantPos: 0},
+		{expr: ":before 2024-13-01", wantPos: 8},
+		{expr: "older x", wantPos: 6},
+		{expr: "age>30", wantPos: 4},
+		{expr: "untagged and", wantPos: 12},
+		{expr: "(untagged", wantPos: 9},
+		{expr: "untagged tagged", wantPos: 9},
+		{expr: `tag~"pr-*`, wantPos: 4},
+		{expr: "tag<v1", wantPos: 3},
+		{expr: "age~30d", wantPos: 3},
+		{expr: "tag latest", wantPos: 4},
+		{expr: "untagged & tagged", wantPos: 9},
+		{expr: "keep", wantPos: 4},
+		{expr: "keep -1", wantPos: 5},
+		{expr: `keep 3 by "(["`, wantPos: 10},
+		{expr: "keep 3 untagged", wantPos: 7},
+		{expr: "untagged |", wantPos: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr, testNow)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse() error = %v, want *ParseError", err)
+			}
+			if perr.Pos != tt.wantPos {
+				t.Errorf("error position = %d, want %d (%v)", perr.Pos, tt.wantPos, perr)
+			}
+		})
+	}
+}
+
+func ids(versions []github.PackageVersion) []int {
+	var out []int
+	for _, v := range versions {
+		out = append(out, v.ID)
+	}
+	return out
+}
+
+func TestKeywordsAndAnchors(t *testing.T) {
+	for _, kw := range Keywords {
+		expr := kw
+		if kw == "and" || kw == "or" {
+			expr = "tagged " + kw + " untagged"
+		}
+		_, err := Parse(expr, testNow)
+		var perr *ParseError
+		if errors.As(err, &perr) && strings.HasPrefix(perr.Msg, "unknown filter") {
+			t.Errorf("keyword %q is not understood by the parser", kw)
+		}
+	}
+	for _, anchor := range Anchors {
+		if _, err := Parse("before "+anchor, testNow); err != nil {
+			t.Errorf("anchor %q: %v", anchor, err)
+		}
+	}
+}
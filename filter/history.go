@@ -0,0 +1,86 @@
+package filter
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/maful/hij/config"
+)
+
+const (
+	historyFileName = "filter_history"
+	// maxHistory is how many filters are remembered
+	maxHistory = 200
+)
+
+// History is the list of previously applied filters, oldest first, stored
+// one per line in the XDG state directory
+type History struct {
+	path    string
+	entries []string
+}
+
+// DefaultHistoryPath returns the history location under the XDG state
+// directory
+func DefaultHistoryPath() (string, error) {
+	dir, err := config.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, historyFileName), nil
+}
+
+// LoadHistory reads the history file at path. A missing file yields an
+// empty history.
+func LoadHistory(path string) (*History, error) {
+	h := &History{path: path}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return h, nil
+	}
+	if err != nil {
+		return h, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			h.entries = append(h.entries, line)
+		}
+	}
+	return h, scanner.Err()
+}
+
+// Entries returns the remembered filters, oldest first
+func (h *History) Entries() []string {
+	return h.entries
+}
+
+// Add records expr as the most recent filter and writes the history file.
+// Repeating an earlier filter moves it to the end.
+func (h *History) Add(expr string) error {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil
+	}
+	entries := make([]string, 0, len(h.entries)+1)
+	for _, e := range h.entries {
+		if e != expr {
+			entries = append(entries, e)
+		}
+	}
+	entries = append(entries, expr)
+	if len(entries) > maxHistory {
+		entries = entries[len(entries)-maxHistory:]
+	}
+	h.entries = entries
+
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(h.path, []byte(strings.Join(entries, "\n")+"\n"), 0o600)
+}
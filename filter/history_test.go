@@ -0,0 +1,51 @@
+package filter
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHistory_AddAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "filter_history")
+
+	h, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory() error: %v", err)
+	}
+	for _, expr := range []string{"untagged", "older 30", " untagged ", ""} {
+		if err := h.Add(expr); err != nil {
+			t.Fatalf("Add(%q) error: %v", expr, err)
+		}
+	}
+
+	reloaded, err := LoadHistory(path)
+	if err != nil {
+		t.Fatalf("LoadHistory() error: %v", err)
+	}
+	// Repeating a filter moves it to the end
+	if got := strings.Join(reloaded.Entries(), "|"); got != "older 30|untagged" {
+		t.Errorf("entries = %q, want %q", got, "older 30|untagged")
+	}
+}
+
+func TestHistory_Limit(t *testing.T) {
+	h, err := LoadHistory(filepath.Join(t.TempDir(), "filter_history"))
+	if err != nil {
+		t.Fatalf("LoadHistory() error: %v", err)
+	}
+	for i := 0; i < maxHistory+5; i++ {
+		if err := h.Add(fmt.Sprintf("older %d", i)); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+	}
+
+	entries := h.Entries()
+	if len(entries) != maxHistory {
+		t.Fatalf("entry count = %d, want %d", len(entries), maxHistory)
+	}
+	if entries[0] != "older 5" {
+		t.Errorf("oldest entry = %q, want %q", entries[0], "older 5")
+	}
+}
@@ -0,0 +1,136 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/maful/hij/github"
+)
+
+// keepStage selects everything except the newest n versions. With a group
+// pattern, versions are grouped by the first capture of the pattern against
+// their tags (or the whole match when it has no capture group) and each group
+// keeps its own n. Versions with no tag matching the pattern belong to no
+// group and are never selected.
+//
+// Grouping per major or minor release orders versions by semantic version
+// instead of creation time, so `keep 1 per minor` keeps the latest patch of
+// each minor. Versions without a semver tag are never selected.
+type keepStage struct {
+	n     int
+	group *regexp.Regexp
+	per   string // "major" or "minor"
+	text  string
+}
+
+func (s keepStage) Select(versions []github.PackageVersion) []github.PackageVersion {
+	newest := make([]github.PackageVersion, len(versions))
+	copy(newest, versions)
+	sort.SliceStable(newest, func(i, j int) bool {
+		if s.per != "" {
+			a, _ := versionOf(newest[i])
+			b, _ := versionOf(newest[j])
+			if c := a.Compare(b); c != 0 {
+				return c > 0
+			}
+		}
+		if !newest[i].CreatedAt.Equal(newest[j].CreatedAt) {
+			return newest[i].CreatedAt.After(newest[j].CreatedAt)
+		}
+		return newest[i].ID > newest[j].ID
+	})
+
+	kept := make(map[string]int)
+	drop := make(map[int]struct{})
+	for _, v := range newest {
+		key, ok := s.groupOf(v)
+		if !ok {
+			continue
+		}
+		if kept[key] < s.n {
+			kept[key]++
+			continue
+		}
+		drop[v.ID] = struct{}{}
+	}
+
+	var selected []github.PackageVersion
+	for _, v := range versions {
+		if _, ok := drop[v.ID]; ok {
+			selected = append(selected, v)
+		}
+	}
+	return selected
+}
+
+func (s keepStage) String() string { return s.text }
+
+// groupOf returns the group key of v and whether it belongs to any group
+func (s keepStage) groupOf(v github.PackageVersion) (string, bool) {
+	if s.per != "" {
+		sv, ok := versionOf(v)
+		if !ok {
+			return "", false
+		}
+		if s.per == "major" {
+			return fmt.Sprintf("%d", sv.Major), true
+		}
+		return fmt.Sprintf("%d.%d", sv.Major, sv.Minor), true
+	}
+	if s.group == nil {
+		return "", true
+	}
+	for _, tag := range v.Tags() {
+		m := s.group.FindStringSubmatch(tag)
+		if m == nil {
+			continue
+		}
+		if len(m) > 1 {
+			return m[1], true
+		}
+		return m[0], true
+	}
+	return "", false
+}
+
+// parseKeep parses `keep N [by REGEX | per major|minor]`
+func (p *parser) parseKeep() (stage, error) {
+	p.next()
+	n, err := p.value("number of versions to keep")
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(n.text)
+	if err != nil || count < 0 {
+		return nil, &ParseError{Pos: n.pos, Msg: fmt.Sprintf("invalid number of versions %q", n.text)}
+	}
+
+	s := keepStage{n: count, text: "keep " + n.text}
+	if p.keyword("per") {
+		t := p.next()
+		unit := strings.ToLower(t.text)
+		if t.kind != tokWord || (unit != "major" && unit != "minor") {
+			return nil, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("expected major or minor, got %s", t.describe())}
+		}
+		s.per = unit
+		p.semver = true
+		s.text += " per " + s.per
+		return s, nil
+	}
+	if !p.keyword("by") {
+		return s, nil
+	}
+	pattern, err := p.value("tag pattern")
+	if err != nil {
+		return nil, err
+	}
+	s.group, err = regexp.Compile(pattern.text)
+	if err != nil {
+		return nil, &ParseError{Pos: pattern.pos, Msg: fmt.Sprintf("invalid pattern: %v", err)}
+	}
+	s.text += " by " + strconv.Quote(pattern.text)
+	return s, nil
+}
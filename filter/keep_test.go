@@ -0,0 +1,129 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/maful/hij/github"
+)
+
+func keepVersions() []github.PackageVersion {
+	day := func(d int) time.Time { return testNow.Add(-time.Duration(d) * 24 * time.Hour) }
+	return []github.PackageVersion{
+		version(1, "sha256:01", day(1), "main-9"),
+		version(2, "sha256:02", day(2), "dev-5"),
+		version(3, "sha256:03", day(3), "main-8"),
+		version(4, "sha256:04", day(4), "pr-3"),
+		version(5, "sha256:05", day(5), "main-7"),
+		version(6, "sha256:06", day(6), "dev-4"),
+		version(7, "sha256:07", day(7)),
+		version(8, "sha256:08", day(8), "main-6", "stable"),
+	}
+}
+
+func TestParse_Keep(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantIDs []int
+	}{
+		{expr: "keep 5", wantIDs: []int{6, 7, 8}},
+		{expr: ":keep 0", wantIDs: []int{1, 2, 3, 4, 5, 6, 7, 8}},
+		{expr: "keep 20", wantIDs: nil},
+		{expr: `keep 1 by "^(\w+)-"`, wantIDs: []int{3, 5, 6, 8}},
+		{expr: `keep 2 by "^(main|dev)-"`, wantIDs: []int{5, 8}},
+		{expr: `keep 1 by "^main-"`, wantIDs: []int{3, 5, 8}},
+		{expr: "tagged | keep 3", wantIDs: []int{4, 5, 6, 8}},
+		{expr: `tag~"main-*" | keep 1 | age>6d`, wantIDs: []int{8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := Parse(tt.expr, testNow)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			got := f.Apply(keepVersions())
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("matched %v, want IDs %v", ids(got), tt.wantIDs)
+			}
+			for i, v := range got {
+				if v.ID != tt.wantIDs[i] {
+					t.Fatalf("matched %v, want IDs %v", ids(got), tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestKeep_SortsByCreatedAt(t *testing.T) {
+	versions := keepVersions()
+	// Reverse the input so the newest versions come last
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+
+	f, err := Parse("keep 6", testNow)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	got := f.Apply(versions)
+	if len(got) != 2 || got[0].ID != 8 || got[1].ID != 7 {
+		t.Errorf("matched %v, want IDs [8 7] in input order", ids(got))
+	}
+}
+
+func TestKeep_MoreThanOnePage(t *testing.T) {
+	// 250 versions, one per hour, newest first as the API returns them;
+	// every fifth one is tagged
+	var all []github.PackageVersion
+	for i := 1; i <= 250; i++ {
+		var tags []string
+		if i%5 == 0 {
+			tags = []string{fmt.Sprintf("main-%d", i)}
+		}
+		all = append(all, version(i, fmt.Sprintf("sha256:%03d", i), testNow.Add(-time.Duration(i)*time.Hour), tags...))
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start := min((page-1)*perPage, len(all))
+		json.NewEncoder(w).Encode(all[start:min(start+perPage, len(all))])
+	}))
+	defer server.Close()
+
+	client := github.NewClient("test-token")
+	client.SetBaseURL(server.URL)
+	versions, err := client.ListPackageVersions("container", "app")
+	if err != nil {
+		t.Fatalf("ListPackageVersions() error: %v", err)
+	}
+
+	tests := []struct {
+		expr          string
+		count, oldest int
+	}{
+		{expr: "keep 100", count: 150, oldest: 250},
+		{expr: "keep 200", count: 50, oldest: 250},
+		{expr: "tagged | keep 30", count: 20, oldest: 250},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := Parse(tt.expr, testNow)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			got := f.Apply(versions)
+			if len(got) != tt.count {
+				t.Fatalf("matched %d versions, want %d", len(got), tt.count)
+			}
+			if oldest := got[len(got)-1].ID; oldest != tt.oldest {
+				t.Errorf("oldest match = %d, want %d", oldest, tt.oldest)
+			}
+		})
+	}
+}
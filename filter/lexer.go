@@ -0,0 +1,109 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokPipe
+)
+
+// token is a lexical unit of a filter expression. Pos is the byte offset of
+// the token in the input.
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of filter"
+	case tokString:
+		return fmt.Sprintf("%q", t.text)
+	}
+	return "\"" + t.text + "\""
+}
+
+// operators, longest first so that `<=` wins over `<`
+var operators = []string{"^=", "!=", "<=", ">=", "~", "=", "<", ">"}
+
+// isWordByte reports whether c may appear in an unquoted word
+func isWordByte(c byte) bool {
+	switch c {
+	case ' ', '\t', '\n', '(', ')', '"', '~', '=', '<', '>', '!', '^', '|':
+		return false
+	}
+	return true
+}
+
+// lex splits a filter expression into tokens
+func lex(input string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: i})
+			i++
+		case c == '|':
+			tokens = append(tokens, token{kind: tokPipe, text: "|", pos: i})
+			i++
+		case c == '"':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(input) && input[j] != '"'; j++ {
+				// Only \" and \\ are escapes so regexes such as \w stay readable
+				if input[j] == '\\' && j+1 < len(input) && (input[j+1] == '"' || input[j+1] == '\\') {
+					j++
+				}
+				sb.WriteByte(input[j])
+			}
+			if j >= len(input) {
+				return nil, &ParseError{Pos: i, Msg: "unterminated string"}
+			}
+			tokens = append(tokens, token{kind: tokString, text: sb.String(), pos: i})
+			i = j + 1
+		default:
+			if op := matchOperator(input[i:]); op != "" {
+				tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+				i += len(op)
+				continue
+			}
+			if !isWordByte(c) {
+				return nil, &ParseError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+			}
+			j := i
+			for j < len(input) && isWordByte(input[j]) {
+				j++
+			}
+			tokens = append(tokens, token{kind: tokWord, text: input[i:j], pos: i})
+			i = j
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(input)}), nil
+}
+
+func matchOperator(s string) string {
+	for _, op := range operators {
+		if strings.HasPrefix(s, op) {
+			return op
+		}
+	}
+	return ""
+}
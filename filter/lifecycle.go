@@ -0,0 +1,25 @@
+package filter
+
+import "github.com/maful/hij/github"
+
+// Lifecycle reports what became of the pull request or branch a version was
+// built from, for the pr-closed and branch-deleted predicates
+type Lifecycle interface {
+	PullRequestClosed(v github.PackageVersion) bool // the pull request was closed or merged
+	BranchDeleted(v github.PackageVersion) bool     // the branch no longer exists
+}
+
+// lifecyclePredicate builds pr-closed or branch-deleted. Without a Lifecycle
+// in the options nothing matches, and NeedsLifecycle tells the caller to
+// load one and parse again.
+func (p *parser) lifecyclePredicate(name string) Expr {
+	p.usesLifecycle = true
+	l := p.lifecycle
+	if l == nil {
+		return predicate{text: name, match: func(github.PackageVersion) bool { return false }}
+	}
+	if name == "pr-closed" {
+		return predicate{text: name, match: l.PullRequestClosed}
+	}
+	return predicate{text: name, match: l.BranchDeleted}
+}
@@ -0,0 +1,78 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/maful/hij/github"
+)
+
+// fakeLifecycle treats versions tagged pr-closed or branch-gone as built from
+// a closed pull request or a deleted branch
+type fakeLifecycle struct{}
+
+func (fakeLifecycle) PullRequestClosed(v github.PackageVersion) bool { return hasTag(v, "pr-closed") }
+func (fakeLifecycle) BranchDeleted(v github.PackageVersion) bool     { return hasTag(v, "branch-gone") }
+
+func hasTag(v github.PackageVersion, tag string) bool {
+	for _, t := range v.Tags() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParse_Lifecycle(t *testing.T) {
+	versions := []github.PackageVersion{
+		version(1, "sha256:01", testNow.Add(-time.Hour), "pr-closed"),
+		version(2, "sha256:02", testNow.Add(-40*24*time.Hour), "branch-gone"),
+		version(3, "sha256:03", testNow.Add(-40*24*time.Hour), "pr-open"),
+	}
+	tests := []struct {
+		expr    string
+		wantIDs []int
+	}{
+		{expr: "pr-closed", wantIDs: []int{1}},
+		{expr: "branch-deleted", wantIDs: []int{2}},
+		{expr: "pr-closed or branch-deleted", wantIDs: []int{1, 2}},
+		{expr: "(pr-closed or branch-deleted) and age>30d", wantIDs: []int{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := ParseWith(tt.expr, Options{Now: testNow})
+			if err != nil {
+				t.Fatalf("ParseWith() error: %v", err)
+			}
+			if !f.NeedsLifecycle() {
+				t.Error("NeedsLifecycle() = false, want true")
+			}
+			if got := f.Apply(versions); len(got) != 0 {
+				t.Errorf("matched %v without a Lifecycle, want none", ids(got))
+			}
+
+			f, err = ParseWith(tt.expr, Options{Now: testNow, Lifecycle: fakeLifecycle{}})
+			if err != nil {
+				t.Fatalf("ParseWith() error: %v", err)
+			}
+			got := ids(f.Apply(versions))
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("matched %v, want IDs %v", got, tt.wantIDs)
+			}
+			for i := range got {
+				if got[i] != tt.wantIDs[i] {
+					t.Fatalf("matched %v, want IDs %v", got, tt.wantIDs)
+				}
+			}
+		})
+	}
+
+	f, err := Parse("untagged or tag=latest", testNow)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if f.NeedsLifecycle() {
+		t.Error("NeedsLifecycle() = true for a filter without pr-closed or branch-deleted")
+	}
+}
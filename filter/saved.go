@@ -0,0 +1,95 @@
+package filter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AllPackages is the package key of saved filters offered for every package
+const AllPackages = "*"
+
+// Saved holds named filters per package. The file is plain YAML mapping
+// package names to filter names and expressions, so a team can check it in
+// and share it:
+//
+//	"*":
+//	  untagged: untagged
+//	app:
+//	  stale-prs: tag~"pr-*" and age>30d
+type Saved struct {
+	path     string
+	packages map[string]map[string]string
+}
+
+// LoadSaved reads saved filters from path. A missing file yields no saved
+// filters.
+func LoadSaved(path string) (*Saved, error) {
+	s := &Saved{path: path, packages: make(map[string]map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := yaml.Unmarshal(data, &s.packages); err != nil {
+		return s, fmt.Errorf("%s: %w", path, err)
+	}
+	if s.packages == nil {
+		s.packages = make(map[string]map[string]string)
+	}
+	return s, nil
+}
+
+// Path returns the file backing the saved filters
+func (s *Saved) Path() string {
+	return s.path
+}
+
+// Get returns the filter saved under name for pkg, falling back to filters
+// saved for all packages
+func (s *Saved) Get(pkg, name string) (string, bool) {
+	if expr, ok := s.packages[pkg][name]; ok {
+		return expr, true
+	}
+	expr, ok := s.packages[AllPackages][name]
+	return expr, ok
+}
+
+// Names returns the sorted names of the filters available for pkg
+func (s *Saved) Names(pkg string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, key := range []string{pkg, AllPackages} {
+		for name := range s.packages[key] {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save stores expr under name for pkg and writes the file
+func (s *Saved) Save(pkg, name, expr string) error {
+	if s.packages[pkg] == nil {
+		s.packages[pkg] = make(map[string]string)
+	}
+	s.packages[pkg][name] = expr
+
+	data, err := yaml.Marshal(s.packages)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0o644)
+}
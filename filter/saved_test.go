@@ -0,0 +1,69 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaved_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hij", "filters.yaml")
+
+	s, err := LoadSaved(path)
+	if err != nil {
+		t.Fatalf("LoadSaved() error: %v", err)
+	}
+	if err := s.Save("app", "stale-prs", `tag~"pr-*" and age>30d`); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if err := s.Save(AllPackages, "untagged", "untagged"); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	reloaded, err := LoadSaved(path)
+	if err != nil {
+		t.Fatalf("LoadSaved() error: %v", err)
+	}
+	if expr, ok := reloaded.Get("app", "stale-prs"); !ok || expr != `tag~"pr-*" and age>30d` {
+		t.Errorf("Get(app, stale-prs) = %q, %v", expr, ok)
+	}
+	if _, ok := reloaded.Get("web", "stale-prs"); ok {
+		t.Error("stale-prs was saved for app only")
+	}
+	if expr, ok := reloaded.Get("web", "untagged"); !ok || expr != "untagged" {
+		t.Errorf("filters saved for all packages should apply to web, got %q, %v", expr, ok)
+	}
+	if got := strings.Join(reloaded.Names("app"), ","); got != "stale-prs,untagged" {
+		t.Errorf("Names(app) = %q", got)
+	}
+}
+
+func TestSaved_PackageOverridesShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filters.yaml")
+	content := "\"*\":\n  old: older 90\napp:\n  old: older 30\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := LoadSaved(path)
+	if err != nil {
+		t.Fatalf("LoadSaved() error: %v", err)
+	}
+	if expr, _ := s.Get("app", "old"); expr != "older 30" {
+		t.Errorf("Get(app, old) = %q, want the package's own filter", expr)
+	}
+	if names := s.Names("app"); len(names) != 1 {
+		t.Errorf("Names(app) = %v, want one entry", names)
+	}
+}
+
+func TestLoadSaved_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "filters.yaml")
+	if err := os.WriteFile(path, []byte("app: [\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSaved(path); err == nil {
+		t.Error("expected error, got nil")
+	}
+}
@@ -0,0 +1,88 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/blang/semver"
+
+	"github.com/maful/hij/github"
+)
+
+// parseSemver parses a tag such as v1.4.2 or 1.4.3-rc.1. Tags that are not
+// full semantic versions, like latest, v1.4 or main-42, are rejected.
+func parseSemver(tag string) (semver.Version, bool) {
+	v, err := semver.Parse(strings.TrimPrefix(tag, "v"))
+	return v, err == nil
+}
+
+// versionOf returns the highest semantic version among the tags of v
+func versionOf(v github.PackageVersion) (semver.Version, bool) {
+	var best semver.Version
+	found := false
+	for _, tag := range v.Tags() {
+		if sv, ok := parseSemver(tag); ok && (!found || sv.GT(best)) {
+			best, found = sv, true
+		}
+	}
+	return best, found
+}
+
+// semverComparison builds a `semver op value` predicate
+func (p *parser) semverComparison(op, val token) (Expr, error) {
+	ref, ok := parseSemver(val.text)
+	if !ok {
+		return nil, &ParseError{Pos: val.pos, Msg: fmt.Sprintf("invalid version %q, expected MAJOR.MINOR.PATCH", val.text)}
+	}
+
+	var cmp func(int) bool
+	switch op.text {
+	case "=":
+		cmp = func(c int) bool { return c == 0 }
+	case "!=":
+		cmp = func(c int) bool { return c != 0 }
+	case "<":
+		cmp = func(c int) bool { return c < 0 }
+	case "<=":
+		cmp = func(c int) bool { return c <= 0 }
+	case ">":
+		cmp = func(c int) bool { return c > 0 }
+	case ">=":
+		cmp = func(c int) bool { return c >= 0 }
+	default:
+		return nil, &ParseError{Pos: op.pos, Msg: fmt.Sprintf("operator %s is not supported for semver", op.text)}
+	}
+
+	p.semver = true
+	return predicate{text: "semver" + op.text + quote(val), match: func(v github.PackageVersion) bool {
+		sv, ok := versionOf(v)
+		return ok && cmp(sv.Compare(ref))
+	}}, nil
+}
+
+// supersededStage selects prereleases lower than the latest stable release
+// in the set, e.g. v1.4.3-rc.1 once v1.4.3 or v1.5.0 exists
+type supersededStage struct{}
+
+func (supersededStage) Select(versions []github.PackageVersion) []github.PackageVersion {
+	var latest semver.Version
+	found := false
+	for _, v := range versions {
+		if sv, ok := versionOf(v); ok && len(sv.Pre) == 0 && (!found || sv.GT(latest)) {
+			latest, found = sv, true
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	var selected []github.PackageVersion
+	for _, v := range versions {
+		if sv, ok := versionOf(v); ok && len(sv.Pre) > 0 && sv.LT(latest) {
+			selected = append(selected, v)
+		}
+	}
+	return selected
+}
+
+func (supersededStage) String() string { return "superseded" }
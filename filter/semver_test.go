@@ -0,0 +1,105 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/maful/hij/github"
+)
+
+func semverVersions() []github.PackageVersion {
+	day := func(d int) time.Time { return testNow.Add(-time.Duration(d) * 24 * time.Hour) }
+	return []github.PackageVersion{
+		version(1, "sha256:01", day(1), "v2.0.0-rc.1"),
+		version(2, "sha256:02", day(2), "v1.5.0", "latest"),
+		version(3, "sha256:03", day(3), "v1.5.0-rc.2"),
+		version(4, "sha256:04", day(4), "v1.4.3"),
+		// Rebuilt later but still an older patch
+		version(5, "sha256:05", day(0), "1.4.2"),
+		version(6, "sha256:06", day(6), "v1.4.3-rc.1"),
+		version(7, "sha256:07", day(7), "v0.9.0"),
+		version(8, "sha256:08", day(8), "main-42"),
+		version(9, "sha256:09", day(9)),
+	}
+}
+
+func TestParse_Semver(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantIDs []int
+	}{
+		{expr: "semver", wantIDs: []int{1, 2, 3, 4, 5, 6, 7}},
+		{expr: "prerelease", wantIDs: []int{1, 3, 6}},
+		{expr: "semver<2.0.0", wantIDs: []int{1, 2, 3, 4, 5, 6, 7}},
+		{expr: "semver<2.0.0 and not prerelease", wantIDs: []int{2, 4, 5, 7}},
+		{expr: "semver<1.0.0", wantIDs: []int{7}},
+		{expr: "semver>=v1.4.3", wantIDs: []int{1, 2, 3, 4}},
+		{expr: "semver=1.5.0", wantIDs: []int{2}},
+		{expr: "superseded", wantIDs: []int{3, 6}},
+		{expr: "keep 1 per minor", wantIDs: []int{3, 5, 6}},
+		{expr: "not prerelease | keep 1 per minor", wantIDs: []int{5}},
+		{expr: "keep 1 per major", wantIDs: []int{3, 4, 5, 6}},
+		{expr: "prerelease | superseded", wantIDs: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := Parse(tt.expr, testNow)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			got := f.Apply(semverVersions())
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("matched %v, want IDs %v", ids(got), tt.wantIDs)
+			}
+			for i, v := range got {
+				if v.ID != tt.wantIDs[i] {
+					t.Fatalf("matched %v, want IDs %v", ids(got), tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestFilter_Ignored(t *testing.T) {
+	f, err := Parse("superseded", testNow)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if got := ids(f.Ignored(semverVersions())); len(got) != 2 || got[0] != 8 || got[1] != 9 {
+		t.Errorf("Ignored() = %v, want [8 9]", got)
+	}
+
+	f, err = Parse("keep 3", testNow)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if got := f.Ignored(semverVersions()); len(got) != 0 {
+		t.Errorf("Ignored() = %v, want none for a filter without semver", ids(got))
+	}
+}
+
+func TestParse_SemverErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantPos int
+	}{
+		{expr: "semver<2.0", wantPos: 7},
+		{expr: "semver~1.0.0", wantPos: 6},
+		{expr: "keep 1 per patch", wantPos: 11},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr, testNow)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse() error = %v, want *ParseError", err)
+			}
+			if perr.Pos != tt.wantPos {
+				t.Errorf("error position = %d, want %d (%v)", perr.Pos, tt.wantPos, perr)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maful/hij/github"
+)
+
+// TimeField selects the timestamp that age, older, before, after and
+// between compare against
+type TimeField int
+
+const (
+	CreatedAt TimeField = iota
+	UpdatedAt
+)
+
+// ParseTimeField parses "created" or "updated", accepting the API names
+// created_at and updated_at as well
+func ParseTimeField(s string) (TimeField, error) {
+	switch strings.ToLower(s) {
+	case "", "created", "created_at":
+		return CreatedAt, nil
+	case "updated", "updated_at":
+		return UpdatedAt, nil
+	}
+	return CreatedAt, fmt.Errorf("invalid timestamp %q, expected created or updated", s)
+}
+
+// Of returns the timestamp of v selected by f
+func (f TimeField) Of(v github.PackageVersion) time.Time {
+	if f == UpdatedAt {
+		return v.UpdatedAt
+	}
+	return v.CreatedAt
+}
+
+// Anchors are the named periods accepted wherever a date is expected
+var Anchors = []string{
+	"today", "yesterday", "this-week", "last-week",
+	"this-month", "last-month", "this-year", "last-year",
+}
+
+// period is the half-open time range [start, end) named by a date, datetime
+// or anchor, so that `before 2024-01-15` and `after 2024-01-15` both exclude
+// the whole day
+type period struct {
+	start, end time.Time
+}
+
+// parsePeriod parses a date value in the location of now:
+//
+//	2024-01-15T12:00   the minute
+//	2024-01-15         the day
+//	2024-01            the month
+//	RFC3339            the second, in its own offset
+//	today, yesterday, this-week, last-week, this-month, last-month,
+//	this-year, last-year
+//
+// Weeks start on Monday.
+func (p *parser) parsePeriod(t token) (period, error) {
+	loc := p.now.Location()
+	y, mo, d := p.now.Date()
+	today := time.Date(y, mo, d, 0, 0, 0, 0, loc)
+	month := time.Date(y, mo, 1, 0, 0, 0, 0, loc)
+	year := time.Date(y, 1, 1, 0, 0, 0, 0, loc)
+	week := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+
+	switch strings.ToLower(t.text) {
+	case "today":
+		return period{today, today.AddDate(0, 0, 1)}, nil
+	case "yesterday":
+		return period{today.AddDate(0, 0, -1), today}, nil
+	case "this-week":
+		return period{week, week.AddDate(0, 0, 7)}, nil
+	case "last-week":
+		return period{week.AddDate(0, 0, -7), week}, nil
+	case "this-month":
+		return period{month, month.AddDate(0, 1, 0)}, nil
+	case "last-month":
+		return period{month.AddDate(0, -1, 0), month}, nil
+	case "this-year":
+		return period{year, year.AddDate(1, 0, 0)}, nil
+	case "last-year":
+		return period{year.AddDate(-1, 0, 0), year}, nil
+	}
+
+	if parsed, err := time.Parse(time.RFC3339, t.text); err == nil {
+		return period{parsed, parsed.Add(time.Second)}, nil
+	}
+	layouts := []struct {
+		layout string
+		length func(time.Time) time.Time
+	}{
+		{"2006-01-02T15:04", func(t time.Time) time.Time { return t.Add(time.Minute) }},
+		{"2006-01-02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+		{"2006-01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	}
+	for _, l := range layouts {
+		if parsed, err := time.ParseInLocation(l.layout, t.text, loc); err == nil {
+			return period{parsed, l.length(parsed)}, nil
+		}
+	}
+	return period{}, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("invalid date %q, expected YYYY-MM-DD, YYYY-MM-DDTHH:MM or an anchor such as yesterday", t.text)}
+}
+
+// comparePeriod returns a function testing `t op p`, where the whole period
+// compares equal, or nil if op is not supported for times
+func comparePeriod(op string, p period) func(time.Time) bool {
+	switch op {
+	case "<":
+		return func(t time.Time) bool { return t.Before(p.start) }
+	case "<=":
+		return func(t time.Time) bool { return t.Before(p.end) }
+	case ">":
+		return func(t time.Time) bool { return !t.Before(p.end) }
+	case ">=":
+		return func(t time.Time) bool { return !t.Before(p.start) }
+	case "=":
+		return func(t time.Time) bool { return !t.Before(p.start) && t.Before(p.end) }
+	case "!=":
+		return func(t time.Time) bool { return t.Before(p.start) || !t.Before(p.end) }
+	}
+	return nil
+}
+
+// parseAgo parses durations such as 12h, 30d, 2w, 3mo or 1y and returns
+// the time that long before now. Days and longer units follow the calendar,
+// so 1mo is a calendar month rather than 30 days.
+func (p *parser) parseAgo(t token) (time.Time, error) {
+	s := strings.ToLower(t.text)
+	i := strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+	if i > 0 {
+		n, err := strconv.Atoi(s[:i])
+		if err == nil {
+			switch s[i:] {
+			case "h":
+				return p.now.Add(-time.Duration(n) * time.Hour), nil
+			case "d":
+				return p.now.AddDate(0, 0, -n), nil
+			case "w":
+				return p.now.AddDate(0, 0, -7*n), nil
+			case "mo":
+				return p.now.AddDate(0, -n, 0), nil
+			case "y":
+				return p.now.AddDate(-n, 0, 0), nil
+			}
+		}
+	}
+	return time.Time{}, &ParseError{Pos: t.pos, Msg: fmt.Sprintf("invalid duration %q, expected a number followed by h, d, w, mo or y", t.text)}
+}
@@ -0,0 +1,130 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse_Time(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantIDs []int
+	}{
+		{expr: "older 2w", wantIDs: []int{2, 3, 4}},
+		{expr: "older 3mo", wantIDs: []int{3, 4}},
+		{expr: "older 12h", wantIDs: []int{1, 2, 3, 4}},
+		{expr: "age>6mo", wantIDs: []int{4}},
+		{expr: "age<6mo", wantIDs: []int{1, 2, 3}},
+		{expr: "age>1y", wantIDs: nil},
+		{expr: "after 2024-06-14", wantIDs: []int{1, 2}},
+		{expr: ":after 2024-06-15", wantIDs: []int{1}},
+		{expr: "between 2024-01-01 2024-01-15", wantIDs: []int{3}},
+		{expr: "between 2023-12 2024-01", wantIDs: []int{3, 4}},
+		{expr: "created=this-month", wantIDs: []int{1, 2}},
+		{expr: "created=this-week", wantIDs: []int{1}},
+		{expr: "created=last-month", wantIDs: nil},
+		{expr: "created!=this-year", wantIDs: []int{4}},
+		{expr: "created<=2024-01-15", wantIDs: []int{3, 4}},
+		{expr: "before last-month", wantIDs: []int{3, 4}},
+		{expr: "after yesterday", wantIDs: nil},
+		{expr: "between last-year this-year", wantIDs: []int{1, 2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			f, err := Parse(tt.expr, testNow)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+			got := f.Apply(testVersions())
+			if len(got) != len(tt.wantIDs) {
+				t.Fatalf("matched %v, want IDs %v", ids(got), tt.wantIDs)
+			}
+			for i, v := range got {
+				if v.ID != tt.wantIDs[i] {
+					t.Fatalf("matched %v, want IDs %v", ids(got), tt.wantIDs)
+				}
+			}
+		})
+	}
+}
+
+func TestParseWith_UpdatedAt(t *testing.T) {
+	created, err := ParseWith("older 4d", Options{Now: testNow})
+	if err != nil {
+		t.Fatalf("ParseWith() error: %v", err)
+	}
+	updated, err := ParseWith("older 4d", Options{Now: testNow, Field: UpdatedAt})
+	if err != nil {
+		t.Fatalf("ParseWith() error: %v", err)
+	}
+
+	if got := ids(created.Apply(testVersions())); len(got) != 4 {
+		t.Errorf("created_at matched %v, want all versions", got)
+	}
+	if got := ids(updated.Apply(testVersions())); len(got) != 3 || got[0] != 2 {
+		t.Errorf("updated_at matched %v, want [2 3 4]", got)
+	}
+}
+
+func TestParse_Timezone(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	// 18:00 in Tokyo is 09:00 UTC, before version 3 was created
+	f, err := Parse("before 2024-01-15T18:00", testNow.In(tokyo))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if got := ids(f.Apply(testVersions())); len(got) != 1 || got[0] != 4 {
+		t.Errorf("matched %v, want [4]", got)
+	}
+
+	f, err = Parse("before 2024-01-15T18:00", testNow)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if got := ids(f.Apply(testVersions())); len(got) != 2 {
+		t.Errorf("matched %v in UTC, want [3 4]", got)
+	}
+}
+
+func TestParseTimeField(t *testing.T) {
+	for in, want := range map[string]TimeField{"": CreatedAt, "created": CreatedAt, "updated_at": UpdatedAt, "Updated": UpdatedAt} {
+		if got, err := ParseTimeField(in); err != nil || got != want {
+			t.Errorf("ParseTimeField(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	if _, err := ParseTimeField("pushed"); err == nil {
+		t.Error("ParseTimeField(\"pushed\") should fail")
+	}
+}
+
+func TestParse_TimeErrors(t *testing.T) {
+	tests := []struct {
+		expr    string
+		wantPos int
+	}{
+		{expr: "older 3m", wantPos: 6},
+		{expr: "after", wantPos: 5},
+		{expr: "after tomorrow", wantPos: 6},
+		{expr: "between 2024-02-01 2024-01-01", wantPos: 19},
+		{expr: "between 2024-01-01", wantPos: 18},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Parse(tt.expr, testNow)
+			var perr *ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("Parse() error = %v, want *ParseError", err)
+			}
+			if perr.Pos != tt.wantPos {
+				t.Errorf("error position = %d, want %d (%v)", perr.Pos, tt.wantPos, perr)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package github
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AppAuth authenticates as the installation of a GitHub App on an
+// organization. Installation tokens expire after an hour and are renewed
+// shortly before they do.
+type AppAuth struct {
+	AppID          int64
+	Key            *rsa.PrivateKey
+	Org            string
+	InstallationID int64 // looked up from Org when 0
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+	now     func() time.Time // replaced in tests
+}
+
+// tokenRefreshMargin is how long before expiry an installation token is
+// renewed, so requests in flight do not fail
+const tokenRefreshMargin = 5 * time.Minute
+
+// ParsePrivateKey decodes a GitHub App private key in PKCS#1 or PKCS#8 PEM form
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// NewAppClient creates a client that authenticates as the installation of
+// the app on its organization and manages the organization's packages
+func NewAppClient(app *AppAuth) *Client {
+	c := NewClient("")
+	c.app = app
+	c.org = app.Org
+	return c
+}
+
+func (a *AppAuth) clock() time.Time {
+	if a.now != nil {
+		return a.now()
+	}
+	return time.Now()
+}
+
+// JWT returns a token signed with the app's private key, used to call the
+// endpoints of the app itself. It is valid for ten minutes, backdated by one
+// to allow for clock drift.
+func (a *AppAuth) JWT() (string, error) {
+	now := a.clock()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(a.AppID, 10),
+	})
+	if err != nil {
+		return "", err
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, a.Key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign app token: %w", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// installationToken returns a valid installation token, creating a new one
+// when there is none or it is about to expire
+func (c *Client) installationToken() (string, error) {
+	a := c.app
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && a.clock().Add(tokenRefreshMargin).Before(a.expires) {
+		return a.token, nil
+	}
+
+	jwt, err := a.JWT()
+	if err != nil {
+		return "", err
+	}
+	if a.InstallationID == 0 {
+		body, err := c.send("GET", "/orgs/"+a.Org+"/installation", jwt)
+		if err != nil {
+			return "", fmt.Errorf("failed to find the app's installation on %s: %w", a.Org, err)
+		}
+		var inst Installation
+		if err := json.Unmarshal(body, &inst); err != nil {
+			return "", err
+		}
+		a.InstallationID = inst.ID
+	}
+
+	body, err := c.send("POST", fmt.Sprintf("/app/installations/%d/access_tokens", a.InstallationID), jwt)
+	if err != nil {
+		return "", fmt.Errorf("failed to create an installation token: %w", err)
+	}
+	var token InstallationToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", err
+	}
+	a.token, a.expires = token.Token, token.ExpiresAt
+	return a.token, nil
+}
+
+// expireToken drops the cached installation token, e.g. after it was rejected
+func (a *AppAuth) expireToken() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.token = ""
+}
+
+// GetApp returns the GitHub App the client authenticates as
+func (c *Client) GetApp() (*App, error) {
+	if c.app == nil {
+		return nil, fmt.Errorf("client does not authenticate as a GitHub App")
+	}
+	jwt, err := c.app.JWT()
+	if err != nil {
+		return nil, err
+	}
+	body, err := c.send("GET", "/app", jwt)
+	if err != nil {
+		return nil, err
+	}
+
+	var app App
+	if err := json.Unmarshal(body, &app); err != nil {
+		return nil, err
+	}
+
+	return &app, nil
+}
+
+// Actor returns the name deletions are recorded under: the login of the
+// user the token belongs to, or the bot account of the GitHub App
+func (c *Client) Actor() (string, error) {
+	if c.app != nil {
+		app, err := c.GetApp()
+		if err != nil {
+			return "", err
+		}
+		return app.Slug + "[bot]", nil
+	}
+	user, err := c.GetAuthenticatedUser()
+	if err != nil {
+		return "", err
+	}
+	return user.Login, nil
+}
@@ -0,0 +1,167 @@
+package github
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+)
+
+func appTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	testKeyOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testKey = key
+	})
+	return testKey
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key := appTestKey(t)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, block := range map[string]*pem.Block{
+		"pkcs1": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		"pkcs8": {Type: "PRIVATE KEY", Bytes: pkcs8},
+	} {
+		got, err := ParsePrivateKey(pem.EncodeToMemory(block))
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if !got.Equal(key) {
+			t.Errorf("%s: parsed a different key", name)
+		}
+	}
+	if _, err := ParsePrivateKey([]byte("not a key")); err == nil {
+		t.Error("expected an error for data that is not PEM")
+	}
+}
+
+func TestAppAuth_JWT(t *testing.T) {
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	a := &AppAuth{AppID: 42, Key: appTestKey(t), now: func() time.Time { return now }}
+	jwt, err := a.JWT()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		t.Fatalf("jwt has %d parts", len(parts))
+	}
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(&a.Key.PublicKey, crypto.SHA256, digest[:], sig); err != nil {
+		t.Errorf("signature does not verify: %v", err)
+	}
+
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Iss != "42" || claims.Iat != now.Add(-time.Minute).Unix() || claims.Exp != now.Add(9*time.Minute).Unix() {
+		t.Errorf("claims = %+v", claims)
+	}
+}
+
+// appServer stands in for the API of an app installed on the acme org. It
+// issues a new installation token on every request for one and accepts only
+// the newest.
+func appServer(t *testing.T) (*httptest.Server, *int) {
+	t.Helper()
+	issued := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /orgs/acme/installation", func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ey") {
+			t.Errorf("installation lookup should use the app JWT, got %q", r.Header.Get("Authorization"))
+		}
+		fmt.Fprint(w, `{"id":7}`)
+	})
+	mux.HandleFunc("POST /app/installations/7/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		issued++
+		fmt.Fprintf(w, `{"token":"ghs_%d","expires_at":"2024-06-30T13:00:00Z"}`, issued)
+	})
+	mux.HandleFunc("GET /app", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":42,"slug":"acme-cleaner","name":"Acme Cleaner"}`)
+	})
+	mux.HandleFunc("GET /orgs/acme/packages", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer ghs_%d", issued) {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message":"Bad credentials"}`)
+			return
+		}
+		fmt.Fprint(w, `[{"id":1,"name":"api"}]`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server, &issued
+}
+
+func TestAppClient_InstallationToken(t *testing.T) {
+	server, issued := appServer(t)
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	app := &AppAuth{AppID: 42, Key: appTestKey(t), Org: "acme", now: func() time.Time { return now }}
+	client := NewAppClient(app)
+	client.SetBaseURL(server.URL)
+
+	packages, err := client.ListPackages("container")
+	if err != nil {
+		t.Fatalf("ListPackages() error: %v", err)
+	}
+	if len(packages) != 1 || app.InstallationID != 7 {
+		t.Errorf("packages = %v, installation = %d", packages, app.InstallationID)
+	}
+	if _, err := client.ListPackages("container"); err != nil || *issued != 1 {
+		t.Errorf("a valid token should be reused, issued %d, err = %v", *issued, err)
+	}
+
+	// Renewed shortly before it expires
+	now = now.Add(56 * time.Minute)
+	if token := client.Token(); token != "ghs_2" {
+		t.Errorf("Token() = %q, want a renewed token", token)
+	}
+
+	if actor, err := client.Actor(); err != nil || actor != "acme-cleaner[bot]" {
+		t.Errorf("Actor() = %q, %v", actor, err)
+	}
+}
+
+func TestAppClient_RetriesRevokedToken(t *testing.T) {
+	server, issued := appServer(t)
+	now := time.Date(2024, 6, 30, 12, 0, 0, 0, time.UTC)
+	client := NewAppClient(&AppAuth{AppID: 42, Key: appTestKey(t), Org: "acme", InstallationID: 7, now: func() time.Time { return now }})
+	client.SetBaseURL(server.URL)
+	client.app.token, client.app.expires = "ghs_revoked", now.Add(time.Hour)
+
+	if _, err := client.ListPackages("container"); err != nil {
+		t.Fatalf("a rejected token should be replaced, got %v", err)
+	}
+	if *issued != 1 {
+		t.Errorf("issued %d tokens, want 1", *issued)
+	}
+}
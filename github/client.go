@@ -2,9 +2,13 @@ package github
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
@@ -13,8 +17,13 @@ const defaultBaseURL = "https://api.github.com"
 // Client is a GitHub API client for package operations
 type Client struct {
 	token      string
+	tokenMu    sync.Mutex
+	refresh    func() (string, error) // obtains a new token when the API rejects the current one
+	expiresAt  *time.Time             // when the token expires, as last reported by the API
 	httpClient *http.Client
 	baseURL    string
+	app        *AppAuth // set when authenticating as a GitHub App installation
+	org        string   // organization whose packages are managed, empty for the user's own
 }
 
 // NewClient creates a new GitHub client with the given PAT
@@ -28,73 +37,286 @@ func NewClient(token string) *Client {
 	}
 }
 
-// doRequest performs an authenticated request to GitHub API
+// SetTimeout sets how long a request may take, 30 seconds by default
+func (c *Client) SetTimeout(timeout time.Duration) {
+	c.httpClient.Timeout = timeout
+}
+
+// SetBaseURL points the client at a different API root, such as a GitHub
+// Enterprise Server instance
+func (c *Client) SetBaseURL(baseURL string) {
+	c.baseURL = strings.TrimSuffix(baseURL, "/")
+}
+
+// SetRefresh sets how to obtain a new token when the API rejects the
+// current one, such as by running a credential helper again
+func (c *Client) SetRefresh(refresh func() (string, error)) {
+	c.refresh = refresh
+}
+
+// Token returns the token the client authenticates with, renewing the
+// installation token of a GitHub App when it is about to expire
+func (c *Client) Token() string {
+	if c.app != nil {
+		token, _ := c.installationToken()
+		return token
+	}
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.token
+}
+
+// SetOrg manages the packages of an organization instead of the
+// authenticated user's own
+func (c *Client) SetOrg(org string) {
+	c.org = org
+}
+
+// Org returns the organization whose packages the client manages, empty
+// for the authenticated user's own packages
+func (c *Client) Org() string {
+	return c.org
+}
+
+// packagesPath returns the root of the packages endpoints of the user or
+// organization
+func (c *Client) packagesPath() string {
+	if c.org != "" {
+		return "/orgs/" + c.org + "/packages"
+	}
+	return "/user/packages"
+}
+
+// ExpiryWarning is how long before a token expires to warn about it
+const ExpiryWarning = 7 * 24 * time.Hour
+
+// TokenExpiration returns when the token expires, as reported by the last
+// API response, or nil when it does not expire or nothing was requested yet
+func (c *Client) TokenExpiration() *time.Time {
+	c.tokenMu.Lock()
+	defer c.tokenMu.Unlock()
+	return c.expiresAt
+}
+
+// doRequest performs an authenticated request to GitHub API, noting when the
+// token expires
 func (c *Client) doRequest(method, path string) ([]byte, error) {
+	body, header, err := c.authorizedRequest(method, path)
+	if expires := parseTokenExpiration(header); expires != nil {
+		c.tokenMu.Lock()
+		c.expiresAt = expires
+		c.tokenMu.Unlock()
+	}
+	return body, err
+}
+
+// authorizedRequest sends a request with the current token, retrying once
+// with a new one when the API rejects it and the token can be renewed
+func (c *Client) authorizedRequest(method, path string) ([]byte, http.Header, error) {
+	if c.app == nil {
+		body, header, err := c.sendHeader(method, path, c.Token())
+		var apiErr *APIError
+		if c.refresh != nil && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+			// The token expired or was revoked, try once more with a new one
+			token, err := c.refresh()
+			if err != nil {
+				return nil, nil, err
+			}
+			c.tokenMu.Lock()
+			c.token = token
+			c.expiresAt = nil
+			c.tokenMu.Unlock()
+			return c.sendHeader(method, path, token)
+		}
+		return body, header, err
+	}
+
+	token, err := c.installationToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	body, header, err := c.sendHeader(method, path, token)
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		// The installation token was revoked, try once more with a new one
+		c.app.expireToken()
+		if token, err = c.installationToken(); err != nil {
+			return nil, nil, err
+		}
+		body, header, err = c.sendHeader(method, path, token)
+	}
+	return body, header, err
+}
+
+// send performs a request to GitHub API with the given bearer token
+func (c *Client) send(method, path, token string) ([]byte, error) {
+	body, _, err := c.sendHeader(method, path, token)
+	return body, err
+}
+
+// sendHeader is send, also returning the response headers
+func (c *Client) sendHeader(method, path, token string) ([]byte, http.Header, error) {
 	req, err := http.NewRequest(method, c.baseURL+path, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Authorization", "Bearer "+token)
 	req.Header.Set("Accept", "application/vnd.github+json")
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
 	// #nosec G704 -- The baseURL is configured within the client and path is constructed from API methods.
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if resp.StatusCode >= 400 {
-		return nil, parseAPIError(resp.StatusCode, body)
+		if sso := parseSSO(resp.Header); sso != nil && resp.StatusCode == http.StatusForbidden {
+			return nil, resp.Header, &APIError{StatusCode: resp.StatusCode, err: sso}
+		}
+		return nil, resp.Header, parseAPIError(resp.StatusCode, body)
 	}
 
-	return body, nil
+	return body, resp.Header, nil
 }
 
-// ListPackages lists all packages for the authenticated user
+// ListPackages lists all packages for the authenticated user, or the
+// organization of a GitHub App
 func (c *Client) ListPackages(packageType string) ([]Package, error) {
-	path := fmt.Sprintf("/user/packages?package_type=%s", packageType)
-	body, err := c.doRequest("GET", path)
+	const perPage = 100
+	var packages []Package
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("%s?package_type=%s&per_page=%d&page=%d", c.packagesPath(), packageType, perPage, page)
+		body, err := c.doRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var batch []Package
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		packages = append(packages, batch...)
+		if len(batch) < perPage {
+			return packages, nil
+		}
+	}
+}
+
+// ListPackageVersions lists all versions for a package
+func (c *Client) ListPackageVersions(packageType, packageName string) ([]PackageVersion, error) {
+	const perPage = 100
+	var versions []PackageVersion
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("%s/%s/%s/versions?per_page=%d&page=%d", c.packagesPath(), packageType, packageName, perPage, page)
+		body, err := c.doRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var batch []PackageVersion
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		versions = append(versions, batch...)
+		if len(batch) < perPage {
+			return versions, nil
+		}
+	}
+}
+
+// DeletePackageVersion deletes a specific package version
+func (c *Client) DeletePackageVersion(packageType, packageName string, versionID int) error {
+	path := fmt.Sprintf("%s/%s/%s/versions/%d", c.packagesPath(), packageType, packageName, versionID)
+	_, err := c.doRequest("DELETE", path)
+	return err
+}
+
+// DeletePackage deletes a package along with all of its versions
+func (c *Client) DeletePackage(packageType, packageName string) error {
+	path := fmt.Sprintf("%s/%s/%s", c.packagesPath(), packageType, packageName)
+	_, err := c.doRequest("DELETE", path)
+	return err
+}
+
+// RestorePackageVersion restores a deleted package version
+func (c *Client) RestorePackageVersion(packageType, packageName string, versionID int) error {
+	path := fmt.Sprintf("%s/%s/%s/versions/%d/restore", c.packagesPath(), packageType, packageName, versionID)
+	_, err := c.doRequest("POST", path)
+	return err
+}
+
+// GetAuthenticatedUser returns the user the token belongs to
+func (c *Client) GetAuthenticatedUser() (*User, error) {
+	body, err := c.doRequest("GET", "/user")
 	if err != nil {
 		return nil, err
 	}
 
-	var packages []Package
-	if err := json.Unmarshal(body, &packages); err != nil {
+	var user User
+	if err := json.Unmarshal(body, &user); err != nil {
 		return nil, err
 	}
 
-	return packages, nil
+	return &user, nil
 }
 
-// ListPackageVersions lists all versions for a package
-func (c *Client) ListPackageVersions(packageType, packageName string) ([]PackageVersion, error) {
-	path := fmt.Sprintf("/user/packages/%s/%s/versions?per_page=100", packageType, packageName)
-	body, err := c.doRequest("GET", path)
+// GetTokenInfo returns who the client authenticates as, with the scopes
+// and expiration of a personal or OAuth token, or the expiration of a
+// GitHub App's installation token
+func (c *Client) GetTokenInfo() (*TokenInfo, error) {
+	if c.app != nil {
+		actor, err := c.Actor()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := c.installationToken(); err != nil {
+			return nil, err
+		}
+		c.app.mu.Lock()
+		expires := c.app.expires
+		c.app.mu.Unlock()
+		return &TokenInfo{Login: actor, ExpiresAt: &expires}, nil
+	}
+
+	body, header, err := c.sendHeader("GET", "/user", c.Token())
 	if err != nil {
 		return nil, err
 	}
-
-	var versions []PackageVersion
-	if err := json.Unmarshal(body, &versions); err != nil {
+	var user User
+	if err := json.Unmarshal(body, &user); err != nil {
 		return nil, err
 	}
 
-	return versions, nil
+	info := &TokenInfo{Login: user.Login, ExpiresAt: parseTokenExpiration(header)}
+	if scopes := header.Get("X-OAuth-Scopes"); scopes != "" {
+		for _, scope := range strings.Split(scopes, ",") {
+			info.Scopes = append(info.Scopes, strings.TrimSpace(scope))
+		}
+	}
+	return info, nil
 }
 
-// DeletePackageVersion deletes a specific package version
-func (c *Client) DeletePackageVersion(packageType, packageName string, versionID int) error {
-	path := fmt.Sprintf("/user/packages/%s/%s/versions/%d", packageType, packageName, versionID)
-	_, err := c.doRequest("DELETE", path)
-	return err
+// parseTokenExpiration reads when the token expires from the
+// GitHub-Authentication-Token-Expiration header, nil when it does not
+func parseTokenExpiration(header http.Header) *time.Time {
+	value := header.Get("GitHub-Authentication-Token-Expiration")
+	if value == "" {
+		return nil
+	}
+	for _, layout := range []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return nil
 }
 
 // ValidateToken checks if the token is valid by attempting to list packages
@@ -103,6 +325,201 @@ func (c *Client) ValidateToken() error {
 	return err
 }
 
+// GetPackage returns a single package of the authenticated user
+func (c *Client) GetPackage(packageType, packageName string) (*Package, error) {
+	path := fmt.Sprintf("%s/%s/%s", c.packagesPath(), packageType, packageName)
+	body, err := c.doRequest("GET", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var pkg Package
+	if err := json.Unmarshal(body, &pkg); err != nil {
+		return nil, err
+	}
+
+	return &pkg, nil
+}
+
+// GetRepository returns the repository owner/repo
+func (c *Client) GetRepository(owner, repo string) (*Repository, error) {
+	path := fmt.Sprintf("/repos/%s/%s", owner, repo)
+	body, err := c.doRequest("GET", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r Repository
+	if err := json.Unmarshal(body, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// GetPullRequest returns a pull request of the repository owner/repo
+func (c *Client) GetPullRequest(owner, repo string, number int) (*PullRequest, error) {
+	path := fmt.Sprintf("/repos/%s/%s/pulls/%d", owner, repo, number)
+	body, err := c.doRequest("GET", path)
+	if err != nil {
+		return nil, err
+	}
+
+	var pr PullRequest
+	if err := json.Unmarshal(body, &pr); err != nil {
+		return nil, err
+	}
+
+	return &pr, nil
+}
+
+// ListBranches lists every branch of the repository owner/repo
+func (c *Client) ListBranches(owner, repo string) ([]Branch, error) {
+	const perPage = 100
+	var branches []Branch
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/repos/%s/%s/branches?per_page=%d&page=%d", owner, repo, perPage, page)
+		body, err := c.doRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var batch []Branch
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		branches = append(branches, batch...)
+		if len(batch) < perPage {
+			return branches, nil
+		}
+	}
+}
+
+// ListReleases lists every release of the repository owner/repo
+func (c *Client) ListReleases(owner, repo string) ([]Release, error) {
+	const perPage = 100
+	var releases []Release
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/repos/%s/%s/releases?per_page=%d&page=%d", owner, repo, perPage, page)
+		body, err := c.doRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var batch []Release
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		releases = append(releases, batch...)
+		if len(batch) < perPage {
+			return releases, nil
+		}
+	}
+}
+
+// ListDeployments lists every deployment of the repository owner/repo,
+// newest first
+func (c *Client) ListDeployments(owner, repo string) ([]Deployment, error) {
+	const perPage = 100
+	var deployments []Deployment
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/repos/%s/%s/deployments?per_page=%d&page=%d", owner, repo, perPage, page)
+		body, err := c.doRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var batch []Deployment
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		deployments = append(deployments, batch...)
+		if len(batch) < perPage {
+			return deployments, nil
+		}
+	}
+}
+
+// ListDeploymentStatuses lists every status of a deployment, newest first
+func (c *Client) ListDeploymentStatuses(owner, repo string, deploymentID int) ([]DeploymentStatus, error) {
+	const perPage = 100
+	var statuses []DeploymentStatus
+	for page := 1; ; page++ {
+		path := fmt.Sprintf("/repos/%s/%s/deployments/%d/statuses?per_page=%d&page=%d", owner, repo, deploymentID, perPage, page)
+		body, err := c.doRequest("GET", path)
+		if err != nil {
+			return nil, err
+		}
+
+		var batch []DeploymentStatus
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, batch...)
+		if len(batch) < perPage {
+			return statuses, nil
+		}
+	}
+}
+
+// APIError is an error response from the GitHub API
+type APIError struct {
+	StatusCode int
+	err        error
+}
+
+func (e *APIError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("GitHub API error (status %d)", e.StatusCode)
+	}
+	return e.err.Error()
+}
+
+func (e *APIError) Unwrap() error {
+	return e.err
+}
+
+// SSOError is returned when an organization enforces SAML single sign-on
+// and the token has not been authorized for it
+type SSOError struct {
+	Org string // empty when the URL does not name it
+	URL string // where to authorize the token
+}
+
+func (e *SSOError) Error() string {
+	org := "an organization"
+	if e.Org != "" {
+		org = "the " + e.Org + " organization"
+	}
+	return fmt.Sprintf("%s requires SAML single sign-on. Authorize the token at %s", org, e.URL)
+}
+
+// parseSSO reads the X-GitHub-SSO header of a response that single sign-on
+// blocked, such as "required; url=https://github.com/orgs/acme/sso?...",
+// nil when there is none
+func parseSSO(header http.Header) *SSOError {
+	value := header.Get("X-GitHub-SSO")
+	kind, params, _ := strings.Cut(value, ";")
+	if strings.TrimSpace(kind) != "required" {
+		return nil
+	}
+	sso := &SSOError{}
+	for _, param := range strings.Split(params, ";") {
+		if u, ok := strings.CutPrefix(strings.TrimSpace(param), "url="); ok {
+			sso.URL = u
+		}
+	}
+	if sso.URL == "" {
+		return nil
+	}
+	if u, err := url.Parse(sso.URL); err == nil {
+		if parts := strings.Split(strings.Trim(u.Path, "/"), "/"); len(parts) >= 2 && parts[0] == "orgs" {
+			sso.Org = parts[1]
+		}
+	}
+	return sso
+}
+
 // apiErrorResponse represents the error response from GitHub API
 type apiErrorResponse struct {
 	Message string `json:"message"`
@@ -110,6 +527,10 @@ type apiErrorResponse struct {
 
 // parseAPIError converts GitHub API error responses into user-friendly messages
 func parseAPIError(statusCode int, body []byte) error {
+	return &APIError{StatusCode: statusCode, err: describeAPIError(statusCode, body)}
+}
+
+func describeAPIError(statusCode int, body []byte) error {
 	var apiErr apiErrorResponse
 	_ = json.Unmarshal(body, &apiErr) // ignore unmarshal errors, we'll use fallback
 
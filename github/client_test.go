@@ -2,6 +2,8 @@ package github
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -97,11 +99,47 @@ func TestClient_ListPackages(t *testing.T) {
 	}
 }
 
+func TestClient_ListPackages_Pages(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("package_type"); got != "container" {
+			t.Errorf("package_type = %q", got)
+		}
+		// A full first page followed by a partial one
+		var packages []Package
+		if r.URL.Query().Get("page") == "1" {
+			for i := 0; i < 100; i++ {
+				packages = append(packages, Package{ID: i + 1, Name: fmt.Sprintf("pkg%d", i)})
+			}
+		} else {
+			packages = []Package{{ID: 500, Name: "last"}}
+		}
+		json.NewEncoder(w).Encode(packages)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.baseURL = server.URL
+
+	packages, err := client.ListPackages("container")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(packages) != 101 || packages[100].Name != "last" {
+		t.Errorf("got %d packages, want 101 ending with last", len(packages))
+	}
+}
+
 func TestClient_ListPackageVersions(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		versions := []PackageVersion{
-			{ID: 1, Name: "sha256:abc123"},
-			{ID: 2, Name: "sha256:def456"},
+		// Two full pages followed by a partial one
+		var versions []PackageVersion
+		page := r.URL.Query().Get("page")
+		if page == "1" || page == "2" {
+			for i := 0; i < 100; i++ {
+				versions = append(versions, PackageVersion{ID: 1000 + i, Name: fmt.Sprintf("sha256:%s-%d", page, i)})
+			}
+		} else {
+			versions = []PackageVersion{{ID: 1, Name: "sha256:abc123"}, {ID: 2, Name: "sha256:def456"}}
 		}
 		json.NewEncoder(w).Encode(versions)
 	}))
@@ -114,8 +152,8 @@ func TestClient_ListPackageVersions(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if len(versions) != 2 {
-		t.Errorf("version count = %d, want 2", len(versions))
+	if len(versions) != 202 || versions[201].Name != "sha256:def456" {
+		t.Errorf("version count = %d, want 202 across three pages", len(versions))
 	}
 }
 
@@ -269,6 +307,299 @@ func TestParseAPIError(t *testing.T) {
 			if !strings.Contains(err.Error(), tt.wantContain) {
 				t.Errorf("error = %q, want containing %q", err.Error(), tt.wantContain)
 			}
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || apiErr.StatusCode != tt.statusCode {
+				t.Errorf("error = %#v, want an APIError with status %d", err, tt.statusCode)
+			}
+		})
+	}
+}
+
+func TestClient_GetPullRequest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/octo/app/pulls/42" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		w.Write([]byte(`{"number":42,"state":"closed","merged_at":"2024-01-15T10:00:00Z"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.baseURL = server.URL
+
+	pr, err := client.GetPullRequest("octo", "app", 42)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pr.State != "closed" || pr.MergedAt == nil {
+		t.Errorf("pull request = %+v, want closed and merged", pr)
+	}
+}
+
+func TestClient_ListBranches(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/octo/app/branches" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		// A full first page followed by a partial one
+		var branches []Branch
+		if r.URL.Query().Get("page") == "1" {
+			for i := 0; i < 100; i++ {
+				branches = append(branches, Branch{Name: fmt.Sprintf("b%d", i)})
+			}
+		} else {
+			branches = []Branch{{Name: "main"}}
+		}
+		json.NewEncoder(w).Encode(branches)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.baseURL = server.URL
+
+	branches, err := client.ListBranches("octo", "app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(branches) != 101 || branches[100].Name != "main" {
+		t.Errorf("got %d branches, want 101 ending with main", len(branches))
+	}
+}
+
+func TestClient_ListDeployments(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A full first page followed by a partial one, for deployments and
+		// for the statuses of deployment 7
+		full := r.URL.Query().Get("page") == "1"
+		switch r.URL.Path {
+		case "/repos/octo/app/deployments":
+			var deployments []Deployment
+			if full {
+				for i := 0; i < 100; i++ {
+					deployments = append(deployments, Deployment{ID: 1000 + i, Environment: "preview"})
+				}
+			} else {
+				deployments = []Deployment{{ID: 7, Environment: "production"}}
+			}
+			json.NewEncoder(w).Encode(deployments)
+		case "/repos/octo/app/deployments/7/statuses":
+			var statuses []DeploymentStatus
+			if full {
+				for i := 0; i < 100; i++ {
+					statuses = append(statuses, DeploymentStatus{State: "inactive"})
+				}
+			} else {
+				statuses = []DeploymentStatus{{State: "success"}}
+			}
+			json.NewEncoder(w).Encode(statuses)
+		default:
+			t.Errorf("path = %q", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.baseURL = server.URL
+
+	deployments, err := client.ListDeployments("octo", "app")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deployments) != 101 || deployments[100].ID != 7 {
+		t.Errorf("got %d deployments, want 101 ending with 7", len(deployments))
+	}
+	statuses, err := client.ListDeploymentStatuses("octo", "app", 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(statuses) != 101 || statuses[100].State != "success" {
+		t.Errorf("got %d statuses, want 101 ending with success", len(statuses))
+	}
+}
+
+func TestClient_RestorePackageVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" {
+			t.Errorf("method = %q, want POST", r.Method)
+		}
+		if r.URL.Path != "/user/packages/container/test-pkg/versions/123/restore" {
+			t.Errorf("path = %q", r.URL.Path)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.baseURL = server.URL
+
+	if err := client.RestorePackageVersion("container", "test-pkg", 123); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestClient_GetAuthenticatedUser(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/user" {
+			t.Errorf("path = %q, want /user", r.URL.Path)
+		}
+		w.Write([]byte(`{"id":1,"login":"octocat"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.baseURL = server.URL
+
+	user, err := client.GetAuthenticatedUser()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Login != "octocat" {
+		t.Errorf("login = %q, want %q", user.Login, "octocat")
+	}
+}
+
+func TestClient_Refresh(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message":"Bad credentials"}`))
+			return
+		}
+		w.Write([]byte(`{"id":1,"login":"octocat"}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("old-token")
+	client.baseURL = server.URL
+	if _, err := client.GetAuthenticatedUser(); err == nil {
+		t.Fatal("expected an error without a way to refresh the token")
+	}
+
+	refreshes := 0
+	client.SetRefresh(func() (string, error) {
+		refreshes++
+		return "new-token", nil
+	})
+	for range 2 {
+		if _, err := client.GetAuthenticatedUser(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if refreshes != 1 || client.Token() != "new-token" {
+		t.Errorf("refreshes = %d, token = %q, want one refresh to the new token", refreshes, client.Token())
+	}
+}
+
+func TestClient_SSORequired(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-GitHub-SSO", "required; url=https://github.com/orgs/acme/sso?authorization_request=AbC123")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"Resource protected by organization SAML enforcement."}`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.baseURL = server.URL
+	client.SetOrg("acme")
+
+	_, err := client.ListPackages("container")
+	var sso *SSOError
+	if !errors.As(err, &sso) {
+		t.Fatalf("error = %v, want an SSOError", err)
+	}
+	if sso.Org != "acme" || sso.URL != "https://github.com/orgs/acme/sso?authorization_request=AbC123" {
+		t.Errorf("SSOError = %+v", sso)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("error = %v, want the 403 kept", err)
+	}
+	if !strings.Contains(err.Error(), "the acme organization requires SAML single sign-on") {
+		t.Errorf("error = %q, want the organization named", err)
+	}
+}
+
+func TestClient_TokenExpiration(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("GitHub-Authentication-Token-Expiration", "2024-07-30 12:00:00 UTC")
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	client := NewClient("test-token")
+	client.baseURL = server.URL
+	if client.TokenExpiration() != nil {
+		t.Fatal("expiration should be unknown before any request")
+	}
+	if _, err := client.ListPackages("container"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := time.Date(2024, 7, 30, 12, 0, 0, 0, time.UTC)
+	if got := client.TokenExpiration(); got == nil || !got.Equal(want) {
+		t.Errorf("TokenExpiration() = %v, want %v", got, want)
+	}
+}
+
+func TestClient_SetBaseURL(t *testing.T) {
+	client := NewClient("test-token")
+	client.SetBaseURL("https://ghes.example.com/api/v3/")
+
+	if client.baseURL != "https://ghes.example.com/api/v3" {
+		t.Errorf("baseURL = %q, want trailing slash trimmed", client.baseURL)
+	}
+}
+
+func TestClient_GetTokenInfo(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      map[string]string
+		wantScopes  []string
+		wantExpires string
+	}{
+		{
+			name:       "classic token",
+			header:     map[string]string{"X-OAuth-Scopes": "delete:packages, read:packages, repo"},
+			wantScopes: []string{"delete:packages", "read:packages", "repo"},
+		},
+		{
+			name:        "fine-grained token",
+			header:      map[string]string{"GitHub-Authentication-Token-Expiration": "2024-07-30 12:00:00 UTC"},
+			wantExpires: "2024-07-30T12:00:00Z",
+		},
+		{
+			name:        "expiration with an offset",
+			header:      map[string]string{"GitHub-Authentication-Token-Expiration": "2024-07-30 05:00:00 -0700"},
+			wantExpires: "2024-07-30T12:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.Write([]byte(`{"login":"octo"}`))
+			}))
+			defer server.Close()
+
+			client := NewClient("test-token")
+			client.baseURL = server.URL
+
+			info, err := client.GetTokenInfo()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if info.Login != "octo" || strings.Join(info.Scopes, "|") != strings.Join(tt.wantScopes, "|") {
+				t.Errorf("info = %+v", info)
+			}
+			var expires string
+			if info.ExpiresAt != nil {
+				expires = info.ExpiresAt.UTC().Format(time.RFC3339)
+			}
+			if expires != tt.wantExpires {
+				t.Errorf("expires = %q, want %q", expires, tt.wantExpires)
+			}
 		})
 	}
 }
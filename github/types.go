@@ -1,6 +1,79 @@
 package github
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
+
+// User represents a GitHub user account
+type User struct {
+	ID    int    `json:"id"`
+	Login string `json:"login"`
+}
+
+// TokenInfo describes the token a client authenticates with
+type TokenInfo struct {
+	Login     string
+	Scopes    []string   // scopes of a classic token, nil for fine-grained tokens and apps
+	ExpiresAt *time.Time // nil when the token does not expire
+}
+
+// Repository represents a GitHub repository
+type Repository struct {
+	FullName string `json:"full_name"`
+	Archived bool   `json:"archived"`
+}
+
+// PullRequest represents a GitHub pull request
+type PullRequest struct {
+	Number   int        `json:"number"`
+	State    string     `json:"state"` // "open" or "closed"
+	MergedAt *time.Time `json:"merged_at"`
+}
+
+// Branch represents a branch of a GitHub repository
+type Branch struct {
+	Name string `json:"name"`
+}
+
+// Release represents a GitHub release
+type Release struct {
+	TagName string `json:"tag_name"`
+	Draft   bool   `json:"draft"`
+}
+
+// Deployment represents a deployment of a repository to an environment
+type Deployment struct {
+	ID          int             `json:"id"`
+	Ref         string          `json:"ref"`
+	Sha         string          `json:"sha"`
+	Environment string          `json:"environment"`
+	Payload     json.RawMessage `json:"payload"` // an object, or a string for older deployments
+}
+
+// DeploymentStatus represents the state of a deployment, such as success
+// or inactive
+type DeploymentStatus struct {
+	State string `json:"state"`
+}
+
+// App represents a GitHub App
+type App struct {
+	ID   int64  `json:"id"`
+	Slug string `json:"slug"`
+	Name string `json:"name"`
+}
+
+// Installation represents the installation of a GitHub App on an account
+type Installation struct {
+	ID int64 `json:"id"`
+}
+
+// InstallationToken is a short-lived token of a GitHub App installation
+type InstallationToken struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
 
 // Package represents a GitHub package
 type Package struct {
@@ -0,0 +1,179 @@
+package lifecycle
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+// Client is the part of the GitHub API used to look up pull requests and
+// branches
+type Client interface {
+	GetPullRequest(owner, repo string, number int) (*github.PullRequest, error)
+	ListBranches(owner, repo string) ([]github.Branch, error)
+}
+
+// Patterns extract pull request numbers and branch names from tags
+type Patterns struct {
+	PullRequest *regexp.Regexp // nil to skip pull requests
+	Branch      *regexp.Regexp // nil to skip branches
+}
+
+// CompilePatterns compiles the configured tag patterns. Each must have a
+// capture group for the pull request number or branch name.
+func CompilePatterns(cfg config.Lifecycle) (Patterns, error) {
+	var p Patterns
+	var err error
+	if p.PullRequest, err = compile("pull_request_tags", cfg.PullRequestTags); err != nil {
+		return p, err
+	}
+	if p.Branch, err = compile("branch_tags", cfg.BranchTags); err != nil {
+		return p, err
+	}
+	return p, nil
+}
+
+func compile(name, pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if re.NumSubexp() == 0 {
+		return nil, fmt.Errorf("%s: %q has no capture group", name, pattern)
+	}
+	return re, nil
+}
+
+// capture returns the first capture group of re against tag
+func capture(re *regexp.Regexp, tag string) (string, bool) {
+	if re == nil {
+		return "", false
+	}
+	m := re.FindStringSubmatch(tag)
+	if m == nil || m[1] == "" {
+		return "", false
+	}
+	return m[1], true
+}
+
+// Status knows which of the pull requests and branches named by a package's
+// tags are gone. It implements filter.Lifecycle.
+type Status struct {
+	patterns Patterns
+	closed   map[int]bool    // pull request number to whether it is closed or merged
+	branches map[string]bool // existing branches, by name and by tag-safe name
+}
+
+// Load looks up the pull requests and branches named by the tags of
+// versions in repo, given as owner/name. Pull requests that do not exist
+// are treated as open.
+func Load(client Client, repo string, patterns Patterns, versions []github.PackageVersion) (*Status, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid repository %q, expected owner/name", repo)
+	}
+
+	s := &Status{patterns: patterns, closed: make(map[int]bool)}
+	needBranches := false
+	for _, v := range versions {
+		for _, tag := range v.Tags() {
+			if _, ok := capture(patterns.Branch, tag); ok {
+				needBranches = true
+			}
+			number, ok := s.pullRequest(tag)
+			if !ok {
+				continue
+			}
+			if _, seen := s.closed[number]; seen {
+				continue
+			}
+			pr, err := client.GetPullRequest(owner, name, number)
+			var apiErr *github.APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+				s.closed[number] = false
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("pull request #%d: %w", number, err)
+			}
+			s.closed[number] = pr.State == "closed"
+		}
+	}
+
+	if needBranches {
+		branches, err := client.ListBranches(owner, name)
+		if err != nil {
+			return nil, fmt.Errorf("branches of %s: %w", repo, err)
+		}
+		s.branches = make(map[string]bool, 2*len(branches))
+		for _, b := range branches {
+			s.branches[b.Name] = true
+			s.branches[tagSafe(b.Name)] = true
+		}
+	}
+	return s, nil
+}
+
+func (s *Status) pullRequest(tag string) (int, bool) {
+	value, ok := capture(s.patterns.PullRequest, tag)
+	if !ok {
+		return 0, false
+	}
+	number, err := strconv.Atoi(value)
+	return number, err == nil
+}
+
+// PullRequestClosed reports whether v has pull request tags and every one
+// of those pull requests is closed or merged
+func (s *Status) PullRequestClosed(v github.PackageVersion) bool {
+	found := false
+	for _, tag := range v.Tags() {
+		number, ok := s.pullRequest(tag)
+		if !ok {
+			continue
+		}
+		if !s.closed[number] {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// BranchDeleted reports whether v has branch tags and none of those
+// branches exists any more
+func (s *Status) BranchDeleted(v github.PackageVersion) bool {
+	if s.branches == nil {
+		return false
+	}
+	found := false
+	for _, tag := range v.Tags() {
+		branch, ok := capture(s.patterns.Branch, tag)
+		if !ok {
+			continue
+		}
+		if s.branches[branch] {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+var tagUnsafe = regexp.MustCompile(`[^A-Za-z0-9_.-]`)
+
+// tagSafe returns branch as CI usually writes it into a tag, with every
+// character a tag cannot hold replaced by a dash, so feature/login becomes
+// feature-login
+func tagSafe(branch string) string {
+	return tagUnsafe.ReplaceAllString(branch, "-")
+}
@@ -0,0 +1,128 @@
+package lifecycle
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+type fakeClient struct {
+	pulls    map[int]string // number to state, missing numbers are not found
+	branches []string
+	requests []string
+}
+
+func (c *fakeClient) GetPullRequest(owner, repo string, number int) (*github.PullRequest, error) {
+	c.requests = append(c.requests, "pull")
+	state, ok := c.pulls[number]
+	if !ok {
+		// The same error the client returns for a 404 response
+		return nil, &github.APIError{StatusCode: 404}
+	}
+	return &github.PullRequest{Number: number, State: state}, nil
+}
+
+func (c *fakeClient) ListBranches(owner, repo string) ([]github.Branch, error) {
+	c.requests = append(c.requests, "branches")
+	var branches []github.Branch
+	for _, name := range c.branches {
+		branches = append(branches, github.Branch{Name: name})
+	}
+	return branches, nil
+}
+
+func version(id int, tags ...string) github.PackageVersion {
+	v := github.PackageVersion{ID: id}
+	v.Metadata.Container.Tags = tags
+	return v
+}
+
+func TestLoad(t *testing.T) {
+	patterns, err := CompilePatterns(config.Default().Lifecycle)
+	if err != nil {
+		t.Fatalf("CompilePatterns() error: %v", err)
+	}
+	client := &fakeClient{
+		pulls:    map[int]string{1: "open", 2: "closed", 3: "closed"},
+		branches: []string{"main", "feature/login"},
+	}
+	versions := []github.PackageVersion{
+		version(1, "pr-1"),
+		version(2, "pr-2"),
+		version(3, "pr-2", "pr-3"),
+		version(4, "pr-2", "pr-1"),
+		version(5, "pr-404"),
+		version(6, "branch-main"),
+		version(7, "branch-feature-login"),
+		version(8, "branch-old"),
+		version(9, "latest"),
+	}
+
+	s, err := Load(client, "octo/app", patterns, versions)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	// pr-2 appears on three versions but is looked up once
+	if got := strings.Join(client.requests, ","); got != "pull,pull,pull,pull,branches" {
+		t.Errorf("requests = %s", got)
+	}
+
+	tests := []struct {
+		id           int
+		closed, gone bool
+	}{
+		{id: 1},
+		{id: 2, closed: true},
+		{id: 3, closed: true},
+		{id: 4}, // still tagged for an open pull request
+		{id: 5}, // unknown pull requests are treated as open
+		{id: 6},
+		{id: 7}, // branch names are compared in their tag-safe form too
+		{id: 8, gone: true},
+		{id: 9},
+	}
+	for _, tt := range tests {
+		v := versions[tt.id-1]
+		if got := s.PullRequestClosed(v); got != tt.closed {
+			t.Errorf("PullRequestClosed(%v) = %v, want %v", v.Tags(), got, tt.closed)
+		}
+		if got := s.BranchDeleted(v); got != tt.gone {
+			t.Errorf("BranchDeleted(%v) = %v, want %v", v.Tags(), got, tt.gone)
+		}
+	}
+}
+
+func TestLoad_SkipsUnusedLookups(t *testing.T) {
+	patterns, _ := CompilePatterns(config.Lifecycle{PullRequestTags: `^pr-(\d+)$`})
+	client := &fakeClient{}
+
+	s, err := Load(client, "octo/app", patterns, []github.PackageVersion{version(1, "latest"), version(2, "branch-old")})
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if len(client.requests) != 0 {
+		t.Errorf("requests = %v, want none", client.requests)
+	}
+	if s.BranchDeleted(version(2, "branch-old")) {
+		t.Error("branches must never count as deleted when branch_tags is disabled")
+	}
+}
+
+func TestCompilePatterns_Invalid(t *testing.T) {
+	for _, cfg := range []config.Lifecycle{
+		{PullRequestTags: `^pr-\d+$`},
+		{BranchTags: `^branch-(`},
+	} {
+		if _, err := CompilePatterns(cfg); err == nil {
+			t.Errorf("CompilePatterns(%+v) expected an error", cfg)
+		}
+	}
+}
+
+func TestLoad_InvalidRepository(t *testing.T) {
+	if _, err := Load(&fakeClient{}, "app", Patterns{}, nil); err == nil {
+		t.Error("expected an error for a repository without an owner")
+	}
+}
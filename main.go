@@ -6,6 +6,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/maful/hij/cli"
 	"github.com/maful/hij/ui"
 	"github.com/maful/hij/updater"
 )
@@ -17,14 +18,42 @@ var (
 )
 
 func main() {
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
+	args, err := cli.GlobalFlags(os.Args[1:])
+	if err != nil {
+		exit(err)
+	}
+	if len(args) > 0 {
+		switch args[0] {
 		case "version":
 			fmt.Printf("hij version %s (%s) built at %s\n", version, commit, date)
 			return
 		case "update":
 			updater.Update(version)
 			return
+		case "app-key":
+			exit(cli.AppKey(args[1:]))
+			return
+		case "auth":
+			exit(cli.Auth(args[1:]))
+			return
+		case "config":
+			exit(cli.Config(args[1:]))
+			return
+		case "audit":
+			exit(cli.Audit(args[1:]))
+			return
+		case "delete":
+			exit(cli.Delete(args[1:]))
+			return
+		case "login":
+			exit(cli.Login(args[1:]))
+			return
+		case "packages":
+			exit(cli.Packages(args[1:]))
+			return
+		case "restore":
+			exit(cli.Restore(args[1:]))
+			return
 		}
 	}
 
@@ -34,3 +63,11 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// exit terminates the process with a non-zero status if a subcommand failed
+func exit(err error) {
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
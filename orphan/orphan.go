@@ -0,0 +1,76 @@
+package orphan
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/maful/hij/github"
+)
+
+// Reason explains why a package is an orphan
+type Reason string
+
+const (
+	Unlinked Reason = "not linked to a repository"
+	Deleted  Reason = "repository deleted"
+	Archived Reason = "repository archived"
+)
+
+// Orphan is a package that no longer has a live repository
+type Orphan struct {
+	Package github.Package
+	Reason  Reason
+}
+
+// Client is the part of the GitHub API used to look up repositories
+type Client interface {
+	GetRepository(owner, repo string) (*github.Repository, error)
+}
+
+// Find checks the repository of each package and returns the orphans in
+// the order given. A repository the token cannot see is reported as
+// deleted, since the API answers both with 404.
+func Find(client Client, packages []github.Package) ([]Orphan, error) {
+	checked := make(map[string]Reason) // repository to reason, "" when live
+	var orphans []Orphan
+	for _, p := range packages {
+		if p.Repository == nil {
+			orphans = append(orphans, Orphan{Package: p, Reason: Unlinked})
+			continue
+		}
+		repo := p.Repository.FullName
+		reason, ok := checked[repo]
+		if !ok {
+			var err error
+			if reason, err = check(client, repo); err != nil {
+				return nil, fmt.Errorf("repository of %s: %w", p.Name, err)
+			}
+			checked[repo] = reason
+		}
+		if reason != "" {
+			orphans = append(orphans, Orphan{Package: p, Reason: reason})
+		}
+	}
+	return orphans, nil
+}
+
+func check(client Client, repo string) (Reason, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok {
+		return "", fmt.Errorf("invalid repository %q, expected owner/name", repo)
+	}
+	r, err := client.GetRepository(owner, name)
+	var apiErr *github.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return Deleted, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if r.Archived {
+		return Archived, nil
+	}
+	return "", nil
+}
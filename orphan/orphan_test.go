@@ -0,0 +1,80 @@
+package orphan
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/maful/hij/github"
+)
+
+type fakeClient struct {
+	repos map[string]bool // full name to archived, missing repositories are not found
+	calls int
+}
+
+func (c *fakeClient) GetRepository(owner, repo string) (*github.Repository, error) {
+	c.calls++
+	archived, ok := c.repos[owner+"/"+repo]
+	if !ok {
+		return nil, &github.APIError{StatusCode: 404}
+	}
+	return &github.Repository{FullName: owner + "/" + repo, Archived: archived}, nil
+}
+
+func pkg(name, repo string) github.Package {
+	p := github.Package{Name: name}
+	if repo != "" {
+		p.Repository = &struct {
+			ID       int    `json:"id"`
+			Name     string `json:"name"`
+			FullName string `json:"full_name"`
+		}{FullName: repo}
+	}
+	return p
+}
+
+func TestFind(t *testing.T) {
+	client := &fakeClient{repos: map[string]bool{"octo/live": false, "octo/old": true}}
+	packages := []github.Package{
+		pkg("api", "octo/live"),
+		pkg("scratch", ""),
+		pkg("legacy", "octo/old"),
+		pkg("gone", "octo/gone"),
+		pkg("api-worker", "octo/live"),
+	}
+
+	orphans, err := Find(client, packages)
+	if err != nil {
+		t.Fatalf("Find() error: %v", err)
+	}
+	want := []Orphan{
+		{Package: packages[1], Reason: Unlinked},
+		{Package: packages[2], Reason: Archived},
+		{Package: packages[3], Reason: Deleted},
+	}
+	if len(orphans) != len(want) {
+		t.Fatalf("orphans = %v, want %v", orphans, want)
+	}
+	for i := range want {
+		if orphans[i].Package.Name != want[i].Package.Name || orphans[i].Reason != want[i].Reason {
+			t.Errorf("orphan %d = %s (%s), want %s (%s)", i, orphans[i].Package.Name, orphans[i].Reason, want[i].Package.Name, want[i].Reason)
+		}
+	}
+	if client.calls != 3 {
+		t.Errorf("repository lookups = %d, want 3 with octo/live looked up once", client.calls)
+	}
+}
+
+type failingClient struct{}
+
+func (failingClient) GetRepository(owner, repo string) (*github.Repository, error) {
+	return nil, &github.APIError{StatusCode: 500}
+}
+
+func TestFind_Error(t *testing.T) {
+	_, err := Find(failingClient{}, []github.Package{pkg("api", "octo/api")})
+	var apiErr *github.APIError
+	if !errors.As(err, &apiErr) {
+		t.Errorf("Find() error = %v, want the API error", err)
+	}
+}
@@ -0,0 +1,72 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+// CheckGuardrails returns an error when deleting the selected versions of
+// packageName would break one of the configured guardrails
+func CheckGuardrails(g config.Guardrails, packageName string, versions []github.PackageVersion, selected map[int]struct{}) error {
+	count := 0
+	for _, v := range versions {
+		if _, ok := selected[v.ID]; ok {
+			count++
+		}
+	}
+	if count == 0 {
+		return nil
+	}
+
+	if g.MaxVersions > 0 && count > g.MaxVersions {
+		return fmt.Errorf("refusing to delete %d versions of %s, the limit is %d", count, packageName, g.MaxVersions)
+	}
+
+	if g.MaxPercent > 0 && count*100 > g.MaxPercent*len(versions) {
+		return fmt.Errorf("refusing to delete %d of %d versions of %s (%d%%), the limit is %d%%",
+			count, len(versions), packageName, count*100/len(versions), g.MaxPercent)
+	}
+
+	if g.KeepLastTagged {
+		tagged, kept := 0, 0
+		for _, v := range versions {
+			if len(v.Tags()) == 0 {
+				continue
+			}
+			tagged++
+			if _, ok := selected[v.ID]; !ok {
+				kept++
+			}
+		}
+		if tagged > 0 && kept == 0 {
+			return fmt.Errorf("refusing to delete every tagged version of %s, keep at least one", packageName)
+		}
+	}
+
+	return nil
+}
+
+// RequiresTypedConfirmation reports whether deleting count versions must be
+// confirmed by typing the package name
+func RequiresTypedConfirmation(g config.Guardrails, count int) bool {
+	return g.ConfirmThreshold > 0 && count > g.ConfirmThreshold
+}
+
+// CheckPackageGuardrails returns an error when packageName may not be
+// deleted as a whole: when it has more versions than max_versions allows, or
+// when blocked explains why one of its versions must be kept. The share and
+// last-tagged guardrails do not apply since removing everything is the
+// intent, and whole packages always require typing their name.
+func CheckPackageGuardrails(g config.Guardrails, packageName string, versions []github.PackageVersion, blocked func(github.PackageVersion) error) error {
+	if g.MaxVersions > 0 && len(versions) > g.MaxVersions {
+		return fmt.Errorf("refusing to delete %s with %d versions, the limit is %d", packageName, len(versions), g.MaxVersions)
+	}
+	for _, v := range versions {
+		if err := blocked(v); err != nil {
+			return fmt.Errorf("refusing to delete %s, version %s (%s) must be kept: %w", packageName, v.Name, v.TagsString(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+func testVersions() []github.PackageVersion {
+	versions := make([]github.PackageVersion, 10)
+	for i := range versions {
+		versions[i].ID = i + 1
+	}
+	versions[0].Metadata.Container.Tags = []string{"latest"}
+	versions[1].Metadata.Container.Tags = []string{"v1"}
+	return versions
+}
+
+func selectIDs(ids ...int) map[int]struct{} {
+	selected := make(map[int]struct{})
+	for _, id := range ids {
+		selected[id] = struct{}{}
+	}
+	return selected
+}
+
+func TestCheckGuardrails(t *testing.T) {
+	tests := []struct {
+		name        string
+		guardrails  config.Guardrails
+		selected    map[int]struct{}
+		wantContain string
+	}{
+		{
+			name:       "nothing selected",
+			guardrails: config.Guardrails{MaxVersions: 1},
+			selected:   selectIDs(),
+		},
+		{
+			name:        "over max versions",
+			guardrails:  config.Guardrails{MaxVersions: 2},
+			selected:    selectIDs(3, 4, 5),
+			wantContain: "limit is 2",
+		},
+		{
+			name:       "at max versions",
+			guardrails: config.Guardrails{MaxVersions: 3},
+			selected:   selectIDs(3, 4, 5),
+		},
+		{
+			name:        "over max percent",
+			guardrails:  config.Guardrails{MaxPercent: 50},
+			selected:    selectIDs(3, 4, 5, 6, 7, 8),
+			wantContain: "(60%)",
+		},
+		{
+			name:       "at max percent",
+			guardrails: config.Guardrails{MaxPercent: 50},
+			selected:   selectIDs(3, 4, 5, 6, 7),
+		},
+		{
+			name:        "last tagged version",
+			guardrails:  config.Guardrails{KeepLastTagged: true},
+			selected:    selectIDs(1, 2),
+			wantContain: "every tagged version",
+		},
+		{
+			name:       "one tagged version kept",
+			guardrails: config.Guardrails{KeepLastTagged: true},
+			selected:   selectIDs(1, 3, 4),
+		},
+		{
+			name:       "keep last tagged disabled",
+			guardrails: config.Guardrails{},
+			selected:   selectIDs(1, 2),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckGuardrails(tt.guardrails, "app", testVersions(), tt.selected)
+			if tt.wantContain == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantContain) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantContain)
+			}
+		})
+	}
+}
+
+func TestRequiresTypedConfirmation(t *testing.T) {
+	g := config.Guardrails{ConfirmThreshold: 10}
+	if RequiresTypedConfirmation(g, 10) {
+		t.Error("10 versions should not require typed confirmation with threshold 10")
+	}
+	if !RequiresTypedConfirmation(g, 11) {
+		t.Error("11 versions should require typed confirmation with threshold 10")
+	}
+	if RequiresTypedConfirmation(config.Guardrails{}, 1000) {
+		t.Error("a zero threshold disables typed confirmation")
+	}
+}
+
+func TestCheckPackageGuardrails(t *testing.T) {
+	versions := []github.PackageVersion{{ID: 1, Name: "sha256:a"}, {ID: 2, Name: "sha256:b"}}
+	allow := func(github.PackageVersion) error { return nil }
+
+	if err := CheckPackageGuardrails(config.Guardrails{MaxPercent: 50, KeepLastTagged: true}, "app", versions, allow); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := CheckPackageGuardrails(config.Guardrails{MaxVersions: 1}, "app", versions, allow); err == nil {
+		t.Error("expected max_versions to refuse the deletion")
+	}
+
+	blocked := func(v github.PackageVersion) error {
+		if v.ID == 2 {
+			return errors.New("in use by deploy.yaml:3")
+		}
+		return nil
+	}
+	err := CheckPackageGuardrails(config.Guardrails{}, "app", versions, blocked)
+	if err == nil || !strings.Contains(err.Error(), "sha256:b (<untagged>) must be kept: in use by deploy.yaml:3") {
+		t.Errorf("error = %v, want the blocked version named", err)
+	}
+}
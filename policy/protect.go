@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+// TagPattern matches tags with a glob such as `v*`, or with a regular
+// expression when written between slashes such as `/^release-\d+$/`
+type TagPattern struct {
+	raw string
+	re  *regexp.Regexp
+}
+
+// CompileTagPattern parses a single protected tag pattern
+func CompileTagPattern(pattern string) (TagPattern, error) {
+	re, err := config.ParseTagPattern(pattern)
+	if err != nil {
+		return TagPattern{}, err
+	}
+	return TagPattern{raw: pattern, re: re}, nil
+}
+
+// CompileTagPatterns parses a list of protected tag patterns
+func CompileTagPatterns(patterns []string) ([]TagPattern, error) {
+	compiled := make([]TagPattern, 0, len(patterns))
+	for _, p := range patterns {
+		tp, err := CompileTagPattern(p)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, tp)
+	}
+	return compiled, nil
+}
+
+// Match reports whether tag matches the pattern
+func (p TagPattern) Match(tag string) bool {
+	if p.re != nil {
+		return p.re.MatchString(tag)
+	}
+	ok, _ := path.Match(p.raw, tag)
+	return ok
+}
+
+// String returns the pattern as written in the config
+func (p TagPattern) String() string {
+	return p.raw
+}
+
+// CheckTags returns an error naming the tag and pattern when one of v's tags
+// is protected
+func CheckTags(patterns []TagPattern, v github.PackageVersion) error {
+	for _, tag := range v.Tags() {
+		for _, p := range patterns {
+			if p.Match(tag) {
+				return fmt.Errorf("tag %q is protected by pattern %q", tag, p)
+			}
+		}
+	}
+	return nil
+}
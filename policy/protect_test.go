@@ -0,0 +1,64 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/maful/hij/github"
+)
+
+func TestTagPattern_Match(t *testing.T) {
+	tests := []struct {
+		pattern string
+		tag     string
+		want    bool
+	}{
+		{pattern: "latest", tag: "latest", want: true},
+		{pattern: "latest", tag: "latest-rc", want: false},
+		{pattern: "v*", tag: "v1.2.3", want: true},
+		{pattern: "v*", tag: "dev", want: false},
+		{pattern: "prod-*", tag: "prod-eu", want: true},
+		{pattern: "/^release-\\d+$/", tag: "release-42", want: true},
+		{pattern: "/^release-\\d+$/", tag: "release-x", want: false},
+	}
+
+	for _, tt := range tests {
+		p, err := CompileTagPattern(tt.pattern)
+		if err != nil {
+			t.Fatalf("CompileTagPattern(%q) error: %v", tt.pattern, err)
+		}
+		if got := p.Match(tt.tag); got != tt.want {
+			t.Errorf("%q.Match(%q) = %v, want %v", tt.pattern, tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestCompileTagPatterns_Invalid(t *testing.T) {
+	for _, pattern := range []string{"[", "/(/"} {
+		if _, err := CompileTagPatterns([]string{"latest", pattern}); err == nil {
+			t.Errorf("CompileTagPatterns(%q) expected error, got nil", pattern)
+		}
+	}
+}
+
+func TestCheckTags(t *testing.T) {
+	patterns, err := CompileTagPatterns([]string{"latest", "v*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var protected, unprotected github.PackageVersion
+	protected.Metadata.Container.Tags = []string{"sha-abc", "v2.0.0"}
+	unprotected.Metadata.Container.Tags = []string{"pr-12"}
+
+	err = CheckTags(patterns, protected)
+	if err == nil || !strings.Contains(err.Error(), `tag "v2.0.0" is protected by pattern "v*"`) {
+		t.Errorf("CheckTags(protected) = %v", err)
+	}
+	if err := CheckTags(patterns, unprotected); err != nil {
+		t.Errorf("CheckTags(unprotected) = %v, want nil", err)
+	}
+	if err := CheckTags(patterns, github.PackageVersion{}); err != nil {
+		t.Errorf("CheckTags(untagged) = %v, want nil", err)
+	}
+}
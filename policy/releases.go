@@ -0,0 +1,162 @@
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+// ReleaseClient is the part of the GitHub API used to find releases and
+// deployments
+type ReleaseClient interface {
+	ListReleases(owner, repo string) ([]github.Release, error)
+	ListDeployments(owner, repo string) ([]github.Deployment, error)
+	ListDeploymentStatuses(owner, repo string, deploymentID int) ([]github.DeploymentStatus, error)
+}
+
+// Releases knows which images back the published releases and active
+// deployments of a repository. A nil Releases protects nothing.
+type Releases struct {
+	backed []backing
+}
+
+// backing is a release or deployment and the values naming its image
+type backing struct {
+	what   string
+	values []string
+}
+
+// LoadReleases looks up the releases and deployments of repo, given as
+// owner/name, as enabled by p
+func LoadReleases(client ReleaseClient, repo string, p config.Protect) (*Releases, error) {
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok {
+		return nil, fmt.Errorf("invalid repository %q, expected owner/name", repo)
+	}
+
+	r := &Releases{}
+	if p.Releases {
+		releases, err := client.ListReleases(owner, name)
+		if err != nil {
+			return nil, fmt.Errorf("releases of %s: %w", repo, err)
+		}
+		for _, rel := range releases {
+			if !rel.Draft {
+				r.backed = append(r.backed, backing{what: "release " + rel.TagName, values: []string{rel.TagName}})
+			}
+		}
+	}
+	if p.Deployments {
+		if err := r.loadDeployments(client, owner, name); err != nil {
+			return nil, fmt.Errorf("deployments of %s: %w", repo, err)
+		}
+	}
+	return r, nil
+}
+
+// loadDeployments records the deployment currently serving each
+// environment: the newest successful one, along with newer deployments
+// still rolling out. An inactive deployment means nothing older is live.
+func (r *Releases) loadDeployments(client ReleaseClient, owner, repo string) error {
+	deployments, err := client.ListDeployments(owner, repo)
+	if err != nil {
+		return err
+	}
+	settled := make(map[string]bool)
+	for _, d := range deployments {
+		if settled[d.Environment] {
+			continue
+		}
+		statuses, err := client.ListDeploymentStatuses(owner, repo, d.ID)
+		if err != nil {
+			return err
+		}
+		state := ""
+		if len(statuses) > 0 {
+			state = statuses[0].State
+		}
+		switch state {
+		case "success":
+			settled[d.Environment] = true
+		case "queued", "pending", "in_progress":
+		case "inactive":
+			settled[d.Environment] = true
+			continue
+		default: // failure, error or no status yet
+			continue
+		}
+		values := append([]string{d.Ref, d.Sha}, payloadStrings(d.Payload)...)
+		r.backed = append(r.backed, backing{what: "deployment to " + d.Environment, values: values})
+	}
+	return nil
+}
+
+// payloadStrings returns every string in a deployment payload, which is an
+// object or, for older deployments, a JSON string
+func payloadStrings(payload json.RawMessage) []string {
+	var v any
+	if len(payload) == 0 || json.Unmarshal(payload, &v) != nil {
+		return nil
+	}
+	if s, ok := v.(string); ok && json.Unmarshal([]byte(s), &v) != nil {
+		return []string{s}
+	}
+
+	var out []string
+	var walk func(v any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case string:
+			out = append(out, v)
+		case []any:
+			for _, e := range v {
+				walk(e)
+			}
+		case map[string]any:
+			for _, e := range v {
+				walk(e)
+			}
+		}
+	}
+	walk(v)
+	return out
+}
+
+// Check returns an error naming the release or deployment backed by v. A
+// value names v when it is one of its tags or its digest, an image
+// reference ending in either, or a commit whose short SHA is in a sha-
+// tag such as sha-1a2b3c4.
+func (r *Releases) Check(v github.PackageVersion) error {
+	if r == nil {
+		return nil
+	}
+	for _, b := range r.backed {
+		for _, value := range b.values {
+			if names(value, v) {
+				return fmt.Errorf("backs %s", b.what)
+			}
+		}
+	}
+	return nil
+}
+
+func names(value string, v github.PackageVersion) bool {
+	if value == "" {
+		return false
+	}
+	if value == v.Name || strings.HasSuffix(value, "@"+v.Name) {
+		return true
+	}
+	for _, tag := range v.Tags() {
+		if value == tag || strings.HasSuffix(value, ":"+tag) {
+			return true
+		}
+		if short, ok := strings.CutPrefix(tag, "sha-"); ok && len(short) >= 7 && strings.HasPrefix(value, short) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package policy
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+type fakeReleaseClient struct {
+	releases    []github.Release
+	deployments []github.Deployment
+	states      map[int]string // deployment ID to its latest state
+	calls       []string
+}
+
+func (c *fakeReleaseClient) ListReleases(owner, repo string) ([]github.Release, error) {
+	c.calls = append(c.calls, "releases")
+	return c.releases, nil
+}
+
+func (c *fakeReleaseClient) ListDeployments(owner, repo string) ([]github.Deployment, error) {
+	c.calls = append(c.calls, "deployments")
+	return c.deployments, nil
+}
+
+func (c *fakeReleaseClient) ListDeploymentStatuses(owner, repo string, id int) ([]github.DeploymentStatus, error) {
+	c.calls = append(c.calls, "statuses")
+	if state, ok := c.states[id]; ok {
+		return []github.DeploymentStatus{{State: state}}, nil
+	}
+	return nil, nil
+}
+
+func TestLoadReleases(t *testing.T) {
+	client := &fakeReleaseClient{
+		releases: []github.Release{{TagName: "v2.0.0"}, {TagName: "v2.1.0", Draft: true}},
+		deployments: []github.Deployment{
+			// Newest first
+			{ID: 6, Environment: "production", Ref: "v1.9.0"},
+			{ID: 5, Environment: "production", Payload: json.RawMessage(`{"image":"ghcr.io/octo/app@sha256:prod"}`)},
+			{ID: 4, Environment: "production", Ref: "v1.7.0"},
+			{ID: 3, Environment: "staging", Sha: "1a2b3c4d5e6f", Payload: json.RawMessage(`"{\"tags\":[\"rc-3\"]}"`)},
+			{ID: 2, Environment: "preview", Ref: "pr-12"},
+			{ID: 1, Environment: "staging", Ref: "v1.0.0"},
+		},
+		states: map[int]string{6: "failure", 5: "success", 4: "success", 3: "in_progress", 2: "inactive", 1: "success"},
+	}
+
+	r, err := LoadReleases(client, "octo/app", config.Protect{Releases: true, Deployments: true})
+	if err != nil {
+		t.Fatalf("LoadReleases() error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		tags []string
+		want string
+	}{
+		{name: "sha256:rel", tags: []string{"v2.0.0"}, want: "backs release v2.0.0"},
+		{name: "sha256:draft", tags: []string{"v2.1.0"}},
+		{name: "sha256:failed", tags: []string{"v1.9.0"}},
+		{name: "sha256:prod", want: "backs deployment to production"},
+		{name: "sha256:old", tags: []string{"v1.7.0"}},
+		{name: "sha256:rc", tags: []string{"rc-3"}, want: "backs deployment to staging"},
+		{name: "sha256:commit", tags: []string{"sha-1a2b3c4"}, want: "backs deployment to staging"},
+		{name: "sha256:stable", tags: []string{"v1.0.0"}, want: "backs deployment to staging"},
+		{name: "sha256:preview", tags: []string{"pr-12"}},
+	}
+	for _, tt := range tests {
+		v := github.PackageVersion{Name: tt.name}
+		v.Metadata.Container.Tags = tt.tags
+		err := r.Check(v)
+		if tt.want == "" && err != nil {
+			t.Errorf("%s: unexpected protection %v", tt.name, err)
+		}
+		if tt.want != "" && (err == nil || err.Error() != tt.want) {
+			t.Errorf("%s: Check() = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+
+	// The successful production deployment settles production, so the
+	// older one is never looked up
+	if got := strings.Join(client.calls, ","); got != "releases,deployments,statuses,statuses,statuses,statuses,statuses" {
+		t.Errorf("calls = %s", got)
+	}
+}
+
+func TestLoadReleases_Disabled(t *testing.T) {
+	client := &fakeReleaseClient{}
+	r, err := LoadReleases(client, "octo/app", config.Protect{})
+	if err != nil {
+		t.Fatalf("LoadReleases() error: %v", err)
+	}
+	if len(client.calls) != 0 {
+		t.Errorf("calls = %v, want none", client.calls)
+	}
+	if err := r.Check(github.PackageVersion{Name: "sha256:a"}); err != nil {
+		t.Errorf("Check() = %v, want nil", err)
+	}
+
+	var none *Releases
+	if err := none.Check(github.PackageVersion{Name: "sha256:a"}); err != nil {
+		t.Errorf("nil Releases Check() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,194 @@
+package refs
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/maful/hij/github"
+)
+
+// Reference is a ghcr.io image reference found in a local file
+type Reference struct {
+	File    string
+	Line    int
+	Owner   string
+	Package string
+	Tag     string // empty when the reference only pins a digest or names no tag
+	Digest  string
+}
+
+// String formats the reference location as file:line
+func (r Reference) String() string {
+	return fmt.Sprintf("%s:%d", r.File, r.Line)
+}
+
+var (
+	imageRegex = regexp.MustCompile(`ghcr\.io/([A-Za-z0-9][A-Za-z0-9-]*)/([a-z0-9]+(?:[._-][a-z0-9]+)*(?:/[a-z0-9]+(?:[._-][a-z0-9]+)*)*)(?::([A-Za-z0-9_][A-Za-z0-9_.-]{0,127}))?(?:@(sha256:[a-f0-9]{64}))?`)
+	// Helm values usually split the image into repository and tag keys
+	repositoryKeyRegex = regexp.MustCompile(`^\s*-?\s*(?:repository|image):\s*["']?ghcr\.io/`)
+	tagKeyRegex        = regexp.MustCompile(`^\s*tag:\s*["']?([A-Za-z0-9_][A-Za-z0-9_.-]{0,127})["']?\s*(?:#.*)?$`)
+	digestKeyRegex     = regexp.MustCompile(`^\s*digest:\s*["']?(sha256:[a-f0-9]{64})["']?`)
+)
+
+// skipDirs are never descended into while scanning
+var skipDirs = map[string]bool{
+	".git":         true,
+	"node_modules": true,
+	"vendor":       true,
+}
+
+// helmLookahead is how many lines after a repository key are searched for
+// its tag or digest
+const helmLookahead = 5
+
+// Scan walks paths and returns every ghcr.io reference found in Kubernetes
+// manifests, Helm values, docker-compose files, Dockerfiles and GitHub
+// workflow files
+func Scan(paths []string) ([]Reference, error) {
+	var found []Reference
+	for _, root := range paths {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && skipDirs[d.Name()] {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !isCandidate(d.Name()) {
+				return nil
+			}
+			refs, err := scanFile(path)
+			if err != nil {
+				return err
+			}
+			found = append(found, refs...)
+			return nil
+		})
+		if err != nil {
+			return found, err
+		}
+	}
+	return found, nil
+}
+
+// isCandidate reports whether a file name looks like a deployment manifest
+func isCandidate(name string) bool {
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".yaml"), strings.HasSuffix(lower, ".yml"):
+		return true
+	case strings.HasPrefix(lower, "dockerfile"), strings.HasSuffix(lower, ".dockerfile"):
+		return true
+	case strings.HasPrefix(lower, "containerfile"):
+		return true
+	}
+	return false
+}
+
+func scanFile(path string) ([]Reference, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	var refs []Reference
+	for i, line := range lines {
+		for _, m := range imageRegex.FindAllStringSubmatch(line, -1) {
+			ref := Reference{
+				File:    path,
+				Line:    i + 1,
+				Owner:   strings.ToLower(m[1]),
+				Package: m[2],
+				Tag:     m[3],
+				Digest:  m[4],
+			}
+			if ref.Tag == "" && ref.Digest == "" && repositoryKeyRegex.MatchString(line) {
+				ref.Tag, ref.Digest = siblingTag(lines[i+1:])
+			}
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// siblingTag looks for the tag and digest keys that accompany a Helm style
+// repository key
+func siblingTag(following []string) (string, string) {
+	var tag, digest string
+	for i, line := range following {
+		if i >= helmLookahead || repositoryKeyRegex.MatchString(line) {
+			break
+		}
+		if m := tagKeyRegex.FindStringSubmatch(line); m != nil {
+			tag = m[1]
+		}
+		if m := digestKeyRegex.FindStringSubmatch(line); m != nil {
+			digest = m[1]
+		}
+	}
+	return tag, digest
+}
+
+// Index groups references by the package they point at
+type Index map[string][]Reference
+
+// NewIndex builds an index from scanned references
+func NewIndex(refs []Reference) Index {
+	idx := make(Index)
+	for _, r := range refs {
+		key := indexKey(r.Owner, r.Package)
+		idx[key] = append(idx[key], r)
+	}
+	return idx
+}
+
+func indexKey(owner, packageName string) string {
+	return strings.ToLower(owner + "/" + packageName)
+}
+
+// Lookup returns the references that resolve to version v of a package. A
+// reference naming neither tag nor digest resolves to `latest`.
+func (idx Index) Lookup(owner, packageName string, v github.PackageVersion) []Reference {
+	var matched []Reference
+	for _, r := range idx[indexKey(owner, packageName)] {
+		if r.matches(v) {
+			matched = append(matched, r)
+		}
+	}
+	return matched
+}
+
+func (r Reference) matches(v github.PackageVersion) bool {
+	if r.Digest != "" {
+		return r.Digest == v.Name
+	}
+	tag := r.Tag
+	if tag == "" {
+		tag = "latest"
+	}
+	for _, t := range v.Tags() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
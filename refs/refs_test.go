@@ -0,0 +1,113 @@
+package refs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maful/hij/github"
+)
+
+const testDigest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func version(name string, tags ...string) github.PackageVersion {
+	v := github.PackageVersion{Name: name}
+	v.Metadata.Container.Tags = tags
+	return v
+}
+
+func TestScan(t *testing.T) {
+	root := writeFiles(t, map[string]string{
+		"k8s/deployment.yaml":          "spec:\n  containers:\n    - image: ghcr.io/Octo/app:v1.2.0\n",
+		"charts/values.yaml":           "image:\n  repository: ghcr.io/octo/api\n  pullPolicy: Always\n  tag: \"2024.01\"\n",
+		"docker-compose.yml":           "services:\n  web:\n    image: ghcr.io/octo/web@" + testDigest + "\n",
+		"Dockerfile":                   "FROM ghcr.io/octo/base:stable AS build\n",
+		".github/workflows/deploy.yml": "jobs:\n  deploy:\n    container: ghcr.io/octo/tools\n",
+		"node_modules/dep/ci.yml":      "image: ghcr.io/octo/ignored:1\n",
+		"README.md":                    "docker pull ghcr.io/octo/ignored:2\n",
+	})
+
+	found, err := Scan([]string{root})
+	if err != nil {
+		t.Fatalf("Scan() error: %v", err)
+	}
+
+	got := map[string]Reference{}
+	for _, r := range found {
+		got[r.Package] = r
+	}
+	if _, ok := got["ignored"]; ok {
+		t.Error("references in skipped directories or non-manifest files should be ignored")
+	}
+
+	tests := []struct {
+		pkg    string
+		tag    string
+		digest string
+		line   int
+	}{
+		{pkg: "app", tag: "v1.2.0", line: 3},
+		{pkg: "api", tag: "2024.01", line: 2},
+		{pkg: "web", digest: testDigest, line: 3},
+		{pkg: "base", tag: "stable", line: 1},
+		{pkg: "tools", line: 3},
+	}
+	for _, tt := range tests {
+		r, ok := got[tt.pkg]
+		if !ok {
+			t.Errorf("no reference found for %s", tt.pkg)
+			continue
+		}
+		if r.Owner != "octo" || r.Tag != tt.tag || r.Digest != tt.digest || r.Line != tt.line {
+			t.Errorf("%s: got %+v, want tag %q digest %q line %d", tt.pkg, r, tt.tag, tt.digest, tt.line)
+		}
+	}
+	if !strings.HasSuffix(got["app"].String(), "deployment.yaml:3") {
+		t.Errorf("String() = %q", got["app"].String())
+	}
+}
+
+func TestIndex_Lookup(t *testing.T) {
+	idx := NewIndex([]Reference{
+		{Owner: "octo", Package: "app", Tag: "v1"},
+		{Owner: "octo", Package: "app", Digest: testDigest},
+		{Owner: "octo", Package: "app"},
+		{Owner: "other", Package: "app", Tag: "v2"},
+	})
+
+	tests := []struct {
+		name    string
+		version github.PackageVersion
+		want    int
+	}{
+		{name: "by tag", version: version("sha256:aaa", "v1"), want: 1},
+		{name: "by digest", version: version(testDigest), want: 1},
+		{name: "bare reference means latest", version: version("sha256:bbb", "latest"), want: 1},
+		{name: "other owner's tag", version: version("sha256:ccc", "v2"), want: 0},
+		{name: "unreferenced", version: version("sha256:ddd", "v3"), want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := idx.Lookup("Octo", "app", tt.version); len(got) != tt.want {
+				t.Errorf("Lookup() = %v, want %d references", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const refNameAnnotation = "org.opencontainers.image.ref.name"
+
+var digestRegex = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// descriptor is an OCI content descriptor
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	URLs        []string          `json:"urls,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Platform    json.RawMessage   `json:"platform,omitempty"`
+}
+
+// manifest covers the fields of image manifests and indexes needed to walk
+// an image graph
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    *descriptor  `json:"config,omitempty"`
+	Layers    []descriptor `json:"layers,omitempty"`
+	Manifests []descriptor `json:"manifests,omitempty"`
+	Subject   *descriptor  `json:"subject,omitempty"`
+}
+
+// index is the top-level index.json of an OCI image layout
+type index struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Manifests     []descriptor `json:"manifests"`
+}
+
+// Archiver copies images out of the registry into OCI image layouts so they
+// can be re-pushed with standard tools (skopeo, crane, oras) after deletion
+type Archiver struct {
+	client  *Client
+	dir     string
+	tarball bool
+}
+
+// NewArchiver creates an archiver writing below dir. Each repository gets its
+// own layout directory, or one tarball per image when tarball is set.
+func NewArchiver(client *Client, dir string, tarball bool) *Archiver {
+	return &Archiver{client: client, dir: dir, tarball: tarball}
+}
+
+// Archive copies the image with the given manifest digest, including every
+// child of an image index, verifying each digest along the way. Tags are
+// recorded as reference names in the layout. It returns the path written.
+func (a *Archiver) Archive(repo, digest string, tags []string) (string, error) {
+	if !digestRegex.MatchString(digest) {
+		return "", fmt.Errorf("cannot archive %q: not a sha256 digest", digest)
+	}
+
+	if !a.tarball {
+		root := filepath.Join(a.dir, filepath.FromSlash(repo))
+		if err := a.copyImage(root, repo, digest, tags); err != nil {
+			return "", err
+		}
+		return root, nil
+	}
+
+	tmp, err := os.MkdirTemp("", "hij-archive-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := a.copyImage(tmp, repo, digest, tags); err != nil {
+		return "", err
+	}
+
+	name := strings.ReplaceAll(repo, "/", "_") + "_" + strings.TrimPrefix(digest, "sha256:")[:12] + ".tar"
+	path := filepath.Join(a.dir, name)
+	if err := writeTar(tmp, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func (a *Archiver) copyImage(root, repo, digest string, tags []string) error {
+	l := layout{root: root}
+	if err := l.init(); err != nil {
+		return err
+	}
+	desc, err := a.copyManifest(l, repo, digest)
+	if err != nil {
+		return err
+	}
+	return l.addToIndex(desc, tags)
+}
+
+// copyManifest stores the manifest and everything it references, returning
+// its descriptor
+func (a *Archiver) copyManifest(l layout, repo, digest string) (descriptor, error) {
+	body, mediaType, err := a.client.GetManifest(repo, digest)
+	if err != nil {
+		return descriptor{}, err
+	}
+	if got := digestOf(body); got != digest {
+		return descriptor{}, fmt.Errorf("manifest digest mismatch: got %s, want %s", got, digest)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return descriptor{}, fmt.Errorf("parsing manifest %s: %w", digest, err)
+	}
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	if m.MediaType != "" {
+		mediaType = m.MediaType
+	}
+
+	if mediaType == MediaTypeOCIIndex || mediaType == MediaTypeDockerList || len(m.Manifests) > 0 {
+		for _, child := range m.Manifests {
+			if _, err := a.copyManifest(l, repo, child.Digest); err != nil {
+				return descriptor{}, err
+			}
+		}
+	} else {
+		var blobs []descriptor
+		if m.Config != nil {
+			blobs = append(blobs, *m.Config)
+		}
+		blobs = append(blobs, m.Layers...)
+		for _, b := range blobs {
+			if len(b.URLs) > 0 {
+				continue // non-distributable layer, not served by the registry
+			}
+			if err := a.copyBlob(l, repo, b); err != nil {
+				return descriptor{}, err
+			}
+		}
+	}
+
+	if err := l.writeBlob(digest, int64(len(body)), bytes.NewReader(body)); err != nil {
+		return descriptor{}, err
+	}
+	return descriptor{MediaType: mediaType, Digest: digest, Size: int64(len(body))}, nil
+}
+
+func (a *Archiver) copyBlob(l layout, repo string, b descriptor) error {
+	if l.hasBlob(b.Digest, b.Size) {
+		return nil
+	}
+	rc, err := a.client.GetBlob(repo, b.Digest)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return l.writeBlob(b.Digest, b.Size, rc)
+}
+
+// layout writes an OCI image layout directory
+type layout struct {
+	root string
+}
+
+func (l layout) init() error {
+	if err := os.MkdirAll(filepath.Join(l.root, "blobs", "sha256"), 0o755); err != nil {
+		return err
+	}
+	marker := filepath.Join(l.root, "oci-layout")
+	if _, err := os.Stat(marker); errors.Is(err, os.ErrNotExist) {
+		if err := os.WriteFile(marker, []byte(`{"imageLayoutVersion":"1.0.0"}`), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l layout) blobPath(digest string) (string, error) {
+	if !digestRegex.MatchString(digest) {
+		return "", fmt.Errorf("unsupported digest %q", digest)
+	}
+	return filepath.Join(l.root, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:")), nil
+}
+
+func (l layout) hasBlob(digest string, size int64) bool {
+	path, err := l.blobPath(digest)
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.Size() == size
+}
+
+// writeBlob streams r into the layout, verifying its size and digest before
+// the blob becomes visible
+func (l layout) writeBlob(digest string, size int64, r io.Reader) error {
+	path, err := l.blobPath(digest)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if n != size {
+		return fmt.Errorf("blob %s size mismatch: got %d bytes, want %d", digest, n, size)
+	}
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != digest {
+		return fmt.Errorf("blob digest mismatch: got %s, want %s", got, digest)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// addToIndex lists desc in index.json once per tag, or once without a
+// reference name for untagged images
+func (l layout) addToIndex(desc descriptor, tags []string) error {
+	path := filepath.Join(l.root, "index.json")
+	idx := index{SchemaVersion: 2, MediaType: MediaTypeOCIIndex}
+	if data, err := os.ReadFile(path); err == nil {
+		if err := json.Unmarshal(data, &idx); err != nil {
+			return fmt.Errorf("parsing %s: %w", path, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	present := func(ref string) bool {
+		for _, d := range idx.Manifests {
+			if d.Digest == desc.Digest && d.Annotations[refNameAnnotation] == ref {
+				return true
+			}
+		}
+		return false
+	}
+
+	refs := tags
+	if len(refs) == 0 {
+		refs = []string{""}
+	}
+	for _, ref := range refs {
+		if present(ref) {
+			continue
+		}
+		d := desc
+		if ref != "" {
+			d.Annotations = map[string]string{refNameAnnotation: ref}
+		}
+		idx.Manifests = append(idx.Manifests, d)
+	}
+
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// writeTar packs the directory src into a tarball at dst
+func writeTar(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+
+	tw := tar.NewWriter(f)
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil || path == src {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		hdr, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		hdr.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+	if closeErr := tw.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst)
+	}
+	return err
+}
@@ -0,0 +1,200 @@
+package registry
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeRegistry serves a multi-platform image: an index with one child
+// manifest that references a config and a layer blob
+type fakeRegistry struct {
+	manifests map[string]string // digest -> body
+	blobs     map[string]string // digest -> body
+	indexDig  string
+}
+
+func newFakeRegistry() *fakeRegistry {
+	r := &fakeRegistry{manifests: map[string]string{}, blobs: map[string]string{}}
+
+	config := `{"architecture":"amd64"}`
+	layer := "layer-bytes"
+	configDig, layerDig := digestOf([]byte(config)), digestOf([]byte(layer))
+	r.blobs[configDig] = config
+	r.blobs[layerDig] = layer
+
+	image := fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"config":{"mediaType":"application/vnd.oci.image.config.v1+json","digest":%q,"size":%d},"layers":[{"mediaType":"application/vnd.oci.image.layer.v1.tar+gzip","digest":%q,"size":%d}]}`,
+		MediaTypeOCIManifest, configDig, len(config), layerDig, len(layer))
+	imageDig := digestOf([]byte(image))
+	r.manifests[imageDig] = image
+
+	idx := fmt.Sprintf(`{"schemaVersion":2,"mediaType":%q,"manifests":[{"mediaType":%q,"digest":%q,"size":%d}]}`,
+		MediaTypeOCIIndex, MediaTypeOCIManifest, imageDig, len(image))
+	r.indexDig = digestOf([]byte(idx))
+	r.manifests[r.indexDig] = idx
+	return r
+}
+
+func (r *fakeRegistry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	const prefix = "/v2/octo/app/"
+	switch {
+	case strings.HasPrefix(req.URL.Path, prefix+"manifests/"):
+		body, ok := r.manifests[strings.TrimPrefix(req.URL.Path, prefix+"manifests/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var m manifest
+		json.Unmarshal([]byte(body), &m)
+		w.Header().Set("Content-Type", m.MediaType)
+		w.Write([]byte(body))
+	case strings.HasPrefix(req.URL.Path, prefix+"blobs/"):
+		body, ok := r.blobs[strings.TrimPrefix(req.URL.Path, prefix+"blobs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(body))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestArchiver_ArchiveLayout(t *testing.T) {
+	reg := newFakeRegistry()
+	server := httptest.NewServer(reg)
+	defer server.Close()
+
+	client := NewClient("octo", "token")
+	client.baseURL = server.URL
+	dir := t.TempDir()
+
+	path, err := NewArchiver(client, dir, false).Archive("octo/app", reg.indexDig, []string{"v1", "latest"})
+	if err != nil {
+		t.Fatalf("Archive() error: %v", err)
+	}
+	if path != filepath.Join(dir, "octo", "app") {
+		t.Errorf("path = %q", path)
+	}
+
+	// Every manifest and blob must be present under its digest
+	for dig := range reg.manifests {
+		assertBlob(t, path, dig)
+	}
+	for dig := range reg.blobs {
+		assertBlob(t, path, dig)
+	}
+
+	data, err := os.ReadFile(filepath.Join(path, "index.json"))
+	if err != nil {
+		t.Fatalf("reading index.json: %v", err)
+	}
+	var idx index
+	if err := json.Unmarshal(data, &idx); err != nil {
+		t.Fatalf("parsing index.json: %v", err)
+	}
+	if len(idx.Manifests) != 2 {
+		t.Fatalf("index entries = %d, want one per tag (2)", len(idx.Manifests))
+	}
+	if idx.Manifests[0].Digest != reg.indexDig || idx.Manifests[0].MediaType != MediaTypeOCIIndex {
+		t.Errorf("index entry = %+v, want the image index", idx.Manifests[0])
+	}
+	if ref := idx.Manifests[1].Annotations[refNameAnnotation]; ref != "latest" {
+		t.Errorf("ref name = %q, want %q", ref, "latest")
+	}
+
+	// Archiving again must not duplicate index entries
+	if _, err := NewArchiver(client, dir, false).Archive("octo/app", reg.indexDig, []string{"v1", "latest"}); err != nil {
+		t.Fatalf("second Archive() error: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(path, "index.json"))
+	json.Unmarshal(data, &idx)
+	if len(idx.Manifests) != 2 {
+		t.Errorf("index entries after re-archive = %d, want 2", len(idx.Manifests))
+	}
+}
+
+func TestArchiver_ArchiveTarball(t *testing.T) {
+	reg := newFakeRegistry()
+	server := httptest.NewServer(reg)
+	defer server.Close()
+
+	client := NewClient("octo", "token")
+	client.baseURL = server.URL
+
+	path, err := NewArchiver(client, t.TempDir(), true).Archive("octo/app", reg.indexDig, nil)
+	if err != nil {
+		t.Fatalf("Archive() error: %v", err)
+	}
+	if !strings.HasSuffix(path, ".tar") {
+		t.Fatalf("path = %q, want a .tar file", path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	names := map[string]bool{}
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names[hdr.Name] = true
+	}
+	for _, want := range []string{"oci-layout", "index.json", "blobs/sha256/" + strings.TrimPrefix(reg.indexDig, "sha256:")} {
+		if !names[want] {
+			t.Errorf("tarball is missing %s", want)
+		}
+	}
+}
+
+func TestArchiver_DigestMismatch(t *testing.T) {
+	reg := newFakeRegistry()
+	for dig := range reg.blobs {
+		reg.blobs[dig] = strings.ToUpper(reg.blobs[dig]) // same size, wrong content
+	}
+	server := httptest.NewServer(reg)
+	defer server.Close()
+
+	client := NewClient("octo", "token")
+	client.baseURL = server.URL
+
+	_, err := NewArchiver(client, t.TempDir(), false).Archive("octo/app", reg.indexDig, nil)
+	if err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Errorf("error = %v, want digest mismatch", err)
+	}
+}
+
+func TestArchiver_RejectsNonDigest(t *testing.T) {
+	_, err := NewArchiver(NewClient("octo", "token"), t.TempDir(), false).Archive("octo/app", "latest", nil)
+	if err == nil {
+		t.Error("expected error for non-digest reference, got nil")
+	}
+}
+
+func assertBlob(t *testing.T, root, digest string) {
+	t.Helper()
+	path := filepath.Join(root, "blobs", "sha256", strings.TrimPrefix(digest, "sha256:"))
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("missing blob %s: %v", digest, err)
+		return
+	}
+	if got := digestOf(data); got != digest {
+		t.Errorf("blob %s has digest %s", digest, got)
+	}
+}
@@ -0,0 +1,210 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultBaseURL = "https://ghcr.io"
+
+// Media types accepted when fetching manifests
+const (
+	MediaTypeOCIIndex        = "application/vnd.oci.image.index.v1+json"
+	MediaTypeOCIManifest     = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerList      = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeDockerManifest  = "application/vnd.docker.distribution.manifest.v2+json"
+	manifestAcceptHeaderList = MediaTypeOCIIndex + ", " + MediaTypeOCIManifest + ", " + MediaTypeDockerList + ", " + MediaTypeDockerManifest
+)
+
+// Client reads images from the GitHub Container Registry
+type Client struct {
+	username   string
+	password   string
+	httpClient *http.Client
+	baseURL    string
+
+	mu     sync.Mutex
+	tokens map[string]string // bearer token per repository
+}
+
+// NewClient creates a registry client authenticating as username with a
+// GitHub token
+func NewClient(username, token string) *Client {
+	return &Client{
+		username: username,
+		password: token,
+		httpClient: &http.Client{
+			Timeout: 5 * time.Minute,
+		},
+		baseURL: defaultBaseURL,
+		tokens:  make(map[string]string),
+	}
+}
+
+// Repository returns the registry repository name of a package
+func Repository(owner, packageName string) string {
+	return strings.ToLower(owner + "/" + packageName)
+}
+
+// GetManifest fetches the manifest for reference (a tag or digest) and returns
+// its raw bytes and media type
+func (c *Client) GetManifest(repo, reference string) ([]byte, string, error) {
+	resp, err := c.get(repo, fmt.Sprintf("/v2/%s/manifests/%s", repo, reference), manifestAcceptHeaderList)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// GetBlob opens the blob with the given digest. The caller must close it.
+func (c *Client) GetBlob(repo, digest string) (io.ReadCloser, error) {
+	resp, err := c.get(repo, fmt.Sprintf("/v2/%s/blobs/%s", repo, digest), "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// get performs an authenticated GET, answering a bearer challenge once
+func (c *Client) get(repo, path, accept string) (*http.Response, error) {
+	resp, err := c.do(repo, path, accept)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		if err := c.authenticate(repo, challenge); err != nil {
+			return nil, err
+		}
+		if resp, err = c.do(repo, path, accept); err != nil {
+			return nil, err
+		}
+	}
+
+	if resp.StatusCode >= 400 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("registry request %s failed (status %d)", path, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+func (c *Client) do(repo, path, accept string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", c.baseURL+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	c.mu.Lock()
+	token := c.tokens[repo]
+	c.mu.Unlock()
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	// #nosec G704 -- The baseURL is configured within the client and path is constructed from registry methods.
+	return c.httpClient.Do(req)
+}
+
+// authenticate exchanges the GitHub credentials for a registry bearer token
+// using the realm advertised in a WWW-Authenticate challenge
+func (c *Client) authenticate(repo, challenge string) error {
+	params := parseChallenge(challenge)
+	realm := params["realm"]
+	if realm == "" {
+		return fmt.Errorf("registry denied access to %s", repo)
+	}
+
+	q := url.Values{}
+	if service := params["service"]; service != "" {
+		q.Set("service", service)
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", repo)
+	}
+	q.Set("scope", scope)
+
+	req, err := http.NewRequest("GET", realm+"?"+q.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(c.username, c.password)
+
+	// #nosec G704 -- The realm comes from the registry's own auth challenge.
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("registry token request for %s failed (status %d)", repo, resp.StatusCode)
+	}
+
+	var tr struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tr); err != nil {
+		return err
+	}
+	token := tr.Token
+	if token == "" {
+		token = tr.AccessToken
+	}
+	if token == "" {
+		return fmt.Errorf("registry returned an empty token for %s", repo)
+	}
+
+	c.mu.Lock()
+	c.tokens[repo] = token
+	c.mu.Unlock()
+	return nil
+}
+
+// parseChallenge parses the parameters of a `Bearer k="v",...` challenge
+func parseChallenge(header string) map[string]string {
+	params := make(map[string]string)
+	_, rest, ok := strings.Cut(header, " ")
+	if !ok {
+		return params
+	}
+	for rest != "" {
+		key, after, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+
+		var value string
+		if strings.HasPrefix(after, `"`) {
+			end := strings.Index(after[1:], `"`)
+			if end < 0 {
+				value, rest = after[1:], ""
+			} else {
+				value, rest = after[1:end+1], after[end+2:]
+			}
+		} else {
+			value, rest, _ = strings.Cut(after, ",")
+		}
+		params[key] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return params
+}
@@ -0,0 +1,69 @@
+package registry
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseChallenge(t *testing.T) {
+	params := parseChallenge(`Bearer realm="https://ghcr.io/token",service="ghcr.io",scope="repository:octo/app:pull"`)
+
+	want := map[string]string{
+		"realm":   "https://ghcr.io/token",
+		"service": "ghcr.io",
+		"scope":   "repository:octo/app:pull",
+	}
+	for k, v := range want {
+		if params[k] != v {
+			t.Errorf("params[%q] = %q, want %q", k, params[k], v)
+		}
+	}
+}
+
+func TestRepository(t *testing.T) {
+	if got := Repository("Octo", "My-App"); got != "octo/my-app" {
+		t.Errorf("Repository() = %q, want %q", got, "octo/my-app")
+	}
+}
+
+func TestClient_GetBlob_TokenExchange(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			user, pass, ok := r.BasicAuth()
+			if !ok || user != "octo" || pass != "ghp_test" {
+				t.Errorf("basic auth = %q/%q, want octo/ghp_test", user, pass)
+			}
+			if scope := r.URL.Query().Get("scope"); scope != "repository:octo/app:pull" {
+				t.Errorf("scope = %q", scope)
+			}
+			w.Write([]byte(`{"token":"registry-token"}`))
+		case "/v2/octo/app/blobs/sha256:abc":
+			if r.Header.Get("Authorization") != "Bearer registry-token" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+server.URL+`/token",service="test"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.Write([]byte("blob"))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("octo", "ghp_test")
+	client.baseURL = server.URL
+
+	rc, err := client.GetBlob("octo/app", "sha256:abc")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rc.Close()
+	data, _ := io.ReadAll(rc)
+	if string(data) != "blob" {
+		t.Errorf("blob = %q, want %q", data, "blob")
+	}
+}
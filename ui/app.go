@@ -1,12 +1,25 @@
 package ui
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/maful/hij/audit"
+	"github.com/maful/hij/auth"
+	"github.com/maful/hij/cleanup"
 	"github.com/maful/hij/config"
+	"github.com/maful/hij/filter"
 	"github.com/maful/hij/github"
+	"github.com/maful/hij/lifecycle"
+	"github.com/maful/hij/orphan"
+	"github.com/maful/hij/policy"
+	"github.com/maful/hij/refs"
 )
 
 // Screen represents the current screen in the app
@@ -28,17 +41,38 @@ type Model struct {
 	loadingMsg string
 	spinner    spinner.Model
 	quitting   bool
+	height     int        // terminal height, 0 until the first WindowSizeMsg
+	login      string     // authenticated user, recorded in the audit log
+	owner      string     // account whose packages are listed: login, or the organization of a GitHub App
+	auditLog   *audit.Log // nil when the state directory is unavailable
+	cfg        config.Config
 
 	// Token screen
 	tokenInput        textinput.Model
 	pendingToken      string
 	tokenFromKeychain bool
+	tokenStore        string     // where tokens are saved, config.SourceKeychain or config.SourceTokenFile
+	tokenSource       string     // where the token was found, empty when typed in
+	tokenExpires      *time.Time // when the token expires, nil when it does not
 	showSavePrompt    bool
+	deviceCode        *auth.DeviceCode   // code shown while signing in with the device flow
+	deviceCancel      context.CancelFunc // stops the device flow
 
 	// Packages screen
-	packages      []github.Package
-	packageCursor int
-	selectedPkg   *github.Package
+	packages            []github.Package
+	filteredPackages    []github.Package // packages matching the search, in sort order
+	packageCursor       int
+	selectedPkg         *github.Package
+	packageSearch       textinput.Model
+	packageSearchActive bool
+	packageSearchErr    error
+	packageSort         string                // "name", "updated" or "versions"
+	orphans             map[int]orphan.Reason // package ID to why it is orphaned, nil until checked
+	showOrphans         bool                  // list only orphaned packages
+	deletePkg           *github.Package       // orphan whose deletion is being confirmed
+	profilePicker       bool                  // choosing a profile to switch to
+	confirmForget       bool                  // asking before removing the saved token
+	profileCursor       int
 
 	// Versions screen
 	versions         []github.PackageVersion
@@ -48,12 +82,33 @@ type Model struct {
 	filterInput      textinput.Model
 	filterActive     bool
 	filterValue      string
+	filterErr        error                   // parse error of the expression being typed
+	references       refs.Index              // image references found in local deployment manifests
+	scanningRefs     bool                    // the deployment manifests are still being scanned
+	refsErr          error                   // why scanning the deployment manifests failed
+	lifecycle        *lifecycle.Status       // pull requests and branches of the selected package, nil until a filter needs them
+	protectedTags    []policy.TagPattern     // tags of the selected package that are never deleted
+	releases         *policy.Releases        // releases and deployments of the selected package's repository
+	releasesErr      error                   // why looking up releases and deployments failed
+	allowInUse       bool                    // override protection of versions that are in use
+	warnMsg          string                  // transient warning, cleared on the next key press
+	history          *filter.History         // previously applied filters
+	historyIdx       int                     // entry shown in the filter input, len(history) for a new one
+	historyDraft     string                  // filter being typed before browsing history
+	saved            *filter.Saved           // named filters
+	filterMatches    []github.PackageVersion // versions matching the filter being typed
+	filterPreviewed  bool                    // filterMatches is set, the typed filter parses
 
 	// Confirm screen
-	confirmYes bool
-	deleting   bool
-	deleteIdx  int
-	deleteErrs []error
+	confirmYes     bool
+	confirmInput   textinput.Model  // typed confirmation of the package name
+	typedConfirm   bool             // selection is large enough to require typing the package name
+	guardErr       error            // guardrail blocking the current selection
+	archiveEnabled bool             // archive each version before deleting it
+	deleter        *cleanup.Deleter // set while a deletion runs
+	deleting       bool
+	deleteIdx      int
+	deleteErrs     []error
 
 	// Success message (shown after deletion)
 	successMsg string
@@ -64,6 +119,16 @@ type Model struct {
 
 // New creates a new application model
 func New() Model {
+	cfg, err := config.Load()
+	if err != nil {
+		err = fmt.Errorf("failed to load config: %w", err)
+	}
+	return newModel(cfg, err)
+}
+
+// newModel creates the application model for cfg, showing err if loading
+// it failed
+func newModel(cfg config.Config, err error) Model {
 	ti := textinput.New()
 	ti.Placeholder = "ghp_xxxxxxxxxxxxxxxxxxxx"
 	ti.Focus()
@@ -73,10 +138,20 @@ func New() Model {
 	ti.EchoCharacter = '•'
 
 	fi := textinput.New()
-	fi.Placeholder = ":older 10 or :before 2024-01-01"
-	fi.CharLimit = 50
+	fi.Placeholder = cfg.UI.FilterPlaceholder
+	fi.CharLimit = 200
+	fi.ShowSuggestions = true
 	fi.Width = 40
 
+	ps := textinput.New()
+	ps.Placeholder = "name is:private repo:api versions:>50"
+	ps.CharLimit = 100
+	ps.Width = 40
+
+	ci := textinput.New()
+	ci.CharLimit = 100
+	ci.Width = 40
+
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = SpinnerStyle
@@ -85,29 +160,81 @@ func New() Model {
 		screen:           ScreenToken,
 		tokenInput:       ti,
 		filterInput:      fi,
+		confirmInput:     ci,
+		packageSearch:    ps,
+		packageSort:      cfg.UI.PackageSort,
 		spinner:          s,
 		selectedVersions: make(map[int]struct{}),
-		sortOrder:        "newest",
+		sortOrder:        cfg.UI.SortOrder,
+		err:              err,
+		cfg:              cfg,
+		tokenStore:       config.TokenStore(),
+	}
+	m.archiveEnabled = cfg.Archive.Enabled
+	m.scanningRefs = len(cfg.ReferencePaths()) > 0 // started by Init
+
+	if log, err := audit.OpenDefault(); err == nil {
+		m.auditLog = log
 	}
 
-	// Check for existing token in env var or keychain
-	if token, source := config.GetToken(); token != "" {
-		m.client = github.NewClient(token)
+	if path, err := filter.DefaultHistoryPath(); err == nil {
+		m.history, _ = filter.LoadHistory(path)
+	}
+	if path, err := cfg.SavedFiltersPath(); err == nil {
+		saved, err := filter.LoadSaved(path)
+		if err != nil {
+			m.err = fmt.Errorf("failed to load saved filters: %w", err)
+		}
+		m.saved = saved
+	}
+
+	if cfg.Auth == "app" {
+		client, err := auth.NewAppClient(cfg)
+		if err != nil {
+			m.err = err
+			return m
+		}
+		m.client = client
+		m.tokenFromKeychain = true // nothing to save
+		m.loading = true
+		m.loadingMsg = "Authenticating as GitHub App..."
+		return m
+	}
+
+	// Check for existing token in env var, keychain or token file
+	token, source, err := cfg.Token()
+	if err != nil {
+		m.err = err
+	}
+	if token != "" {
+		m.client = m.newClient(token)
 		m.pendingToken = token
-		m.tokenFromKeychain = (source == "keychain")
+		m.tokenSource = source
+		// Tokens of the keychain, the token file or the gh CLI are stored already
+		m.tokenFromKeychain = source == config.SourceKeychain || source == config.SourceTokenFile ||
+			source == config.SourceGHHosts || source == config.SourceGHAuthToken || source == config.SourceTokenCommand
 		m.loading = true
-		m.loadingMsg = "Found token, validating..."
+		m.loadingMsg = "Found token in " + source + ", validating..."
 	}
 
 	return m
 }
 
+// newClient returns an API client for the configured host and owner
+func (m Model) newClient(token string) *github.Client {
+	return auth.NewTokenClient(m.cfg, token)
+}
+
 // Init implements tea.Model
 func (m Model) Init() tea.Cmd {
+	cmds := []tea.Cmd{textinput.Blink}
+	if paths := m.cfg.ReferencePaths(); len(paths) > 0 {
+		cmds = append(cmds, scanReferences(paths))
+	}
 	if m.client != nil && m.loading {
-		return tea.Batch(textinput.Blink, m.spinner.Tick, m.fetchPackages())
+		cmds = append(cmds, m.spinner.Tick, m.fetchPackages())
 	}
-	return textinput.Blink
+	return tea.Batch(cmds...)
 }
 
 // Update implements tea.Model
@@ -119,14 +246,13 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.quitting = true
 			return m, tea.Quit
 		case "q":
-			if m.screen != ScreenToken && !m.filterActive {
+			if m.screen != ScreenToken && !m.filterActive && !m.packageSearchActive && m.deletePkg == nil && !m.profilePicker && !m.confirmForget && !(m.screen == ScreenConfirm && m.typedConfirm) {
 				m.quitting = true
 				return m, tea.Quit
 			}
 		case "esc":
 			if m.filterActive {
-				m.filterActive = false
-				m.filterInput.Blur()
+				m.closeFilterInput()
 				return m, nil
 			}
 			// Go back
@@ -141,6 +267,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						}
 					}
 				}
+				m.refreshPackages()
 				m.screen = ScreenPackages
 				m.selectedVersions = make(map[int]struct{})
 				return m, nil
@@ -149,6 +276,9 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 		}
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		return m, nil
 	case errMsg:
 		m.loading = false
 		m.err = msg.err
@@ -160,11 +290,17 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.loading = false
 		m.packages = msg.packages
+		m.login = msg.login
+		m.owner = msg.owner
+		m.tokenExpires = msg.expires
+		m.refreshPackages()
 		m.screen = ScreenPackages
 		return m, nil
 	case versionsMsg:
 		m.loading = false
 		m.versions = msg.versions
+		m.releases = msg.releases
+		m.releasesErr = msg.releasesErr
 		m.sortVersions(m.versions)      // Sort initially
 		m.filteredVersions = m.versions // Initially show all versions
 		// Update the version count on the selected package
@@ -176,10 +312,29 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 			}
 		}
+		m.refreshPackages()
 		m.screen = ScreenVersions
 		return m, nil
 	case deleteResultMsg:
 		return m.handleDeleteResult(msg)
+	case orphansMsg:
+		return m.handleOrphans(msg)
+	case packageDeletedMsg:
+		return m.handlePackageDeleted(msg)
+	case lifecycleMsg:
+		m.loading = false
+		if msg.err != nil {
+			m.err = fmt.Errorf("failed to look up pull requests and branches: %w", msg.err)
+			return m, nil
+		}
+		m.lifecycle = msg.status
+		m.applyFilter()
+		return m, nil
+	case referencesMsg:
+		m.scanningRefs = false
+		m.references = msg.index
+		m.refsErr = msg.err
+		return m, nil
 	case spinner.TickMsg:
 		if m.loading || m.deleting {
 			var cmd tea.Cmd
@@ -223,11 +378,62 @@ func (m Model) View() string {
 	return ""
 }
 
+// viewError renders err below a screen. When single sign-on blocks the
+// token it shows which organization to authorize it for and where.
+func viewError(err error) string {
+	var sso *github.SSOError
+	if errors.As(err, &sso) {
+		org := "an organization"
+		if sso.Org != "" {
+			org = "the " + sso.Org + " organization"
+		}
+		s := "\n  " + ErrorStyle.Render("✗ SAML single sign-on of "+org+" blocks this token") + "\n"
+		s += "  " + Muted("Authorize it at ") + SelectedStyle.Render(sso.URL) + Muted(", then try again") + "\n"
+		return s
+	}
+	return "\n  " + ErrorStyle.Render("✗ "+err.Error()) + "\n"
+}
+
 // Custom messages
 type errMsg struct{ err error }
-type packagesMsg struct{ packages []github.Package }
-type versionsMsg struct{ versions []github.PackageVersion }
+type packagesMsg struct {
+	packages []github.Package
+	login    string
+	owner    string
+	expires  *time.Time // when the token expires, nil when it does not
+}
+type versionsMsg struct {
+	versions    []github.PackageVersion
+	releases    *policy.Releases
+	releasesErr error // why the releases and deployments could not be looked up
+}
+type referencesMsg struct {
+	index refs.Index
+	err   error
+}
+type orphansMsg struct {
+	orphans []orphan.Orphan
+	err     error
+}
+type packageDeletedMsg struct {
+	pkg      github.Package
+	err      error
+	auditErr error
+}
+type lifecycleMsg struct {
+	status *lifecycle.Status
+	err    error
+}
 type deleteResultMsg struct {
-	idx int
-	err error
+	idx      int
+	err      error
+	auditErr error
+}
+
+// scanReferences looks for image references in local deployment manifests
+func scanReferences(paths []string) tea.Cmd {
+	return func() tea.Msg {
+		found, err := refs.Scan(paths)
+		return referencesMsg{index: refs.NewIndex(found), err: err}
+	}
 }
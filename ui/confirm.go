@@ -4,6 +4,10 @@ import (
 	"fmt"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/maful/hij/cleanup"
+	"github.com/maful/hij/policy"
+	"github.com/maful/hij/registry"
 )
 
 func (m Model) updateConfirm(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -13,15 +17,37 @@ func (m Model) updateConfirm(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		// Nothing can be confirmed while a guardrail blocks the deletion
+		if m.guardErr != nil {
+			if key := msg.String(); key == "n" || key == "N" {
+				m.screen = ScreenVersions
+			}
+			return m, nil
+		}
+
+		if m.typedConfirm {
+			switch msg.String() {
+			case "tab":
+				m.archiveEnabled = !m.archiveEnabled
+				return m, nil
+			case "enter":
+				if m.confirmInput.Value() != m.selectedPkg.Name {
+					m.deleteErrs = []error{fmt.Errorf("type %q exactly to confirm", m.selectedPkg.Name)}
+					return m, nil
+				}
+				return m.startDeletion()
+			}
+			var cmd tea.Cmd
+			m.confirmInput, cmd = m.confirmInput.Update(msg)
+			return m, cmd
+		}
+
 		switch msg.String() {
+		case "tab":
+			m.archiveEnabled = !m.archiveEnabled
+			return m, nil
 		case "y", "Y":
-			m.deleting = true
-			m.deleteIdx = 0
-			m.deleteErrs = nil
-			return m, tea.Batch(
-				m.spinner.Tick,
-				m.deleteNextVersion(),
-			)
+			return m.startDeletion()
 		case "n", "N", "esc":
 			m.screen = ScreenVersions
 			return m, nil
@@ -30,10 +56,50 @@ func (m Model) updateConfirm(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// enterConfirm switches to the confirm screen, evaluating the guardrails for
+// the current selection
+func (m *Model) enterConfirm() {
+	m.screen = ScreenConfirm
+	m.confirmYes = false
+	m.deleteErrs = nil
+	m.guardErr = policy.CheckGuardrails(m.cfg.Guardrails, m.selectedPkg.Name, m.versions, m.selectedVersions)
+	m.typedConfirm = policy.RequiresTypedConfirmation(m.cfg.Guardrails, len(m.selectedVersions))
+	m.confirmInput.SetValue("")
+	if m.typedConfirm {
+		m.confirmInput.Focus()
+	} else {
+		m.confirmInput.Blur()
+	}
+}
+
+func (m Model) startDeletion() (tea.Model, tea.Cmd) {
+	if err := m.deleteBlock(); err != nil {
+		m.deleteErrs = []error{err}
+		return m, nil
+	}
+	deleter, err := m.newDeleter()
+	if err != nil {
+		m.deleteErrs = []error{err}
+		return m, nil
+	}
+	m.deleter = deleter
+	m.deleting = true
+	m.deleteIdx = 0
+	m.deleteErrs = nil
+	m.confirmInput.Blur()
+	return m, tea.Batch(
+		m.spinner.Tick,
+		m.deleteNextVersion(),
+	)
+}
+
 func (m Model) handleDeleteResult(msg deleteResultMsg) (tea.Model, tea.Cmd) {
 	if msg.err != nil {
 		m.deleteErrs = append(m.deleteErrs, msg.err)
 	}
+	if msg.auditErr != nil {
+		m.err = fmt.Errorf("failed to write audit log: %w", msg.auditErr)
+	}
 
 	m.deleteIdx++
 
@@ -45,6 +111,10 @@ func (m Model) handleDeleteResult(msg deleteResultMsg) (tea.Model, tea.Cmd) {
 			deletedCount := len(m.selectedVersions)
 			m.selectedVersions = make(map[int]struct{})
 			m.successMsg = fmt.Sprintf("Successfully deleted %d version(s)", deletedCount)
+			if m.deleter.Archiver != nil {
+				dir, _ := m.cfg.ArchiveDir()
+				m.successMsg += " (archived to " + dir + ")"
+			}
 			m.screen = ScreenVersions
 			m.loading = true
 			m.loadingMsg = "Refreshing versions..."
@@ -69,8 +139,8 @@ func (m Model) deleteNextVersion() tea.Cmd {
 		if _, ok := m.selectedVersions[v.ID]; ok {
 			if count == m.deleteIdx {
 				return func() tea.Msg {
-					err := m.client.DeletePackageVersion("container", m.selectedPkg.Name, v.ID)
-					return deleteResultMsg{idx: v.ID, err: err}
+					res := m.deleter.Delete(v)
+					return deleteResultMsg{idx: v.ID, err: res.Err, auditErr: res.AuditErr}
 				}
 			}
 			count++
@@ -79,12 +149,42 @@ func (m Model) deleteNextVersion() tea.Cmd {
 	return nil
 }
 
+// newDeleter builds the deletion pipeline for the selected package
+func (m Model) newDeleter() (*cleanup.Deleter, error) {
+	d := &cleanup.Deleter{
+		Client:      m.client,
+		Actor:       m.login,
+		Owner:       m.owner,
+		PackageType: m.cfg.PackageType,
+		Package:     m.selectedPkg.Name,
+		Protected:   m.protectedTags,
+		AuditLog:    m.auditLog,
+		Blocked:     m.blockReason,
+	}
+	if m.archiveEnabled {
+		if m.cfg.PackageType != "container" {
+			return nil, fmt.Errorf("only container images can be archived, press tab to turn archiving off")
+		}
+		dir, err := m.cfg.ArchiveDir()
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve archive directory: %w", err)
+		}
+		client := registry.NewClient(m.login, m.client.Token())
+		d.Archiver = registry.NewArchiver(client, dir, m.cfg.Archive.Format == "tar")
+	}
+	return d, nil
+}
+
 func (m Model) viewConfirm() string {
 	s := "\n"
 	s += "  " + TitleStyle.Render("⚠️  Confirm Deletion") + "\n\n"
 
 	if m.deleting {
-		s += "  " + m.spinner.View() + fmt.Sprintf(" Deleting... (%d/%d)\n", m.deleteIdx+1, len(m.selectedVersions))
+		action := "Deleting"
+		if m.deleter.Archiver != nil {
+			action = "Archiving and deleting"
+		}
+		s += "  " + m.spinner.View() + fmt.Sprintf(" %s... (%d/%d)\n", action, m.deleteIdx+1, len(m.selectedVersions))
 
 		if len(m.deleteErrs) > 0 {
 			s += "\n  " + ErrorStyle.Render(fmt.Sprintf("%d errors occurred", len(m.deleteErrs))) + "\n"
@@ -125,8 +225,40 @@ func (m Model) viewConfirm() string {
 		}
 	}
 
+	// Versions still referenced by deployment manifests
+	inUse := 0
+	for _, v := range m.versions {
+		if _, ok := m.selectedVersions[v.ID]; ok && len(m.inUse(v)) > 0 {
+			inUse++
+		}
+	}
+	if inUse > 0 {
+		s += "\n  " + WarningStyle.Render(fmt.Sprintf("⚠ %d selected version(s) are still referenced by deployment manifests", inUse)) + "\n"
+	}
+
+	// Archive mode
+	if m.archiveEnabled {
+		dir, _ := m.cfg.ArchiveDir()
+		s += "\n  " + Success("✓ Archive before delete") + " " + Muted("→ "+dir) + "\n"
+	} else {
+		s += "\n  " + Muted("○ Archive before delete (off)") + "\n"
+	}
+
+	if m.guardErr != nil {
+		s += "\n  " + ErrorStyle.Render("✗ Blocked by guardrail: "+m.guardErr.Error()) + "\n"
+		s += "\n" + HelpStyle.Render("  n/esc: back") + "\n"
+		return s
+	}
+
 	s += "\n  " + Danger("This action cannot be undone!") + "\n"
+	if m.typedConfirm {
+		s += "\n  " + Muted("Type ") + SelectedStyle.Render(m.selectedPkg.Name) + Muted(" to confirm:") + "\n"
+		s += FocusedInputStyle.Render(m.confirmInput.View()) + "\n"
+		s += HelpStyle.Render("  enter: delete • tab: toggle archive • esc: back") + "\n"
+		return s
+	}
 	s += "\n  " + Muted("Delete these versions? ") + SelectedStyle.Render("[y/n]") + "\n"
+	s += HelpStyle.Render("  tab: toggle archive") + "\n"
 
 	return s
 }
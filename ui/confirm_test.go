@@ -0,0 +1,67 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+)
+
+func newConfirmModel(g config.Guardrails, selected ...int) Model {
+	m := Model{
+		cfg:              config.Config{Guardrails: g},
+		selectedPkg:      &github.Package{Name: "app"},
+		versions:         createTestVersions(),
+		selectedVersions: make(map[int]struct{}),
+		confirmInput:     textinput.New(),
+	}
+	for _, id := range selected {
+		m.selectedVersions[id] = struct{}{}
+	}
+	m.enterConfirm()
+	return m
+}
+
+func TestModel_EnterConfirm_Guardrail(t *testing.T) {
+	m := newConfirmModel(config.Guardrails{MaxVersions: 2}, 1, 2, 3)
+
+	if m.guardErr == nil {
+		t.Fatal("expected guardrail error for 3 versions with max_versions 2")
+	}
+
+	model, _ := m.updateConfirm(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	if model.(Model).deleting {
+		t.Error("deletion must not start while a guardrail blocks it")
+	}
+}
+
+func TestModel_TypedConfirmation(t *testing.T) {
+	m := newConfirmModel(config.Guardrails{ConfirmThreshold: 1}, 1, 2)
+
+	if !m.typedConfirm {
+		t.Fatal("expected typed confirmation above the threshold")
+	}
+
+	// y is typed into the input rather than confirming
+	model, _ := m.updateConfirm(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("y")})
+	m = model.(Model)
+	if m.deleting {
+		t.Fatal("y must not confirm when typed confirmation is required")
+	}
+
+	model, _ = m.updateConfirm(tea.KeyMsg{Type: tea.KeyEnter})
+	m = model.(Model)
+	if m.deleting || len(m.deleteErrs) == 0 {
+		t.Error("a wrong package name must be rejected")
+	}
+}
+
+func TestModel_EnterConfirm_BelowThreshold(t *testing.T) {
+	m := newConfirmModel(config.Guardrails{ConfirmThreshold: 10}, 1)
+
+	if m.guardErr != nil || m.typedConfirm {
+		t.Errorf("guardErr = %v, typedConfirm = %v, want neither", m.guardErr, m.typedConfirm)
+	}
+}
@@ -1,10 +1,14 @@
 package ui
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maful/hij/filter"
 	"github.com/maful/hij/github"
 )
 
@@ -19,6 +23,16 @@ func createTestVersions() []github.PackageVersion {
 	}
 }
 
+// shown reports whether version id is in the filtered list
+func shown(m *Model, id int) bool {
+	for _, v := range m.filteredVersions {
+		if v.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
 func TestModel_ApplyFilter_OlderN(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -67,16 +81,16 @@ func TestModel_ApplyFilter_OlderN(t *testing.T) {
 				t.Errorf("filtered count = %d, want %d", len(m.filteredVersions), tt.expectedCount)
 			}
 
-			// Check that correct versions were selected
-			if len(m.selectedVersions) != tt.expectedCount {
-				t.Errorf("selected count = %d, want %d", len(m.selectedVersions), tt.expectedCount)
-			}
-
 			for _, id := range tt.expectedIDs {
-				if _, ok := m.selectedVersions[id]; !ok {
-					t.Errorf("expected version ID %d to be selected", id)
+				if !shown(m, id) {
+					t.Errorf("expected version ID %d to be shown", id)
 				}
 			}
+
+			// Filtering only narrows the view
+			if len(m.selectedVersions) != 0 {
+				t.Errorf("selected count = %d, want 0", len(m.selectedVersions))
+			}
 		})
 	}
 }
@@ -132,8 +146,8 @@ func TestModel_ApplyFilter_BeforeDate(t *testing.T) {
 			}
 
 			for _, id := range tt.expectedIDs {
-				if _, ok := m.selectedVersions[id]; !ok {
-					t.Errorf("expected version ID %d to be selected", id)
+				if !shown(m, id) {
+					t.Errorf("expected version ID %d to be shown", id)
 				}
 			}
 		})
@@ -152,6 +166,7 @@ func TestModel_ApplyFilter_BeforeDateTime(t *testing.T) {
 		filterInput:      textinput.New(),
 		filterValue:      "before 2024-01-15T12:00",
 	}
+	m.cfg.Filters.Timezone = "UTC"
 
 	m.applyFilter()
 
@@ -159,8 +174,8 @@ func TestModel_ApplyFilter_BeforeDateTime(t *testing.T) {
 		t.Errorf("filtered count = %d, want 1", len(m.filteredVersions))
 	}
 
-	if _, ok := m.selectedVersions[2]; !ok {
-		t.Error("expected version ID 2 to be selected")
+	if !shown(m, 2) {
+		t.Error("expected version ID 2 to be shown")
 	}
 }
 
@@ -199,6 +214,29 @@ func TestModel_ApplyFilter_InvalidFilter(t *testing.T) {
 	}
 }
 
+func TestModel_ApplyFilter_SemverReportsIgnored(t *testing.T) {
+	versions := createTestVersions()
+	versions[0].Metadata.Container.Tags = []string{"v1.2.0"}
+	versions[1].Metadata.Container.Tags = []string{"v1.1.0"}
+	versions[2].Metadata.Container.Tags = []string{"v1.1.0-rc.1"}
+
+	m := &Model{
+		versions:         versions,
+		selectedVersions: make(map[int]struct{}),
+		filterInput:      textinput.New(),
+		filterValue:      "semver<1.2.0",
+	}
+
+	m.applyFilter()
+
+	if len(m.filteredVersions) != 2 {
+		t.Errorf("filtered count = %d, want 2", len(m.filteredVersions))
+	}
+	if !strings.Contains(m.warnMsg, "1 version(s) without a semver tag") {
+		t.Errorf("warnMsg = %q, want a report of the untagged version", m.warnMsg)
+	}
+}
+
 func TestModel_ResetFilter(t *testing.T) {
 	versions := createTestVersions()
 
@@ -219,10 +257,112 @@ func TestModel_ResetFilter(t *testing.T) {
 	if len(m.filteredVersions) != len(versions) {
 		t.Errorf("filteredVersions count = %d, want %d", len(m.filteredVersions), len(versions))
 	}
-	if len(m.selectedVersions) != 0 {
-		t.Errorf("selectedVersions count = %d, want 0", len(m.selectedVersions))
+	// The selection survives clearing the filter
+	if len(m.selectedVersions) != 2 {
+		t.Errorf("selectedVersions count = %d, want 2", len(m.selectedVersions))
 	}
 	if m.versionCursor != 0 {
 		t.Errorf("versionCursor = %d, want 0", m.versionCursor)
 	}
 }
+
+func TestModel_SelectShown(t *testing.T) {
+	m := &Model{
+		versions:         createTestVersions(),
+		selectedVersions: make(map[int]struct{}),
+		filterInput:      textinput.New(),
+	}
+	filterBy := func(expr string) {
+		m.filterValue = expr
+		m.applyFilter()
+	}
+	selected := func() []int {
+		var ids []int
+		for _, v := range m.versions {
+			if _, ok := m.selectedVersions[v.ID]; ok {
+				ids = append(ids, v.ID)
+			}
+		}
+		return ids
+	}
+	want := func(step string, ids ...int) {
+		t.Helper()
+		if got := selected(); fmt.Sprint(got) != fmt.Sprint(ids) {
+			t.Errorf("%s: selected %v, want %v", step, got, ids)
+		}
+	}
+
+	filterBy("older 10")
+	m.selectShown(selectAdd)
+	want("add", 2, 3, 4)
+
+	filterBy("older 45")
+	m.selectShown(selectRemove)
+	want("remove", 2, 3)
+
+	m.resetFilter()
+	m.selectShown(selectInvert)
+	want("invert", 1, 4)
+
+	filterBy("older 1")
+	if m.hiddenSelected() != 0 {
+		t.Errorf("hidden = %d, want 0", m.hiddenSelected())
+	}
+	filterBy("older 45")
+	if m.hiddenSelected() != 1 {
+		t.Errorf("hidden = %d, want 1", m.hiddenSelected())
+	}
+	m.selectShown(selectIntersect)
+	want("intersect", 4)
+}
+
+func TestModel_FilterEnter_ParseError(t *testing.T) {
+	m := Model{
+		versions:         createTestVersions(),
+		selectedVersions: make(map[int]struct{}),
+		filterInput:      textinput.New(),
+		filterActive:     true,
+	}
+	m.filterInput.SetValue("age>30 and untagged")
+
+	model, _ := m.updateVersions(tea.KeyMsg{Type: tea.KeyEnter})
+	got := model.(Model)
+
+	if !got.filterActive {
+		t.Error("filter input should stay open when the expression does not parse")
+	}
+	if got.filterErr == nil {
+		t.Fatal("expected a filter error")
+	}
+	if got.filterValue != "" {
+		t.Errorf("filterValue = %q, want unchanged", got.filterValue)
+	}
+	if view := viewFilterError(got.filterErr, got.filterInput.Value()); !strings.Contains(view, "^") {
+		t.Errorf("error view %q should point at the error position", view)
+	}
+
+	got.filterInput.SetValue("age>45d and untagged")
+	model, _ = got.updateVersions(tea.KeyMsg{Type: tea.KeyEnter})
+	got = model.(Model)
+	if got.filterActive || got.filterErr != nil {
+		t.Errorf("valid expression should close the input, active = %v, err = %v", got.filterActive, got.filterErr)
+	}
+	if len(got.filteredVersions) != 1 || got.filteredVersions[0].ID != 4 {
+		t.Errorf("filtered = %v, want only version 4", got.filteredVersions)
+	}
+}
+
+func TestViewFilterError_LongExpression(t *testing.T) {
+	expr := strings.Repeat(`tag~"ü-ß" or `, 8) + "age>>30d"
+	pos := strings.Index(expr, ">>") + 1
+	view := viewFilterError(&filter.ParseError{Pos: pos, Msg: "unexpected >"}, expr)
+
+	lines := strings.Split(view, "\n")
+	excerpt, caret := []rune(lines[0]), strings.Index(lines[1], "^")
+	if len(excerpt) > 2+2*filterExcerpt+2 {
+		t.Errorf("excerpt %q should be cut around the error", lines[0])
+	}
+	if caret < 1 || caret >= len(excerpt) || excerpt[caret] != '>' || excerpt[caret-1] != '>' {
+		t.Errorf("caret at column %d does not point at the second > of %q", caret, lines[0])
+	}
+}
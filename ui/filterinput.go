@@ -0,0 +1,258 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maful/hij/filter"
+)
+
+// updateFilterInput handles keys while the filter input is open
+func (m Model) updateFilterInput(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "enter":
+			return m.submitFilter()
+		case "esc":
+			m.closeFilterInput()
+			return m, nil
+		case "up":
+			m.recallHistory(-1)
+			m.filterInputChanged()
+			return m, nil
+		case "down":
+			m.recallHistory(1)
+			m.filterInputChanged()
+			return m, nil
+		}
+	}
+
+	before := m.filterInput.Value()
+	var cmd tea.Cmd
+	m.filterInput, cmd = m.filterInput.Update(msg)
+	if m.filterInput.Value() != before {
+		m.filterInputChanged()
+	}
+	return m, cmd
+}
+
+// filterInputChanged validates the value being typed, previews how many
+// versions it matches and refreshes the completions offered with tab
+func (m *Model) filterInputChanged() {
+	value := m.filterInput.Value()
+	m.filterInput.SetSuggestions(m.filterCompletions(value))
+
+	m.filterErr = nil
+	m.filterMatches = nil
+	m.filterPreviewed = false
+	value = strings.TrimSpace(value)
+	if command, _ := filterCommand(value); value == "" || command != "" {
+		return
+	}
+	parsed, err := filter.ParseWith(value, m.filterOptions())
+	if err != nil {
+		m.filterErr = err
+		return
+	}
+	if parsed.NeedsLifecycle() && m.lifecycle == nil {
+		// Nothing matches until the pull requests and branches are loaded
+		return
+	}
+	m.filterMatches = parsed.Apply(m.versions)
+	m.filterPreviewed = true
+}
+
+// openFilterInput focuses the filter input, starting history navigation
+// from the newest entry
+func (m *Model) openFilterInput() {
+	m.filterActive = true
+	m.historyIdx = len(m.historyEntries())
+	m.historyDraft = ""
+	m.filterInput.Focus()
+	m.filterInputChanged()
+}
+
+// submitFilter runs a save or load command, or applies the typed filter. The
+// input stays open with an error until the value is valid.
+func (m Model) submitFilter() (tea.Model, tea.Cmd) {
+	value := strings.TrimSpace(m.filterInput.Value())
+
+	switch command, name := filterCommand(value); command {
+	case "save":
+		if err := m.saveFilter(name); err != nil {
+			m.filterErr = err
+			return m, nil
+		}
+		m.successMsg = fmt.Sprintf("Saved filter %q to %s", name, m.saved.Path())
+		m.filterInput.SetValue(m.filterValue)
+		m.closeFilterInput()
+		return m, nil
+	case "load":
+		expr, err := m.loadFilter(name)
+		if err != nil {
+			m.filterErr = err
+			return m, nil
+		}
+		value = expr
+		m.filterInput.SetValue(expr)
+	}
+
+	if value != "" {
+		if _, err := filter.ParseWith(value, m.filterOptions()); err != nil {
+			m.filterErr = err
+			return m, nil
+		}
+		if m.history != nil {
+			if err := m.history.Add(value); err != nil {
+				m.warnMsg = "Failed to save filter history: " + err.Error()
+			}
+		}
+	}
+	m.filterValue = value
+	m.closeFilterInput()
+	m.applyFilter()
+	return m, m.fetchLifecycle()
+}
+
+func (m *Model) closeFilterInput() {
+	m.filterErr = nil
+	m.filterMatches = nil
+	m.filterPreviewed = false
+	m.filterActive = false
+	m.filterInput.Blur()
+}
+
+// filterCommand splits `save NAME` and `load NAME` into the command and the
+// name. Anything else is a filter expression and yields no command.
+func filterCommand(value string) (string, string) {
+	fields := strings.Fields(strings.TrimPrefix(value, ":"))
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", ""
+	}
+	switch command := strings.ToLower(fields[0]); command {
+	case "save", "load":
+		if len(fields) == 2 {
+			return command, fields[1]
+		}
+		return command, ""
+	}
+	return "", ""
+}
+
+// saveFilter stores the applied filter under name for the current package
+func (m Model) saveFilter(name string) error {
+	switch {
+	case m.saved == nil:
+		return fmt.Errorf("saved filters are unavailable")
+	case name == "":
+		return fmt.Errorf("usage: save NAME")
+	case m.filterValue == "":
+		return fmt.Errorf("no filter applied, apply one before saving it")
+	}
+	return m.saved.Save(m.selectedPkg.Name, name, m.filterValue)
+}
+
+// loadFilter returns the filter saved under name for the current package
+func (m Model) loadFilter(name string) (string, error) {
+	if m.saved == nil {
+		return "", fmt.Errorf("saved filters are unavailable")
+	}
+	names := m.saved.Names(m.selectedPkg.Name)
+	if name == "" {
+		if len(names) == 0 {
+			return "", fmt.Errorf("no saved filters, use save NAME to add one")
+		}
+		return "", fmt.Errorf("usage: load NAME, saved: %s", strings.Join(names, ", "))
+	}
+	expr, ok := m.saved.Get(m.selectedPkg.Name, name)
+	if !ok {
+		return "", fmt.Errorf("no saved filter %q", name)
+	}
+	return expr, nil
+}
+
+func (m Model) historyEntries() []string {
+	if m.history == nil {
+		return nil
+	}
+	return m.history.Entries()
+}
+
+// recallHistory moves through previously applied filters, -1 for older and
+// 1 for newer. Moving past the newest entry restores what was being typed.
+func (m *Model) recallHistory(delta int) {
+	entries := m.historyEntries()
+	idx := m.historyIdx + delta
+	if idx < 0 || idx > len(entries) {
+		return
+	}
+	if m.historyIdx == len(entries) {
+		m.historyDraft = m.filterInput.Value()
+	}
+	m.historyIdx = idx
+	if idx == len(entries) {
+		m.filterInput.SetValue(m.historyDraft)
+	} else {
+		m.filterInput.SetValue(entries[idx])
+	}
+	m.filterInput.CursorEnd()
+}
+
+var (
+	// tagValueRegex matches input ending where a tag value is expected
+	tagValueRegex = regexp.MustCompile(`(?i)\btag\s*(=|!=|\^=|~)\s*"?$`)
+	// dateValueRegex matches input ending where a date is expected
+	dateValueRegex = regexp.MustCompile(`(?i)(\b(before|after|between)\s+(\S+\s+)?|\b(created|updated)\s*(=|!=|<=|>=|<|>)\s*)$`)
+)
+
+// filterCompletions returns the completions of the word being typed at the
+// end of value: saved filter names after save or load, known tags after a
+// tag operator, anchors where a date is expected and keywords otherwise.
+// Each completion is the whole input, as the text input expects.
+func (m Model) filterCompletions(value string) []string {
+	start := strings.LastIndexAny(value, " \t()\"=~<>!^") + 1
+	prefix, partial := value[:start], value[start:]
+
+	var candidates []string
+	if command, _ := filterCommand(value); command != "" && strings.TrimSpace(prefix) != "" {
+		if m.saved != nil && m.selectedPkg != nil {
+			candidates = m.saved.Names(m.selectedPkg.Name)
+		}
+	} else if tagValueRegex.MatchString(prefix) {
+		candidates = m.knownTags()
+	} else if dateValueRegex.MatchString(prefix) {
+		candidates = filter.Anchors
+	} else if partial != "" {
+		candidates = filter.Keywords
+		if strings.TrimSpace(strings.TrimPrefix(prefix, ":")) == "" {
+			candidates = append([]string{"save", "load"}, candidates...)
+		}
+	}
+
+	var completions []string
+	for _, c := range candidates {
+		if len(c) > len(partial) && strings.HasPrefix(strings.ToLower(c), strings.ToLower(partial)) {
+			completions = append(completions, prefix+c)
+		}
+	}
+	return completions
+}
+
+// knownTags returns the sorted tags of the loaded versions
+func (m Model) knownTags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, v := range m.versions {
+		for _, tag := range v.Tags() {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
@@ -0,0 +1,252 @@
+package ui
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/filter"
+	"github.com/maful/hij/github"
+	"github.com/maful/hij/lifecycle"
+)
+
+func newFilterInputModel(t *testing.T) Model {
+	t.Helper()
+	dir := t.TempDir()
+	history, err := filter.LoadHistory(filepath.Join(dir, "filter_history"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved, err := filter.LoadSaved(filepath.Join(dir, "filters.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Model{
+		selectedPkg:      &github.Package{Name: "app"},
+		versions:         createTestVersions(),
+		filteredVersions: createTestVersions(),
+		selectedVersions: make(map[int]struct{}),
+		filterInput:      textinput.New(),
+		history:          history,
+		saved:            saved,
+	}
+}
+
+// typeFilter opens the filter input, types value and presses enter
+func typeFilter(m Model, value string) Model {
+	m.openFilterInput()
+	m.filterInput.SetValue(value)
+	model, _ := m.updateVersions(tea.KeyMsg{Type: tea.KeyEnter})
+	return model.(Model)
+}
+
+func pressKey(m Model, k tea.KeyType) Model {
+	model, _ := m.updateVersions(tea.KeyMsg{Type: k})
+	return model.(Model)
+}
+
+func TestModel_FilterHistory(t *testing.T) {
+	m := newFilterInputModel(t)
+	m = typeFilter(m, "older 10")
+	m = typeFilter(m, "older 45")
+
+	m.openFilterInput()
+	m.filterInput.SetValue("unt")
+
+	m = pressKey(m, tea.KeyUp)
+	if got := m.filterInput.Value(); got != "older 45" {
+		t.Errorf("first up = %q, want the newest entry", got)
+	}
+	m = pressKey(m, tea.KeyUp)
+	m = pressKey(m, tea.KeyUp) // stays on the oldest entry
+	if got := m.filterInput.Value(); got != "older 10" {
+		t.Errorf("second up = %q, want the oldest entry", got)
+	}
+	m = pressKey(m, tea.KeyDown)
+	m = pressKey(m, tea.KeyDown)
+	if got := m.filterInput.Value(); got != "unt" {
+		t.Errorf("down past the newest entry = %q, want the draft", got)
+	}
+}
+
+func TestModel_SaveAndLoadFilter(t *testing.T) {
+	m := newFilterInputModel(t)
+
+	m = typeFilter(m, "save stale")
+	if !m.filterActive || m.filterErr == nil {
+		t.Fatal("saving without an applied filter should fail and keep the input open")
+	}
+	m.closeFilterInput()
+
+	m = typeFilter(m, "older 45")
+	m = typeFilter(m, ":save stale")
+	if m.filterActive || m.filterErr != nil {
+		t.Fatalf("save should close the input, err = %v", m.filterErr)
+	}
+	if !strings.Contains(m.successMsg, "stale") {
+		t.Errorf("successMsg = %q", m.successMsg)
+	}
+	if expr, ok := m.saved.Get("app", "stale"); !ok || expr != "older 45" {
+		t.Errorf("saved filter = %q, %v", expr, ok)
+	}
+
+	m.resetFilter()
+	m = typeFilter(m, "load stale")
+	if m.filterValue != "older 45" || len(m.filteredVersions) != 1 {
+		t.Errorf("load applied %q with %d versions, want older 45 with 1", m.filterValue, len(m.filteredVersions))
+	}
+
+	m = typeFilter(m, "load missing")
+	if m.filterErr == nil {
+		t.Error("loading an unknown filter should fail")
+	}
+	m.closeFilterInput()
+
+	if got := m.filterCompletions("load "); len(got) != 1 || got[0] != "load stale" {
+		t.Errorf("completions = %v, want [load stale]", got)
+	}
+}
+
+func TestFilterCommand(t *testing.T) {
+	tests := []struct {
+		value       string
+		wantCommand string
+		wantName    string
+	}{
+		{value: "save stale-prs", wantCommand: "save", wantName: "stale-prs"},
+		{value: ":load stale-prs", wantCommand: "load", wantName: "stale-prs"},
+		{value: "load", wantCommand: "load"},
+		{value: "untagged", wantCommand: ""},
+		{value: "save a b", wantCommand: ""},
+	}
+	for _, tt := range tests {
+		command, name := filterCommand(tt.value)
+		if command != tt.wantCommand || name != tt.wantName {
+			t.Errorf("filterCommand(%q) = %q, %q, want %q, %q", tt.value, command, name, tt.wantCommand, tt.wantName)
+		}
+	}
+}
+
+func TestModel_FilterCompletions(t *testing.T) {
+	m := newFilterInputModel(t)
+	m.versions[0].Metadata.Container.Tags = []string{"pr-12", "latest"}
+	m.versions[1].Metadata.Container.Tags = []string{"pr-7"}
+
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{value: "unt", want: []string{"untagged"}},
+		{value: "sa", want: []string{"save"}},
+		{value: "untagged an", want: []string{"untagged and"}},
+		{value: "untagged and ta", want: []string{"untagged and tagged", "untagged and tag"}},
+		{value: "tag=pr", want: []string{"tag=pr-12", "tag=pr-7"}},
+		{value: `(tag~"l`, want: []string{`(tag~"latest`}},
+		{value: "before yes", want: []string{"before yesterday"}},
+		{value: "between 2024-01-01 last-", want: []string{"between 2024-01-01 last-week", "between 2024-01-01 last-month", "between 2024-01-01 last-year"}},
+		{value: "created>=this-m", want: []string{"created>=this-month"}},
+		{value: "untagged ", want: nil},
+		{value: "untagged", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got := m.filterCompletions(tt.value)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("filterCompletions(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModel_FilterLivePreview(t *testing.T) {
+	m := newFilterInputModel(t)
+	m.openFilterInput()
+
+	for _, r := range ":before 2024-13-01" {
+		model, _ := m.updateVersions(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		m = model.(Model)
+	}
+	if m.filterErr == nil || m.filterPreviewed {
+		t.Fatalf("invalid date should be reported while typing, err = %v", m.filterErr)
+	}
+	if view := m.viewVersions(); !strings.Contains(view, "invalid date") {
+		t.Errorf("view should show the error inline:\n%s", view)
+	}
+
+	m.filterInput.SetValue("older 1")
+	m.filterInputChanged()
+	if m.filterErr != nil || !m.filterPreviewed || len(m.filterMatches) != 4 {
+		t.Fatalf("preview = %d matches, err = %v, want 4", len(m.filterMatches), m.filterErr)
+	}
+	if view := m.viewVersions(); !strings.Contains(view, "4 of 4 versions match") {
+		t.Errorf("view should show the match count:\n%s", view)
+	}
+	// The list and selection are untouched until enter
+	if len(m.selectedVersions) != 0 || m.filterValue != "" {
+		t.Error("typing should not apply the filter")
+	}
+}
+
+type lifecycleClient struct{}
+
+func (lifecycleClient) GetPullRequest(owner, repo string, number int) (*github.PullRequest, error) {
+	state := "open"
+	if number == 1 {
+		state = "closed"
+	}
+	return &github.PullRequest{Number: number, State: state}, nil
+}
+
+func (lifecycleClient) ListBranches(owner, repo string) ([]github.Branch, error) {
+	return []github.Branch{{Name: "main"}}, nil
+}
+
+func TestModel_LifecycleFilter(t *testing.T) {
+	m := newFilterInputModel(t)
+	m.cfg = config.Default()
+	m.versions[0].Metadata.Container.Tags = []string{"pr-1"}
+	m.versions[1].Metadata.Container.Tags = []string{"pr-2"}
+	m.versions[2].Metadata.Container.Tags = []string{"branch-old"}
+
+	m = typeFilter(m, "pr-closed or branch-deleted")
+	if m.warnMsg == "" || m.loading {
+		t.Errorf("a package without a repository should warn, warn = %q, loading = %v", m.warnMsg, m.loading)
+	}
+
+	m.selectedPkg.Repository = &struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		FullName string `json:"full_name"`
+	}{FullName: "octo/app"}
+	m.openFilterInput()
+	m.filterInput.SetValue("pr-closed or branch-deleted")
+	model, cmd := m.updateVersions(tea.KeyMsg{Type: tea.KeyEnter})
+	m = model.(Model)
+	if cmd == nil || !m.loading {
+		t.Fatal("applying the filter should start looking up pull requests and branches")
+	}
+	if len(m.filteredVersions) != 0 {
+		t.Errorf("shown %d versions before the lookup finished, want none", len(m.filteredVersions))
+	}
+
+	patterns, _ := lifecycle.CompilePatterns(m.cfg.Lifecycle)
+	status, err := lifecycle.Load(lifecycleClient{}, "octo/app", patterns, m.versions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, _ = m.Update(lifecycleMsg{status: status})
+	m = model.(Model)
+	if m.loading || !shown(&m, 1) || shown(&m, 2) || !shown(&m, 3) || shown(&m, 4) {
+		t.Errorf("shown = %v, want versions 1 and 3", m.filteredVersions)
+	}
+
+	// Loaded once per package
+	m = typeFilter(m, "pr-closed")
+	if m.loading {
+		t.Error("the lookup should not run again for the same package")
+	}
+}
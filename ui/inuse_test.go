@@ -0,0 +1,200 @@
+package ui
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+	"github.com/maful/hij/policy"
+	"github.com/maful/hij/refs"
+)
+
+func newInUseModel() *Model {
+	versions := createTestVersions()
+	versions[2].Metadata.Container.Tags = []string{"prod"} // v3, 30 days old
+	return &Model{
+		login:            "octo",
+		owner:            "octo",
+		selectedPkg:      &github.Package{Name: "app"},
+		versions:         versions,
+		filteredVersions: versions,
+		selectedVersions: make(map[int]struct{}),
+		filterInput:      textinput.New(),
+		references:       refs.NewIndex([]refs.Reference{{Owner: "octo", Package: "app", Tag: "prod"}}),
+	}
+}
+
+func TestModel_SelectShown_SkipsInUse(t *testing.T) {
+	m := newInUseModel()
+	m.filterValue = "older 10"
+
+	m.applyFilter()
+	m.selectShown(selectAdd)
+
+	if len(m.filteredVersions) != 3 {
+		t.Errorf("filtered count = %d, want 3", len(m.filteredVersions))
+	}
+	if _, ok := m.selectedVersions[3]; ok {
+		t.Error("in-use version 3 should not be selected")
+	}
+	if len(m.selectedVersions) != 2 {
+		t.Errorf("selected count = %d, want 2", len(m.selectedVersions))
+	}
+}
+
+func TestModel_SelectAll_Override(t *testing.T) {
+	m := newInUseModel()
+	key := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+	model, _ := m.updateVersions(key("a"))
+	got := model.(Model)
+	if _, ok := got.selectedVersions[3]; ok {
+		t.Error("select all should skip the in-use version")
+	}
+
+	model, _ = got.updateVersions(key("O"))
+	model, _ = model.(Model).updateVersions(key("a"))
+	got = model.(Model)
+	if _, ok := got.selectedVersions[3]; !ok {
+		t.Error("select all should include the in-use version once overridden")
+	}
+
+	model, _ = got.updateVersions(key("O"))
+	got = model.(Model)
+	if _, ok := got.selectedVersions[3]; ok {
+		t.Error("removing the override should deselect in-use versions")
+	}
+}
+
+func TestModel_SelectShown_SkipsProtectedTags(t *testing.T) {
+	m := newInUseModel()
+	m.references = nil
+	m.versions[1].Metadata.Container.Tags = []string{"v1.0.0"} // v2, 15 days old
+	m.protectedTags, _ = policy.CompileTagPatterns([]string{"v*"})
+	m.allowInUse = true // the override must not unlock protected tags
+	m.filterValue = "older 10"
+
+	m.applyFilter()
+	m.selectShown(selectAdd)
+
+	if _, ok := m.selectedVersions[2]; ok {
+		t.Error("version 2 has a protected tag and should not be selected")
+	}
+	if len(m.selectedVersions) != 2 {
+		t.Errorf("selected count = %d, want 2", len(m.selectedVersions))
+	}
+}
+
+type releaseClient struct{}
+
+func (releaseClient) ListReleases(owner, repo string) ([]github.Release, error) {
+	return []github.Release{{TagName: "v1.0.0"}}, nil
+}
+
+func (releaseClient) ListDeployments(owner, repo string) ([]github.Deployment, error) {
+	return nil, nil
+}
+
+func (releaseClient) ListDeploymentStatuses(owner, repo string, id int) ([]github.DeploymentStatus, error) {
+	return nil, nil
+}
+
+func TestModel_SelectShown_SkipsReleases(t *testing.T) {
+	m := newInUseModel()
+	m.references = nil
+	m.versions[0].Metadata.Container.Tags = []string{"v1.0.0"} // v1
+	releases, err := policy.LoadReleases(releaseClient{}, "octo/app", config.Protect{Releases: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.releases = releases
+
+	m.selectShown(selectAdd)
+	if _, ok := m.selectedVersions[1]; ok {
+		t.Error("version 1 backs a release and should not be selected")
+	}
+	if len(m.selectedVersions) != 3 {
+		t.Errorf("selected count = %d, want 3", len(m.selectedVersions))
+	}
+
+	model, _ := m.updateVersions(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")})
+	got := model.(Model)
+	if _, ok := got.selectedVersions[1]; ok || got.warnMsg != "Protected: backs release v1.0.0" {
+		t.Errorf("toggling a release version: warn = %q", got.warnMsg)
+	}
+}
+
+func TestModel_DeleteWaitsForReferenceScan(t *testing.T) {
+	m := newInUseModel()
+	m.scanningRefs = true
+	m.selectedVersions[1] = struct{}{}
+	key := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+
+	model, _ := m.updateVersions(key("d"))
+	got := model.(Model)
+	if got.screen == ScreenConfirm || got.err == nil {
+		t.Fatal("d should not confirm a deletion while the manifests are scanned")
+	}
+	if _, cmd := got.startDeletion(); cmd != nil {
+		t.Fatal("nothing should be deleted while the manifests are scanned")
+	}
+
+	model, _ = got.Update(referencesMsg{err: errors.New("permission denied")})
+	got = model.(Model)
+	got.err = nil // dismissed
+	model, _ = got.updateVersions(key("d"))
+	got = model.(Model)
+	if got.screen == ScreenConfirm || got.err == nil || !strings.Contains(got.viewVersions(), "permission denied") {
+		t.Error("a failed scan should keep blocking deletions")
+	}
+
+	model, _ = got.Update(referencesMsg{index: m.references})
+	model, _ = model.(Model).updateVersions(key("d"))
+	if got = model.(Model); got.screen != ScreenConfirm {
+		t.Errorf("screen = %v, want the confirmation once the scan succeeded", got.screen)
+	}
+}
+
+func TestModel_ReleasesLookupFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/versions") {
+			json.NewEncoder(w).Encode(createTestVersions())
+			return
+		}
+		// A token with only the packages scopes cannot see the repository
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message":"Not Found"}`))
+	}))
+	defer server.Close()
+
+	m := newPackagesModel()
+	m.cfg = config.Default()
+	m.client = github.NewClient("test-token")
+	m.client.SetBaseURL(server.URL)
+	m.selectedPkg = &m.packages[0] // linked to octo/web
+
+	model, _ := m.Update(m.fetchVersions()())
+	got := model.(Model)
+	if got.screen != ScreenVersions || len(got.versions) != 4 || got.err != nil {
+		t.Fatalf("screen = %v, versions = %d, err = %v, want the versions listed", got.screen, len(got.versions), got.err)
+	}
+	if !strings.Contains(got.viewVersions(), "Deleting is blocked: failed to load releases and deployments") {
+		t.Error("the versions screen should warn that the lookup failed")
+	}
+
+	got.selectedVersions = map[int]struct{}{1: {}}
+	model, _ = got.updateVersions(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("d")})
+	if got = model.(Model); got.screen == ScreenConfirm || got.err == nil {
+		t.Error("d should not confirm a deletion when releases could not be looked up")
+	}
+	if _, cmd := got.startDeletion(); cmd != nil {
+		t.Error("nothing should be deleted when releases could not be looked up")
+	}
+}
@@ -0,0 +1,181 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maful/hij/cleanup"
+	"github.com/maful/hij/github"
+	"github.com/maful/hij/orphan"
+	"github.com/maful/hij/policy"
+)
+
+// toggleOrphans switches the packages list between all packages and those
+// whose repository is gone, archived or unlinked, checking the
+// repositories the first time
+func (m Model) toggleOrphans() (tea.Model, tea.Cmd) {
+	if m.showOrphans {
+		m.showOrphans = false
+		m.refreshPackages()
+		return m, nil
+	}
+	if m.orphans != nil {
+		m.showOrphans = true
+		m.refreshPackages()
+		return m, nil
+	}
+	m.loading = true
+	m.loadingMsg = "Checking repositories..."
+	client, packages := m.client, m.packages
+	return m, tea.Batch(m.spinner.Tick, func() tea.Msg {
+		found, err := orphan.Find(client, packages)
+		return orphansMsg{orphans: found, err: err}
+	})
+}
+
+func (m Model) handleOrphans(msg orphansMsg) (tea.Model, tea.Cmd) {
+	m.loading = false
+	if msg.err != nil {
+		m.err = fmt.Errorf("failed to check repositories: %w", msg.err)
+		return m, nil
+	}
+	m.orphans = make(map[int]orphan.Reason, len(msg.orphans))
+	for _, o := range msg.orphans {
+		m.orphans[o.Package.ID] = o.Reason
+	}
+	m.showOrphans = true
+	m.refreshPackages()
+	return m, nil
+}
+
+// startPackageDelete asks for the name of the orphan under the cursor
+// before deleting it with all of its versions
+func (m Model) startPackageDelete() Model {
+	if len(m.filteredPackages) == 0 {
+		return m
+	}
+	pkg := m.filteredPackages[m.packageCursor]
+	if _, ok := m.orphans[pkg.ID]; !ok {
+		m.err = fmt.Errorf("only orphaned packages can be deleted, press o to list them")
+		return m
+	}
+	if err := m.referencesBlock(); err != nil {
+		m.err = err
+		return m
+	}
+	m.err = nil
+	m.deletePkg = &pkg
+	m.confirmInput.SetValue("")
+	m.confirmInput.Focus()
+	return m
+}
+
+// updatePackageDelete handles keys while a package deletion is confirmed
+func (m Model) updatePackageDelete(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc":
+			m.deletePkg = nil
+			m.confirmInput.Blur()
+			return m, nil
+		case "enter":
+			if m.confirmInput.Value() != m.deletePkg.Name {
+				m.err = fmt.Errorf("type %s to confirm", m.deletePkg.Name)
+				return m, nil
+			}
+			pkg := *m.deletePkg
+			m.deletePkg = nil
+			m.confirmInput.Blur()
+			m.err = nil
+			m.loading = true
+			m.loadingMsg = "Deleting " + pkg.Name + "..."
+			return m, tea.Batch(m.spinner.Tick, m.deletePackage(pkg))
+		}
+	}
+
+	var cmd tea.Cmd
+	m.confirmInput, cmd = m.confirmInput.Update(msg)
+	return m, cmd
+}
+
+// deletePackage removes pkg with the same protections as version deletion:
+// protected tags, references in deployment manifests, and releases and
+// deployments of archived repositories, which can still be looked up
+func (m Model) deletePackage(pkg github.Package) tea.Cmd {
+	return func() tea.Msg {
+		protected, err := policy.CompileTagPatterns(m.cfg.ProtectedTagsFor(pkg.Name))
+		if err != nil {
+			return packageDeletedMsg{pkg: pkg, err: fmt.Errorf("protected_tags: %w", err)}
+		}
+		var releases *policy.Releases
+		if m.orphans[pkg.ID] == orphan.Archived {
+			releases, err = policy.LoadReleases(m.client, pkg.Repository.FullName, m.cfg.Protect)
+			if err != nil {
+				return packageDeletedMsg{pkg: pkg, err: fmt.Errorf("failed to load releases and deployments: %w", err)}
+			}
+		}
+		versions, err := m.client.ListPackageVersions(m.cfg.PackageType, pkg.Name)
+		if err != nil {
+			return packageDeletedMsg{pkg: pkg, err: err}
+		}
+
+		d := &cleanup.Deleter{
+			Client:      m.client,
+			Actor:       m.login,
+			Owner:       m.owner,
+			PackageType: m.cfg.PackageType,
+			Package:     pkg.Name,
+			Protected:   protected,
+			AuditLog:    m.auditLog,
+			Blocked: func(v github.PackageVersion) error {
+				if err := policy.CheckTags(protected, v); err != nil {
+					return err
+				}
+				if err := releases.Check(v); err != nil {
+					return err
+				}
+				if used := m.references.Lookup(m.owner, pkg.Name, v); len(used) > 0 {
+					return fmt.Errorf("in use by %s", used[0])
+				}
+				return nil
+			},
+		}
+		if err := policy.CheckPackageGuardrails(m.cfg.Guardrails, pkg.Name, versions, d.Blocked); err != nil {
+			return packageDeletedMsg{pkg: pkg, err: err}
+		}
+		res := d.DeletePackage(versions)
+		return packageDeletedMsg{pkg: pkg, err: res.Err, auditErr: res.AuditErr}
+	}
+}
+
+func (m Model) handlePackageDeleted(msg packageDeletedMsg) (tea.Model, tea.Cmd) {
+	m.loading = false
+	if msg.auditErr != nil {
+		m.err = fmt.Errorf("failed to write audit log: %w", msg.auditErr)
+	}
+	if msg.err != nil {
+		m.err = msg.err
+		return m, nil
+	}
+	for i, p := range m.packages {
+		if p.ID == msg.pkg.ID {
+			m.packages = append(m.packages[:i:i], m.packages[i+1:]...)
+			break
+		}
+	}
+	delete(m.orphans, msg.pkg.ID)
+	m.successMsg = fmt.Sprintf("Deleted package %s with %d version(s)", msg.pkg.Name, msg.pkg.VersionCount)
+	m.refreshPackages()
+	return m, nil
+}
+
+func (m Model) viewPackageDelete() string {
+	s := "\n  " + Danger(fmt.Sprintf("Delete %s with all %d version(s)? This cannot be undone!", m.deletePkg.Name, m.deletePkg.VersionCount)) + "\n"
+	s += "\n  " + Muted("Type ") + SelectedStyle.Render(m.deletePkg.Name) + Muted(" to confirm:") + "\n"
+	s += FocusedInputStyle.Render(m.confirmInput.View()) + "\n"
+	if m.err != nil {
+		s += viewError(m.err)
+	}
+	s += "\n" + HelpStyle.Render("  enter: delete package • esc: cancel") + "\n"
+	return s
+}
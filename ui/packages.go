@@ -2,59 +2,391 @@ package ui
 
 import (
 	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/maful/hij/github"
+	"github.com/maful/hij/policy"
 )
 
+// packageSorts are the orders the packages list cycles through with s
+var packageSorts = []string{"name", "updated", "versions"}
+
 func (m Model) updatePackages(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if m.deletePkg != nil {
+		return m.updatePackageDelete(msg)
+	}
+	if m.profilePicker {
+		return m.updateProfilePicker(msg)
+	}
+	if m.confirmForget {
+		return m.updateForgetToken(msg)
+	}
+	if m.packageSearchActive {
+		return m.updatePackageSearch(msg)
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		m.successMsg = ""
 		switch msg.String() {
 		case "up", "k":
-			if m.packageCursor > 0 {
-				m.packageCursor--
-			}
+			m.movePackageCursor(-1)
 		case "down", "j":
-			if m.packageCursor < len(m.packages)-1 {
-				m.packageCursor++
-			}
+			m.movePackageCursor(1)
+		case "pgup", "ctrl+u":
+			m.movePackageCursor(-m.listHeight())
+		case "pgdown", "ctrl+d":
+			m.movePackageCursor(m.listHeight())
+		case "home", "g":
+			m.packageCursor = 0
+		case "end", "G":
+			m.movePackageCursor(len(m.filteredPackages))
+		case "/":
+			m.packageSearchActive = true
+			m.packageSearch.Focus()
+			return m, nil
+		case "c", "esc":
+			m.packageSearch.SetValue("")
+			m.refreshPackages()
+		case "s":
+			m.packageSort = packageSorts[(indexOf(packageSorts, m.packageSort)+1)%len(packageSorts)]
+			m.refreshPackages()
+		case "o":
+			return m.toggleOrphans()
+		case "D":
+			return m.startPackageDelete(), nil
+		case "P":
+			return m.openProfilePicker(), nil
+		case "L":
+			m.confirmForget = true
+			return m, nil
 		case "enter":
-			if len(m.packages) > 0 {
-				m.selectedPkg = &m.packages[m.packageCursor]
-				// Reset filter state for new package
-				m.filterValue = ""
-				m.filterInput.SetValue("")
-				m.selectedVersions = make(map[int]struct{})
-				m.versionCursor = 0
-				m.loading = true
-				m.loadingMsg = "Loading versions..."
-				return m, tea.Batch(
-					m.spinner.Tick,
-					m.fetchVersions(),
-				)
+			if len(m.filteredPackages) > 0 {
+				return m.selectPackage(m.filteredPackages[m.packageCursor].ID)
 			}
 		}
 	}
 	return m, nil
 }
 
+// updatePackageSearch handles keys while the search input is focused. The
+// list narrows as the query is typed and can still be navigated.
+func (m Model) updatePackageSearch(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "enter":
+			m.packageSearchActive = false
+			m.packageSearch.Blur()
+			return m, nil
+		case "esc":
+			m.packageSearchActive = false
+			m.packageSearch.Blur()
+			m.packageSearch.SetValue("")
+			m.refreshPackages()
+			return m, nil
+		case "up":
+			m.movePackageCursor(-1)
+			return m, nil
+		case "down":
+			m.movePackageCursor(1)
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	m.packageSearch, cmd = m.packageSearch.Update(msg)
+	m.refreshPackages()
+	return m, cmd
+}
+
+// selectPackage opens the versions screen of the package with the given ID
+func (m Model) selectPackage(id int) (tea.Model, tea.Cmd) {
+	for i := range m.packages {
+		if m.packages[i].ID == id {
+			m.selectedPkg = &m.packages[i]
+		}
+	}
+	// Reset filter state for new package
+	m.filterValue = ""
+	m.filterInput.SetValue("")
+	m.selectedVersions = make(map[int]struct{})
+	m.allowInUse = false
+	m.lifecycle = nil
+	protected, err := policy.CompileTagPatterns(m.cfg.ProtectedTagsFor(m.selectedPkg.Name))
+	if err != nil {
+		m.err = fmt.Errorf("protected_tags: %w", err)
+		return m, nil
+	}
+	m.protectedTags = protected
+	m.versionCursor = 0
+	m.loading = true
+	m.loadingMsg = "Loading versions..."
+	return m, tea.Batch(
+		m.spinner.Tick,
+		m.fetchVersions(),
+	)
+}
+
+func (m *Model) movePackageCursor(delta int) {
+	m.packageCursor += delta
+	if m.packageCursor >= len(m.filteredPackages) {
+		m.packageCursor = len(m.filteredPackages) - 1
+	}
+	if m.packageCursor < 0 {
+		m.packageCursor = 0
+	}
+}
+
+// refreshPackages recomputes the shown packages from the search query and
+// sort order, keeping the cursor on the same package when it is still shown
+func (m *Model) refreshPackages() {
+	var current int
+	if m.packageCursor < len(m.filteredPackages) {
+		current = m.filteredPackages[m.packageCursor].ID
+	}
+
+	q, err := parsePackageQuery(m.packageSearch.Value())
+	m.packageSearchErr = err
+	m.filteredPackages = nil
+	for _, p := range m.packages {
+		if _, orphaned := m.orphans[p.ID]; m.showOrphans && !orphaned {
+			continue
+		}
+		if err != nil || q.match(p) {
+			m.filteredPackages = append(m.filteredPackages, p)
+		}
+	}
+	sortPackages(m.filteredPackages, m.packageSort)
+
+	m.packageCursor = 0
+	for i, p := range m.filteredPackages {
+		if p.ID == current {
+			m.packageCursor = i
+		}
+	}
+}
+
+// sortPackages orders packages by name, most recently updated or most
+// versions
+func sortPackages(packages []github.Package, by string) {
+	sort.SliceStable(packages, func(i, j int) bool {
+		switch by {
+		case "updated":
+			return packages[i].UpdatedAt.After(packages[j].UpdatedAt)
+		case "versions":
+			return packages[i].VersionCount > packages[j].VersionCount
+		}
+		return strings.ToLower(packages[i].Name) < strings.ToLower(packages[j].Name)
+	})
+}
+
+// packageQuery is a parsed packages search. Qualifiers narrow the list and
+// the remaining words are fuzzy matched against package names:
+//
+//	is:public  is:private  is:internal
+//	repo:api  repo:none
+//	versions:>100  versions:<=5  versions:0
+type packageQuery struct {
+	words      []string
+	visibility string
+	repo       string
+	versionOp  string
+	versions   int
+}
+
+func parsePackageQuery(s string) (packageQuery, error) {
+	var q packageQuery
+	for _, field := range strings.Fields(s) {
+		key, value, ok := strings.Cut(field, ":")
+		if !ok {
+			q.words = append(q.words, strings.ToLower(field))
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "is":
+			switch v := strings.ToLower(value); v {
+			case "public", "private", "internal":
+				q.visibility = v
+			default:
+				return q, fmt.Errorf("unknown visibility %q, expected public, private or internal", value)
+			}
+		case "repo":
+			q.repo = strings.ToLower(value)
+		case "versions":
+			q.versionOp = "="
+			for _, op := range []string{">=", "<=", ">", "<", "="} {
+				if strings.HasPrefix(value, op) {
+					q.versionOp, value = op, value[len(op):]
+					break
+				}
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return q, fmt.Errorf("invalid version count %q", value)
+			}
+			q.versions = n
+		default:
+			return q, fmt.Errorf("unknown qualifier %q, expected is:, repo: or versions:", key+":")
+		}
+	}
+	return q, nil
+}
+
+func (q packageQuery) match(p github.Package) bool {
+	if q.visibility != "" && !strings.EqualFold(p.Visibility, q.visibility) {
+		return false
+	}
+	switch {
+	case q.repo == "":
+	case q.repo == "none":
+		if p.Repository != nil {
+			return false
+		}
+	case p.Repository == nil || !strings.Contains(strings.ToLower(p.Repository.FullName), q.repo):
+		return false
+	}
+	if q.versionOp != "" && !compareCount(p.VersionCount, q.versionOp, q.versions) {
+		return false
+	}
+	name := strings.ToLower(p.Name)
+	for _, w := range q.words {
+		if !fuzzyMatch(w, name) {
+			return false
+		}
+	}
+	return true
+}
+
+func compareCount(n int, op string, ref int) bool {
+	switch op {
+	case ">":
+		return n > ref
+	case ">=":
+		return n >= ref
+	case "<":
+		return n < ref
+	case "<=":
+		return n <= ref
+	}
+	return n == ref
+}
+
+// fuzzyMatch reports whether the characters of pattern appear in s in order,
+// so "wbap" matches "web-app"
+func fuzzyMatch(pattern, s string) bool {
+	for _, r := range pattern {
+		i := strings.IndexRune(s, r)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(string(r)):]
+	}
+	return true
+}
+
+func indexOf(values []string, v string) int {
+	for i, value := range values {
+		if value == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// listHeight is how many rows of a list fit on screen, leaving room for the
+// title, inputs and help
+func (m Model) listHeight() int {
+	const chrome = 14
+	if m.height == 0 {
+		return 15
+	}
+	return max(m.height-chrome, 3)
+}
+
+// scrollWindow returns the range of a list of total rows to render so that
+// the cursor stays visible, centred where possible
+func scrollWindow(cursor, total, height int) (int, int) {
+	if total <= height {
+		return 0, total
+	}
+	start := min(max(cursor-height/2, 0), total-height)
+	return start, start + height
+}
+
 func (m Model) viewPackages() string {
 	s := "\n"
 	s += "  " + TitleStyle.Render("📦 Your Packages") + "\n"
-	s += "  " + SubtitleStyle.Render("Container images in your account") + "\n\n"
+	s += "  " + SubtitleStyle.Render(m.packagesSubtitle()) + "\n\n"
 
 	if m.loading {
 		s += "  " + m.spinner.View() + " " + m.loadingMsg + "\n"
 		return s
 	}
+	s += m.viewTokenExpiry(time.Now())
 
 	if len(m.packages) == 0 {
-		s += "  " + Muted("No container packages found.") + "\n"
-		s += "\n" + HelpStyle.Render("  q: quit") + "\n"
+		if m.profilePicker {
+			return s + m.viewProfilePicker()
+		}
+		s += "  " + Muted("No "+m.cfg.PackageType+" packages found.") + "\n"
+		if m.err != nil {
+			s += viewError(m.err)
+		}
+		s += "\n" + HelpStyle.Render("  P: switch profile • q: quit") + "\n"
+		return s
+	}
+
+	if m.deletePkg != nil {
+		return s + m.viewPackageDelete()
+	}
+	if m.profilePicker {
+		return s + m.viewProfilePicker()
+	}
+	if m.confirmForget {
+		s += "  " + WarningStyle.Render("Forget the token saved in the "+m.tokenStore+"?") + "\n"
+		s += "  " + Muted("You will be asked for a token the next time hij starts") + "\n"
+		s += "\n" + HelpStyle.Render("  y: forget • n: cancel") + "\n"
 		return s
 	}
 
-	for i, pkg := range m.packages {
+	// Search input
+	if m.packageSearchActive {
+		s += FocusedInputStyle.Render(m.packageSearch.View()) + "\n"
+	} else if m.packageSearch.Value() != "" {
+		s += "  " + Muted("Search: ") + TagStyle.Render(m.packageSearch.Value()) + "\n"
+	}
+	if m.packageSearchErr != nil {
+		s += "  " + ErrorStyle.Render("✗ "+m.packageSearchErr.Error()) + "\n"
+	}
+	s += "  " + Muted(fmt.Sprintf("%d of %d packages • Sort: ", len(m.filteredPackages), len(m.packages))) +
+		TagStyle.Render(m.packageSort)
+	if m.showOrphans {
+		s += Muted(" • ") + WarningStyle.Render("orphans only")
+	}
+	s += "\n\n"
+	if m.successMsg != "" {
+		s += "  " + SuccessStyle.Render("✓ "+m.successMsg) + "\n\n"
+	}
+
+	if len(m.filteredPackages) == 0 {
+		if m.showOrphans && m.packageSearch.Value() == "" {
+			s += "  " + Muted("No orphaned packages.") + "\n"
+		} else {
+			s += "  " + Muted("No packages match the search.") + "\n"
+		}
+	}
+
+	start, end := scrollWindow(m.packageCursor, len(m.filteredPackages), m.listHeight())
+	if start > 0 {
+		s += "  " + Muted(fmt.Sprintf("↑ %d more", start)) + "\n"
+	}
+	for i := start; i < end; i++ {
+		pkg := m.filteredPackages[i]
 		cursor := "  "
 		if m.packageCursor == i {
 			cursor = Cursor() + " "
@@ -68,24 +400,62 @@ func (m Model) viewPackages() string {
 		versions := Muted(fmt.Sprintf("(%d versions)", pkg.VersionCount))
 		visibility := TagStyle.Render(pkg.Visibility)
 
-		s += fmt.Sprintf("%s%s %s %s\n", cursor, name, versions, visibility)
+		row := fmt.Sprintf("%s%s %s %s", cursor, name, versions, visibility)
+		if pkg.Repository != nil {
+			row += " " + Muted(pkg.Repository.FullName)
+		}
+		if reason, ok := m.orphans[pkg.ID]; ok {
+			row += " " + WarningStyle.Render("⚠ "+string(reason))
+		}
+		s += row + "\n"
+	}
+	if end < len(m.filteredPackages) {
+		s += "  " + Muted(fmt.Sprintf("↓ %d more", len(m.filteredPackages)-end)) + "\n"
 	}
 
 	if m.err != nil {
-		s += "\n  " + ErrorStyle.Render("✗ "+m.err.Error()) + "\n"
+		s += viewError(m.err)
 	}
 
-	s += "\n" + HelpStyle.Render("  ↑/k: up • ↓/j: down • enter: select • q: quit") + "\n"
+	if m.packageSearchActive {
+		s += "\n" + HelpStyle.Render("  fuzzy name, is:public, repo:api, versions:>100 • ↑/↓: move • enter: done • esc: clear") + "\n"
+	} else {
+		s += "\n" + HelpStyle.Render("  ↑/k: up • ↓/j: down • pgup/pgdn: page • /: search • s: sort • c: clear • o: orphans • D: delete orphan • P: profile • L: forget token • enter: select • q: quit") + "\n"
+	}
+
+	return s
+}
 
+// packagesSubtitle describes the listed packages: their type, whose they
+// are and the active profile
+func (m Model) packagesSubtitle() string {
+	kind := "Container images"
+	if m.cfg.PackageType != "container" {
+		kind = m.cfg.PackageType + " packages"
+	}
+	s := kind + " in your account"
+	if m.owner != "" && m.owner != m.login {
+		s = kind + " of " + m.owner
+	}
+	if m.cfg.Profile != "" {
+		s += " • profile " + m.cfg.Profile
+	}
 	return s
 }
 
 func (m Model) fetchVersions() tea.Cmd {
 	return func() tea.Msg {
-		versions, err := m.client.ListPackageVersions("container", m.selectedPkg.Name)
+		versions, err := m.client.ListPackageVersions(m.cfg.PackageType, m.selectedPkg.Name)
 		if err != nil {
 			return errMsg{err}
 		}
-		return versionsMsg{versions}
+		var releases *policy.Releases
+		if repo := m.selectedPkg.Repository; repo != nil {
+			// Without them the versions are still listed, but not deleted
+			if releases, err = policy.LoadReleases(m.client, repo.FullName, m.cfg.Protect); err != nil {
+				return versionsMsg{versions: versions, releasesErr: err}
+			}
+		}
+		return versionsMsg{versions: versions, releases: releases}
 	}
 }
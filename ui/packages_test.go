@@ -0,0 +1,263 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+	"github.com/maful/hij/orphan"
+)
+
+func newPackagesModel() Model {
+	now := time.Now()
+	pkg := func(id int, name, visibility string, versions int, updated time.Duration, repo string) github.Package {
+		p := github.Package{ID: id, Name: name, Visibility: visibility, VersionCount: versions, UpdatedAt: now.Add(-updated)}
+		if repo != "" {
+			p.Repository = &struct {
+				ID       int    `json:"id"`
+				Name     string `json:"name"`
+				FullName string `json:"full_name"`
+			}{Name: repo, FullName: "octo/" + repo}
+		}
+		return p
+	}
+	m := Model{
+		screen: ScreenPackages,
+		packages: []github.Package{
+			pkg(1, "web-app", "public", 120, 3*time.Hour, "web"),
+			pkg(2, "api", "private", 8, time.Hour, "api"),
+			pkg(3, "worker", "private", 40, 48*time.Hour, ""),
+			pkg(4, "Base-Image", "internal", 300, 24*time.Hour, "infra"),
+		},
+		packageSearch: textinput.New(),
+		packageSort:   "name",
+	}
+	m.refreshPackages()
+	return m
+}
+
+func packageNames(packages []github.Package) []string {
+	var names []string
+	for _, p := range packages {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestModel_PackageSearch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "", want: []string{"api", "Base-Image", "web-app", "worker"}},
+		{query: "wbap", want: []string{"web-app"}},
+		{query: "w", want: []string{"web-app", "worker"}},
+		{query: "is:private", want: []string{"api", "worker"}},
+		{query: "repo:none", want: []string{"worker"}},
+		{query: "repo:octo/we", want: []string{"web-app"}},
+		{query: "versions:>=40", want: []string{"Base-Image", "web-app", "worker"}},
+		{query: "versions:8", want: []string{"api"}},
+		{query: "w is:private versions:<100", want: []string{"worker"}},
+		{query: "bim", want: []string{"Base-Image"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			m := newPackagesModel()
+			m.packageSearch.SetValue(tt.query)
+			m.refreshPackages()
+			if m.packageSearchErr != nil {
+				t.Fatalf("unexpected error: %v", m.packageSearchErr)
+			}
+			if got := packageNames(m.filteredPackages); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("shown %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestModel_PackageSearch_Invalid(t *testing.T) {
+	for _, query := range []string{"is:secret", "versions:>lots", "owner:me"} {
+		m := newPackagesModel()
+		m.packageSearch.SetValue(query)
+		m.refreshPackages()
+		if m.packageSearchErr == nil {
+			t.Errorf("%q: expected an error", query)
+		}
+		if len(m.filteredPackages) != len(m.packages) {
+			t.Errorf("%q: invalid search should show all packages", query)
+		}
+	}
+}
+
+func TestModel_PackageSort(t *testing.T) {
+	m := newPackagesModel()
+	m.packageCursor = 2 // web-app
+	key := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("s")}
+
+	model, _ := m.updatePackages(key)
+	m = model.(Model)
+	if m.packageSort != "updated" || fmt.Sprint(packageNames(m.filteredPackages)) != "[api web-app Base-Image worker]" {
+		t.Errorf("sort %s = %v", m.packageSort, packageNames(m.filteredPackages))
+	}
+	if m.filteredPackages[m.packageCursor].Name != "web-app" {
+		t.Errorf("cursor moved to %s, want it to stay on web-app", m.filteredPackages[m.packageCursor].Name)
+	}
+
+	model, _ = m.updatePackages(key)
+	m = model.(Model)
+	if m.packageSort != "versions" || fmt.Sprint(packageNames(m.filteredPackages)) != "[Base-Image web-app worker api]" {
+		t.Errorf("sort %s = %v", m.packageSort, packageNames(m.filteredPackages))
+	}
+
+	model, _ = m.updatePackages(key)
+	if got := model.(Model).packageSort; got != "name" {
+		t.Errorf("sort = %s, want name after a full cycle", got)
+	}
+}
+
+func TestModel_PackageSearchTyping(t *testing.T) {
+	m := newPackagesModel()
+	model, _ := m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	for _, r := range "wq" {
+		model, _ = model.(Model).Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	m = model.(Model)
+	if m.quitting {
+		t.Fatal("q typed into the search should not quit")
+	}
+	if m.packageSearch.Value() != "wq" || len(m.filteredPackages) != 0 {
+		t.Errorf("search %q shows %v", m.packageSearch.Value(), packageNames(m.filteredPackages))
+	}
+
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = model.(Model)
+	if m.packageSearchActive || len(m.filteredPackages) != 4 {
+		t.Errorf("esc should close and clear the search, active = %v, shown = %d", m.packageSearchActive, len(m.filteredPackages))
+	}
+}
+
+func TestScrollWindow(t *testing.T) {
+	tests := []struct {
+		cursor, total, height int
+		wantStart, wantEnd    int
+	}{
+		{cursor: 0, total: 5, height: 10, wantStart: 0, wantEnd: 5},
+		{cursor: 0, total: 150, height: 10, wantStart: 0, wantEnd: 10},
+		{cursor: 50, total: 150, height: 10, wantStart: 45, wantEnd: 55},
+		{cursor: 149, total: 150, height: 10, wantStart: 140, wantEnd: 150},
+	}
+	for _, tt := range tests {
+		start, end := scrollWindow(tt.cursor, tt.total, tt.height)
+		if start != tt.wantStart || end != tt.wantEnd {
+			t.Errorf("scrollWindow(%d, %d, %d) = %d, %d, want %d, %d", tt.cursor, tt.total, tt.height, start, end, tt.wantStart, tt.wantEnd)
+		}
+	}
+}
+
+func TestModel_ListHeight(t *testing.T) {
+	m := newPackagesModel()
+	if got := m.listHeight(); got != 15 {
+		t.Errorf("listHeight() before a resize = %d, want 15", got)
+	}
+	model, _ := m.Update(tea.WindowSizeMsg{Width: 80, Height: 40})
+	if got := model.(Model).listHeight(); got != 26 {
+		t.Errorf("listHeight() = %d, want 26", got)
+	}
+}
+
+func TestModel_Orphans(t *testing.T) {
+	m := newPackagesModel()
+	m.confirmInput = textinput.New()
+	model, _ := m.handleOrphans(orphansMsg{orphans: []orphan.Orphan{
+		{Package: m.packages[2], Reason: orphan.Unlinked},
+		{Package: m.packages[3], Reason: orphan.Archived},
+	}})
+	m = model.(Model)
+	if got := fmt.Sprint(packageNames(m.filteredPackages)); got != "[Base-Image worker]" {
+		t.Errorf("orphans shown = %s", got)
+	}
+
+	// Toggling back shows every package without checking again
+	model, cmd := m.updatePackages(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	if m = model.(Model); cmd != nil || len(m.filteredPackages) != 4 {
+		t.Errorf("shown %d packages after toggling orphans off, want 4", len(m.filteredPackages))
+	}
+	m.packageCursor = 0 // api
+	model, _ = m.updatePackages(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("D")})
+	if m = model.(Model); m.deletePkg != nil || m.err == nil {
+		t.Error("deleting a package that is not orphaned should be refused")
+	}
+
+	model, _ = m.updatePackages(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("o")})
+	m = model.(Model)
+	model, _ = m.updatePackages(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("D")})
+	if m = model.(Model); m.deletePkg == nil || m.deletePkg.Name != "Base-Image" {
+		t.Fatalf("delete should ask to confirm Base-Image, got %v", m.deletePkg)
+	}
+
+	// q is typed into the confirmation rather than quitting
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	model, cmd = model.(Model).Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if m = model.(Model); m.quitting || cmd != nil || m.err == nil {
+		t.Error("a mistyped name should not start the deletion")
+	}
+
+	model, _ = m.handlePackageDeleted(packageDeletedMsg{pkg: m.packages[3]})
+	m = model.(Model)
+	if got := fmt.Sprint(packageNames(m.filteredPackages)); got != "[worker]" || len(m.packages) != 3 {
+		t.Errorf("after deleting Base-Image shown = %s, packages = %d", got, len(m.packages))
+	}
+}
+
+func TestModel_SwitchProfile(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("XDG_STATE_HOME", dir)
+	t.Setenv("HIJ_PROFILE", "")
+	t.Setenv("HIJ_TEST_WORK_TOKEN", "ghp_work")
+	content := "profile: personal\nprofiles:\n  personal:\n    token_source: env:HIJ_TEST_PERSONAL_TOKEN\n" +
+		"  work:\n    owner: acme\n    token_source: env:HIJ_TEST_WORK_TOKEN\n"
+	if err := os.MkdirAll(filepath.Join(dir, "hij"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "hij", "config.yaml"), []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := newPackagesModel()
+	m.cfg = cfg
+	m = pressPackageKey(m, "P")
+	if !m.profilePicker || m.profileCursor != 0 {
+		t.Fatalf("P should open the picker on the active profile, cursor = %d", m.profileCursor)
+	}
+	if view := m.viewPackages(); !strings.Contains(view, "work") || !strings.Contains(view, "(active)") {
+		t.Errorf("picker should list the profiles:\n%s", view)
+	}
+
+	m = pressPackageKey(m, "j")
+	model, cmd := m.updatePackages(tea.KeyMsg{Type: tea.KeyEnter})
+	m = model.(Model)
+	if m.cfg.Profile != "work" || m.client == nil || m.client.Org() != "acme" || m.pendingToken != "ghp_work" {
+		t.Fatalf("switched to profile %q with owner %v", m.cfg.Profile, m.client)
+	}
+	if cmd == nil || !m.loading || m.screen != ScreenToken {
+		t.Error("switching should load the packages of the new profile")
+	}
+}
+
+func pressPackageKey(m Model, key string) Model {
+	model, _ := m.updatePackages(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)})
+	return model.(Model)
+}
@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maful/hij/config"
+)
+
+// openProfilePicker lists the configured profiles to switch to
+func (m Model) openProfilePicker() Model {
+	names := m.cfg.ProfileNames()
+	if len(names) == 0 {
+		m.err = fmt.Errorf("no profiles configured, add them under profiles in the config file")
+		return m
+	}
+	m.err = nil
+	m.profilePicker = true
+	m.profileCursor = max(indexOf(names, m.cfg.Profile), 0)
+	return m
+}
+
+// updateProfilePicker handles keys while choosing a profile
+func (m Model) updateProfilePicker(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	names := m.cfg.ProfileNames()
+	switch key.String() {
+	case "up", "k":
+		m.profileCursor = max(m.profileCursor-1, 0)
+	case "down", "j":
+		m.profileCursor = min(m.profileCursor+1, len(names)-1)
+	case "esc":
+		m.profilePicker = false
+	case "enter":
+		m.profilePicker = false
+		return m.switchProfile(names[m.profileCursor])
+	}
+	return m, nil
+}
+
+// switchProfile starts over with the settings and token of the named
+// profile, asking for a token when it has none
+func (m Model) switchProfile(name string) (tea.Model, tea.Cmd) {
+	if name == m.cfg.Profile {
+		return m, nil
+	}
+	cfg, err := config.LoadProfile(name)
+	if err != nil {
+		m.err = fmt.Errorf("failed to load profile %s: %w", name, err)
+		return m, nil
+	}
+	next := newModel(cfg, nil)
+	next.height = m.height
+	return next, next.Init()
+}
+
+func (m Model) viewProfilePicker() string {
+	s := "  " + SubtitleStyle.Render("Switch profile") + "\n\n"
+	for i, name := range m.cfg.ProfileNames() {
+		p := m.cfg.Profiles[name]
+		cursor := "  "
+		label := name
+		if i == m.profileCursor {
+			cursor = Cursor() + " "
+			label = SelectedStyle.Render(name)
+		}
+		row := cursor + label
+		if p.Host != "" {
+			row += " " + Muted(p.Host)
+		}
+		if p.Owner != "" {
+			row += " " + TagStyle.Render(p.Owner)
+		}
+		if name == m.cfg.Profile {
+			row += " " + Muted("(active)")
+		}
+		s += row + "\n"
+	}
+	s += "\n" + HelpStyle.Render("  ↑/↓: move • enter: switch • esc: cancel") + "\n"
+	return s
+}
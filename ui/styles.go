@@ -123,6 +123,10 @@ func Unchecked() string {
 	return UncheckedStyle.Render("○")
 }
 
+func Locked() string {
+	return WarningStyle.Render("🔒")
+}
+
 func Danger(s string) string {
 	return ErrorStyle.Render(s)
 }
@@ -15,7 +15,8 @@ import (
 // This year: "on Nov 17"
 // Last year: "on Jan 31, 2012"
 func HumanizeTime(t time.Time) string {
-	now := time.Now()
+	// Compare calendar days in the location t is displayed in
+	now := time.Now().In(t.Location())
 	diff := now.Sub(t)
 
 	// Future time (shouldn't happen for version creation, but handle gracefully)
@@ -1,10 +1,14 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/maful/hij/auth"
 	"github.com/maful/hij/config"
 	"github.com/maful/hij/github"
 )
@@ -25,19 +29,33 @@ func (m Model) updateToken(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.err = fmt.Errorf("token cannot be empty")
 				return m, nil
 			}
-			m.client = github.NewClient(token)
+			m.client = m.newClient(token)
 			m.loading = true
 			m.loadingMsg = "Validating token..."
 			m.err = nil
 			m.pendingToken = token
+			m.tokenSource = ""
 			m.tokenFromKeychain = false
 			return m, tea.Batch(
 				m.spinner.Tick,
 				m.fetchPackages(),
 			)
-		case "s": // Save token to keychain when prompted
+		case "tab": // Sign in with the browser instead of pasting a token
+			if m.loading || m.showSavePrompt {
+				return m, nil
+			}
+			return m.startDeviceLogin()
+		case "esc": // Cancel signing in with the browser
+			if m.deviceCancel != nil {
+				m.deviceCancel()
+				m.deviceCancel = nil
+				m.deviceCode = nil
+				m.loading = false
+				return m, nil
+			}
+		case "s": // Save token to the keychain or token file when prompted
 			if m.showSavePrompt {
-				if err := config.SaveToken(m.pendingToken); err != nil {
+				if err := config.SaveToken(m.cfg.Profile, m.pendingToken); err != nil {
 					m.err = fmt.Errorf("failed to save token: %w", err)
 				}
 				m.showSavePrompt = false
@@ -51,10 +69,17 @@ func (m Model) updateToken(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 		}
+	case deviceCodeMsg:
+		return m.handleDeviceCode(msg)
+	case deviceTokenMsg:
+		return m.handleDeviceToken(msg)
 	case packagesMsg:
 		// Token validated successfully
 		m.loading = false
 		m.packages = msg.packages
+		m.login = msg.login
+		m.owner = msg.owner
+		m.tokenExpires = msg.expires
 		// If token came from manual input (not keychain), offer to save
 		if !m.tokenFromKeychain && m.pendingToken != "" {
 			m.showSavePrompt = true
@@ -72,20 +97,39 @@ func (m Model) updateToken(msg tea.Msg) (tea.Model, tea.Cmd) {
 func (m Model) viewToken() string {
 	s := "\n"
 	s += "  " + LogoStyle.Render(" hij ") + "\n"
-	s += "  " + TitleStyle.Render("GitHub Packages Cleaner") + "\n\n"
+	s += "  " + TitleStyle.Render("GitHub Packages Cleaner") + "\n"
+	if m.cfg.Profile != "" {
+		s += "  " + Muted("Profile "+m.cfg.Profile+" on "+m.cfg.Host) + "\n"
+	}
+	s += "\n"
 
 	// Show save prompt if needed
 	if m.showSavePrompt {
-		s += "  " + Success("✓ Token validated!") + "\n\n"
-		s += "  " + SubtitleStyle.Render("Save token to keychain for future use?") + "\n\n"
+		s += "  " + Success("✓ Token validated!") + "\n"
+		if m.tokenSource != "" {
+			s += "  " + Muted("Read from "+m.tokenSource) + "\n"
+		}
+		s += "\n"
+		s += "  " + SubtitleStyle.Render("Save token to the "+m.tokenStore+" for future use?") + "\n\n"
 		s += "\n" + HelpStyle.Render("  s: save • n: skip") + "\n"
 		return s
 	}
 
 	s += "  " + SubtitleStyle.Render("Enter your GitHub Personal Access Token") + "\n"
 	s += "  " + Muted("Required scopes: read:packages, delete:packages") + "\n"
-	s += "  " + Muted("Tip: Set HIJ_GITHUB_TOKEN env var to skip this step") + "\n\n"
+	s += "  " + Muted("Tip: Set HIJ_GITHUB_TOKEN or GH_TOKEN, or log in with gh, to skip this step") + "\n\n"
 
+	if m.deviceCode != nil {
+		s += "  " + Muted("Open ") + SelectedStyle.Render(m.deviceCode.VerificationURI) + Muted(" and enter the code") + "\n\n"
+		s += "      " + LogoStyle.Render(" "+m.deviceCode.UserCode+" ") + "\n\n"
+		s += "  " + m.spinner.View() + " " + m.loadingMsg + "\n"
+		s += "\n" + HelpStyle.Render("  esc: cancel • ctrl+c: quit") + "\n"
+		return s
+	}
+
+	if m.successMsg != "" {
+		s += "  " + SuccessStyle.Render("✓ "+m.successMsg) + "\n\n"
+	}
 	if m.loading {
 		s += "  " + m.spinner.View() + " " + m.loadingMsg + "\n"
 	} else {
@@ -93,31 +137,168 @@ func (m Model) viewToken() string {
 	}
 
 	if m.err != nil {
-		s += "\n  " + ErrorStyle.Render("✗ "+m.err.Error()) + "\n"
+		s += viewError(m.err)
+		if m.tokenSource != "" {
+			s += "  " + Muted("The token was read from "+m.tokenSource) + "\n"
+		}
 	}
 
-	s += "\n" + HelpStyle.Render("  enter: submit • ctrl+c: quit") + "\n"
+	s += "\n" + HelpStyle.Render("  enter: submit • tab: log in with browser • ctrl+c: quit") + "\n"
 	s += "\n" + Muted("  built with love by @mafulprayoga") + "\n"
 
 	return s
 }
 
+// updateForgetToken handles the answer to forgetting the saved token
+func (m Model) updateForgetToken(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.String() {
+	case "y":
+		m.confirmForget = false
+		return m.forgetToken()
+	case "n", "esc":
+		m.confirmForget = false
+	}
+	return m, nil
+}
+
+// forgetToken removes the saved token of the active profile and
+// starts over, asking for a token unless another source still has one
+func (m Model) forgetToken() (tea.Model, tea.Cmd) {
+	if err := config.DeleteToken(m.cfg.Profile); err != nil {
+		if errors.Is(err, config.ErrTokenNotFound) && m.tokenSource != "" {
+			err = fmt.Errorf("%w, the token comes from %s", err, m.tokenSource)
+		}
+		m.err = err
+		return m, nil
+	}
+	next := newModel(m.cfg, nil)
+	next.height = m.height
+	next.successMsg = "Removed the saved token from the " + m.tokenStore
+	return next, next.Init()
+}
+
+// deviceCodeMsg carries the code to show while signing in with the browser
+type deviceCodeMsg struct {
+	flow *auth.DeviceFlow
+	code *auth.DeviceCode
+	err  error
+}
+
+// deviceTokenMsg is sent when the device flow ends
+type deviceTokenMsg struct {
+	token string
+	err   error
+}
+
+// startDeviceLogin requests a code for signing in with the OAuth device flow
+func (m Model) startDeviceLogin() (tea.Model, tea.Cmd) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.deviceCancel = cancel
+	m.err = nil
+	m.loading = true
+	m.loadingMsg = "Requesting a login code..."
+	flow := auth.NewDeviceFlow(m.cfg)
+	return m, tea.Batch(m.spinner.Tick, func() tea.Msg {
+		code, err := flow.Start(ctx)
+		return deviceCodeMsg{flow: flow, code: code, err: err}
+	})
+}
+
+func (m Model) handleDeviceCode(msg deviceCodeMsg) (tea.Model, tea.Cmd) {
+	if m.deviceCancel == nil {
+		return m, nil // cancelled
+	}
+	if msg.err != nil {
+		m.deviceCancel()
+		m.deviceCancel = nil
+		m.loading = false
+		m.err = msg.err
+		return m, nil
+	}
+	m.deviceCode = msg.code
+	m.loadingMsg = "Waiting for authorization..."
+	ctx, cancel := context.WithCancel(context.Background())
+	m.deviceCancel = cancel
+	return m, func() tea.Msg {
+		token, err := msg.flow.Poll(ctx, msg.code)
+		return deviceTokenMsg{token: token, err: err}
+	}
+}
+
+// handleDeviceToken saves the token the device flow returned and loads the
+// packages with it
+func (m Model) handleDeviceToken(msg deviceTokenMsg) (tea.Model, tea.Cmd) {
+	if m.deviceCancel == nil {
+		return m, nil // cancelled
+	}
+	m.deviceCancel()
+	m.deviceCancel = nil
+	m.deviceCode = nil
+	if msg.err != nil {
+		m.loading = false
+		m.err = msg.err
+		return m, nil
+	}
+	if err := config.SaveToken(m.cfg.Profile, msg.token); err != nil {
+		m.err = fmt.Errorf("failed to save token: %w", err)
+	}
+	m.client = m.newClient(msg.token)
+	m.pendingToken = msg.token
+	m.tokenFromKeychain = true // saved already, do not offer to save it
+	m.tokenSource = m.tokenStore
+	m.loadingMsg = "Validating token..."
+	return m, tea.Batch(m.spinner.Tick, m.fetchPackages())
+}
+
 func (m Model) fetchPackages() tea.Cmd {
 	return func() tea.Msg {
-		packages, err := m.client.ListPackages("container")
+		login, err := m.client.Actor()
+		if err != nil {
+			return errMsg{err}
+		}
+		owner := login
+		if org := m.client.Org(); org != "" {
+			owner = org
+		}
+
+		packages, err := m.client.ListPackages(m.cfg.PackageType)
 		if err != nil {
 			return errMsg{err}
 		}
 
 		// Fetch version counts for each package since the API doesn't return them
 		for i, pkg := range packages {
-			versions, err := m.client.ListPackageVersions("container", pkg.Name)
+			versions, err := m.client.ListPackageVersions(m.cfg.PackageType, pkg.Name)
 			if err != nil {
 				continue // Skip on error, leave count as 0
 			}
 			packages[i].VersionCount = len(versions)
 		}
 
-		return packagesMsg{packages}
+		return packagesMsg{packages: packages, login: login, owner: owner, expires: m.client.TokenExpiration()}
+	}
+}
+
+// viewTokenExpiry warns when the token expires within github.ExpiryWarning
+func (m Model) viewTokenExpiry(now time.Time) string {
+	if m.tokenExpires == nil {
+		return ""
+	}
+	left := m.tokenExpires.Sub(now)
+	if left > github.ExpiryWarning {
+		return ""
+	}
+	when := fmt.Sprintf("in %d hours", int(left.Hours()))
+	switch {
+	case left <= 0:
+		when = "now"
+	case left >= 48*time.Hour:
+		when = fmt.Sprintf("in %d days", int(left.Hours()/24))
 	}
+	return "  " + WarningStyle.Render(fmt.Sprintf("⚠ Your token expires %s (%s)", when, m.tokenExpires.Local().Format("2006-01-02 15:04"))) +
+		" " + Muted("renew it and run hij auth rotate") + "\n\n"
 }
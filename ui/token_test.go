@@ -0,0 +1,139 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/maful/hij/auth"
+	"github.com/maful/hij/config"
+	"github.com/maful/hij/github"
+	"github.com/zalando/go-keyring"
+)
+
+func TestModel_DeviceLogin(t *testing.T) {
+	m := Model{screen: ScreenToken, tokenInput: textinput.New(), cfg: config.Default()}
+
+	model, cmd := m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = model.(Model)
+	if cmd == nil || !m.loading || m.deviceCancel == nil {
+		t.Fatal("tab should start the device flow")
+	}
+
+	code := &auth.DeviceCode{UserCode: "ABCD-1234", VerificationURI: "https://github.com/login/device", Interval: 5}
+	model, cmd = m.Update(deviceCodeMsg{flow: &auth.DeviceFlow{}, code: code})
+	m = model.(Model)
+	if cmd == nil {
+		t.Fatal("the code should start polling for the token")
+	}
+	view := m.viewToken()
+	if !strings.Contains(view, "ABCD-1234") || !strings.Contains(view, "https://github.com/login/device") {
+		t.Errorf("view should show the code and where to enter it:\n%s", view)
+	}
+
+	model, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	m = model.(Model)
+	if m.deviceCode != nil || m.deviceCancel != nil || m.loading {
+		t.Error("esc should cancel the device flow")
+	}
+
+	// The poll that was cancelled reports back afterwards
+	model, _ = m.Update(deviceTokenMsg{err: errors.New("context canceled")})
+	m = model.(Model)
+	if m.err != nil {
+		t.Errorf("a cancelled device flow should not report an error, got %v", m.err)
+	}
+}
+
+func TestModel_DeviceLogin_StartError(t *testing.T) {
+	m := Model{screen: ScreenToken, tokenInput: textinput.New(), cfg: config.Default()}
+	model, _ := m.Update(tea.KeyMsg{Type: tea.KeyTab})
+	m = model.(Model)
+
+	model, _ = m.Update(deviceCodeMsg{err: errors.New("oauth.client_id is not set")})
+	m = model.(Model)
+	if m.err == nil || m.loading || m.deviceCancel != nil {
+		t.Errorf("err = %v, loading = %v, want the error shown and the flow stopped", m.err, m.loading)
+	}
+}
+
+func TestModel_ForgetToken(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_STATE_HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("GH_CONFIG_DIR", dir)
+	t.Setenv("PATH", "") // no gh CLI
+	for _, name := range []string{"HIJ_GITHUB_TOKEN", "GH_TOKEN", "GITHUB_TOKEN"} {
+		t.Setenv(name, "")
+	}
+	keyring.MockInit()
+	if err := config.SaveToken("", "ghp_saved"); err != nil {
+		t.Fatal(err)
+	}
+
+	m := newPackagesModel()
+	m.cfg = config.Default()
+	m.tokenSource = config.SourceKeychain
+	m = pressPackageKey(m, "L")
+	if !m.confirmForget || !strings.Contains(m.viewPackages(), "Forget the token") {
+		t.Fatal("L should ask before forgetting the token")
+	}
+	m = pressPackageKey(m, "n")
+	if m.confirmForget {
+		t.Fatal("n should cancel")
+	}
+
+	m = pressPackageKey(m, "L")
+	m = pressPackageKey(m, "y")
+	if m.screen != ScreenToken || m.client != nil || !strings.Contains(m.viewToken(), "Removed the saved token") {
+		t.Errorf("forgetting should ask for a new token, screen = %v", m.screen)
+	}
+	if _, err := config.SavedToken(""); !errors.Is(err, config.ErrTokenNotFound) {
+		t.Errorf("SavedToken() error = %v, want the token removed", err)
+	}
+
+	// Nothing left to forget
+	m = newPackagesModel()
+	m.tokenSource = "GH_TOKEN"
+	m = pressPackageKey(m, "L")
+	m = pressPackageKey(m, "y")
+	if m.err == nil || !strings.Contains(m.err.Error(), "GH_TOKEN") {
+		t.Errorf("err = %v, want where the token comes from", m.err)
+	}
+}
+
+func TestModel_TokenExpiry(t *testing.T) {
+	now := time.Now()
+	soon := now.Add(3*24*time.Hour + time.Hour)
+	m := newPackagesModel()
+	model, _ := m.Update(packagesMsg{packages: m.packages, login: "octo", owner: "octo", expires: &soon})
+	m = model.(Model)
+	if view := m.viewPackages(); !strings.Contains(view, "Your token expires in 3 days") {
+		t.Errorf("view should warn about the expiring token:\n%s", view)
+	}
+
+	later := now.Add(60 * 24 * time.Hour)
+	m.tokenExpires = &later
+	if warning := m.viewTokenExpiry(now); warning != "" {
+		t.Errorf("viewTokenExpiry() = %q, want no warning for a distant expiry", warning)
+	}
+	m.tokenExpires = nil
+	if warning := m.viewTokenExpiry(now); warning != "" {
+		t.Errorf("viewTokenExpiry() = %q, want no warning for a token without expiry", warning)
+	}
+}
+
+func TestViewError_SSO(t *testing.T) {
+	err := fmt.Errorf("failed to list packages: %w", &github.SSOError{Org: "acme", URL: "https://github.com/orgs/acme/sso?authorization_request=AbC"})
+	view := viewError(err)
+	if !strings.Contains(view, "the acme organization") || !strings.Contains(view, "https://github.com/orgs/acme/sso?authorization_request=AbC") {
+		t.Errorf("view should show the organization and where to authorize the token:\n%s", view)
+	}
+	if view := viewError(errors.New("boom")); !strings.Contains(view, "✗ boom") {
+		t.Errorf("viewError() = %q", view)
+	}
+}
@@ -1,44 +1,32 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
-	"regexp"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/maful/hij/filter"
 	"github.com/maful/hij/github"
+	"github.com/maful/hij/lifecycle"
+	"github.com/maful/hij/policy"
+	"github.com/maful/hij/refs"
 )
 
 func (m Model) updateVersions(msg tea.Msg) (tea.Model, tea.Cmd) {
-	// If filter is active, handle text input
 	if m.filterActive {
-		switch msg := msg.(type) {
-		case tea.KeyMsg:
-			switch msg.String() {
-			case "enter":
-				m.filterValue = m.filterInput.Value()
-				m.filterActive = false
-				m.filterInput.Blur()
-				m.applyFilter()
-				return m, nil
-			case "esc":
-				m.filterActive = false
-				m.filterInput.Blur()
-				return m, nil
-			}
-		}
-		var cmd tea.Cmd
-		m.filterInput, cmd = m.filterInput.Update(msg)
-		return m, cmd
+		return m.updateFilterInput(msg)
 	}
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		// Clear success message on any key press
+		// Clear transient messages on any key press
 		m.successMsg = ""
+		m.warnMsg = ""
 
 		switch msg.String() {
 		case "up", "k":
@@ -51,22 +39,38 @@ func (m Model) updateVersions(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case " ": // Space to toggle selection
 			if len(m.filteredVersions) > 0 {
-				idx := m.filteredVersions[m.versionCursor].ID
-				if _, ok := m.selectedVersions[idx]; ok {
-					delete(m.selectedVersions, idx)
+				v := m.filteredVersions[m.versionCursor]
+				if _, ok := m.selectedVersions[v.ID]; ok {
+					delete(m.selectedVersions, v.ID)
+				} else if err := m.protection(v); err != nil {
+					m.warnMsg = "Protected: " + err.Error()
+				} else if used := m.inUse(v); len(used) > 0 && !m.allowInUse {
+					m.warnMsg = fmt.Sprintf("In use by %s. Press O to override", used[0])
 				} else {
-					m.selectedVersions[idx] = struct{}{}
+					m.selectedVersions[v.ID] = struct{}{}
 				}
 			}
-		case "a": // Select all (filtered)
-			for _, v := range m.filteredVersions {
-				m.selectedVersions[v.ID] = struct{}{}
+		case "a": // Add shown versions to the selection
+			m.selectShown(selectAdd)
+		case "r": // Remove shown versions from the selection
+			m.selectShown(selectRemove)
+		case "i": // Keep only the shown versions selected
+			m.selectShown(selectIntersect)
+		case "v": // Invert the selection of shown versions
+			m.selectShown(selectInvert)
+		case "O": // Toggle override of in-use protection
+			m.allowInUse = !m.allowInUse
+			if !m.allowInUse {
+				for _, v := range m.versions {
+					if !m.selectable(v) {
+						delete(m.selectedVersions, v.ID)
+					}
+				}
 			}
 		case "n": // Deselect all
 			m.selectedVersions = make(map[int]struct{})
 		case "/", ":": // Activate filter
-			m.filterActive = true
-			m.filterInput.Focus()
+			m.openFilterInput()
 			return m, nil
 		case "c": // Clear filter
 			m.resetFilter()
@@ -79,8 +83,11 @@ func (m Model) updateVersions(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.sortVersions(m.filteredVersions)
 		case "d": // Delete selected
 			if len(m.selectedVersions) > 0 {
-				m.screen = ScreenConfirm
-				m.confirmYes = false
+				if err := m.deleteBlock(); err != nil {
+					m.err = err
+					return m, nil
+				}
+				m.enterConfirm()
 			}
 		case "esc":
 			m.screen = ScreenPackages
@@ -93,69 +100,248 @@ func (m Model) updateVersions(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-// resetFilter clears the filter and restores all versions
+// resetFilter clears the filter and shows all versions again. The selection
+// is kept.
 func (m *Model) resetFilter() {
 	m.filterValue = ""
 	m.filterInput.SetValue("")
 	m.filteredVersions = m.versions
-	m.selectedVersions = make(map[int]struct{})
 	m.versionCursor = 0
 }
 
+// applyFilter narrows the shown versions to those matching the filter. It
+// never changes the selection, see selectShown.
 func (m *Model) applyFilter() {
-	filter := strings.TrimSpace(m.filterValue)
-	if filter == "" {
+	expr := strings.TrimSpace(m.filterValue)
+	if expr == "" {
 		m.filteredVersions = m.versions
 		return
 	}
 
-	// Reset filtered list and selections
 	m.filteredVersions = nil
-	m.selectedVersions = make(map[int]struct{})
 	m.versionCursor = 0
 
-	// Parse :older N format (colon is optional since : key activates filter mode)
-	olderRegex := regexp.MustCompile(`^:?older\s+(\d+)$`)
-	if matches := olderRegex.FindStringSubmatch(filter); len(matches) == 2 {
-		days, _ := strconv.Atoi(matches[1])
-		cutoff := time.Now().AddDate(0, 0, -days)
-		for _, v := range m.versions {
-			if v.CreatedAt.Before(cutoff) {
-				m.filteredVersions = append(m.filteredVersions, v)
+	parsed, err := filter.ParseWith(expr, m.filterOptions())
+	if err != nil {
+		// If the filter cannot be parsed, show all versions
+		m.warnMsg = err.Error()
+		m.filteredVersions = m.versions
+		m.sortVersions(m.filteredVersions)
+		return
+	}
+
+	m.filteredVersions = parsed.Apply(m.versions)
+	if ignored := parsed.Ignored(m.versions); len(ignored) > 0 {
+		m.warnMsg = fmt.Sprintf("%d version(s) without a semver tag left untouched", len(ignored))
+	}
+}
+
+// fetchLifecycle starts looking up the pull requests and branches named by
+// the tags of the selected package when the applied filter needs them
+func (m *Model) fetchLifecycle() tea.Cmd {
+	parsed, err := filter.ParseWith(m.filterValue, m.filterOptions())
+	if err != nil || !parsed.NeedsLifecycle() || m.lifecycle != nil {
+		return nil
+	}
+	if m.selectedPkg.Repository == nil {
+		m.warnMsg = "pr-closed and branch-deleted need a package linked to a repository"
+		return nil
+	}
+	patterns, err := lifecycle.CompilePatterns(m.cfg.Lifecycle)
+	if err != nil {
+		m.err = fmt.Errorf("lifecycle: %w", err)
+		return nil
+	}
+
+	m.loading = true
+	m.loadingMsg = "Checking pull requests and branches..."
+	client, repo, versions := m.client, m.selectedPkg.Repository.FullName, m.versions
+	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+		status, err := lifecycle.Load(client, repo, patterns, versions)
+		return lifecycleMsg{status: status, err: err}
+	})
+}
+
+// selectOp combines the shown versions with the current selection
+type selectOp int
+
+const (
+	selectAdd       selectOp = iota // add shown versions
+	selectRemove                    // remove shown versions
+	selectIntersect                 // drop selected versions that are not shown
+	selectInvert                    // toggle shown versions
+)
+
+// selectShown applies op to the versions shown by the current filter, so a
+// precise selection can be built up over several filters. Versions that are
+// protected or in use are never added.
+func (m *Model) selectShown(op selectOp) {
+	switch op {
+	case selectAdd:
+		for _, v := range m.filteredVersions {
+			if m.selectable(v) {
+				m.selectedVersions[v.ID] = struct{}{}
+			}
+		}
+	case selectRemove:
+		for _, v := range m.filteredVersions {
+			delete(m.selectedVersions, v.ID)
+		}
+	case selectIntersect:
+		shown := make(map[int]struct{}, len(m.filteredVersions))
+		for _, v := range m.filteredVersions {
+			shown[v.ID] = struct{}{}
+		}
+		for id := range m.selectedVersions {
+			if _, ok := shown[id]; !ok {
+				delete(m.selectedVersions, id)
+			}
+		}
+	case selectInvert:
+		for _, v := range m.filteredVersions {
+			if _, ok := m.selectedVersions[v.ID]; ok {
+				delete(m.selectedVersions, v.ID)
+			} else if m.selectable(v) {
 				m.selectedVersions[v.ID] = struct{}{}
 			}
 		}
-		return
 	}
+}
 
-	// Parse :before DATE or :before DATETIME format (colon is optional)
-	beforeRegex := regexp.MustCompile(`^:?before\s+(.+)$`)
-	if matches := beforeRegex.FindStringSubmatch(filter); len(matches) == 2 {
-		dateStr := matches[1]
-		var cutoff time.Time
-		var err error
+// hiddenSelected counts selected versions hidden by the current filter
+func (m Model) hiddenSelected() int {
+	shown := 0
+	for _, v := range m.filteredVersions {
+		if _, ok := m.selectedVersions[v.ID]; ok {
+			shown++
+		}
+	}
+	return len(m.selectedVersions) - shown
+}
+
+// filterOptions returns how filters typed in the TUI are parsed, using the
+// configured timezone and timestamp
+func (m Model) filterOptions() filter.Options {
+	loc, err := m.cfg.Location()
+	if err != nil {
+		loc = time.Local
+	}
+	field, _ := filter.ParseTimeField(m.cfg.Filters.Timestamp)
+	opts := filter.Options{Now: time.Now().In(loc), Field: field}
+	if m.lifecycle != nil {
+		opts.Lifecycle = m.lifecycle
+	}
+	return opts
+}
 
-		// Try datetime format first
-		cutoff, err = time.Parse("2006-01-02T15:04", dateStr)
-		if err != nil {
-			// Try date-only format
-			cutoff, err = time.Parse("2006-01-02", dateStr)
+// previewMatches describes the versions matched by the filter being typed,
+// naming the first few
+func previewMatches(matches []github.PackageVersion, total int) string {
+	s := fmt.Sprintf("%d of %d versions match", len(matches), total)
+	const maxPreview = 3
+	var names []string
+	for i, v := range matches {
+		if i == maxPreview {
+			names = append(names, "…")
+			break
 		}
+		if tags := v.Tags(); len(tags) > 0 {
+			names = append(names, tags[0])
+		} else {
+			names = append(names, v.Name)
+		}
+	}
+	if len(names) > 0 {
+		s += ": " + strings.Join(names, ", ")
+	}
+	return s
+}
 
-		if err == nil {
-			for _, v := range m.versions {
-				if v.CreatedAt.Before(cutoff) {
-					m.filteredVersions = append(m.filteredVersions, v)
-					m.selectedVersions[v.ID] = struct{}{}
-				}
-			}
+// filterExcerpt is how many characters of the expression are shown on each
+// side of a parse error
+const filterExcerpt = 30
+
+// viewFilterError renders a parse error of expr with a caret under the
+// offending position. The input scrolls, so the part of expr around the
+// error is printed again for the caret to point into.
+func viewFilterError(err error, expr string) string {
+	if err == nil {
+		return ""
+	}
+	var perr *filter.ParseError
+	if errors.As(err, &perr) {
+		pos := min(max(perr.Pos, 0), len(expr))
+		for pos > 0 && pos < len(expr) && !utf8.RuneStart(expr[pos]) {
+			pos--
 		}
-		return
+		before, after := []rune(expr[:pos]), []rune(expr[pos:])
+		if len(before) > filterExcerpt {
+			before = append([]rune("…"), before[len(before)-filterExcerpt:]...)
+		}
+		if len(after) > filterExcerpt {
+			after = append(after[:filterExcerpt:filterExcerpt], '…')
+		}
+		return "  " + Muted(string(before)+string(after)) + "\n" +
+			strings.Repeat(" ", 2+lipgloss.Width(string(before))) + ErrorStyle.Render("^") + "\n" +
+			"  " + ErrorStyle.Render("✗ "+perr.Msg) + "\n"
 	}
+	return "  " + ErrorStyle.Render("✗ "+err.Error()) + "\n"
+}
 
-	// If no filter pattern matched, show all versions
-	m.filteredVersions = m.versions
-	m.sortVersions(m.filteredVersions)
+// referencesBlock explains why nothing may be deleted yet: versions in use
+// by deployment manifests are only known once the scan has succeeded
+func (m Model) referencesBlock() error {
+	if m.scanningRefs {
+		return fmt.Errorf("still scanning deployment manifests for images in use, try again in a moment")
+	}
+	if m.refsErr != nil {
+		return fmt.Errorf("scanning deployment manifests failed, so images in use cannot be protected: %w", m.refsErr)
+	}
+	return nil
+}
+
+// deleteBlock explains why none of the selected versions may be deleted:
+// those in use or backing releases and deployments are not known yet
+func (m Model) deleteBlock() error {
+	if err := m.referencesBlock(); err != nil {
+		return err
+	}
+	if m.releasesErr != nil {
+		return fmt.Errorf("looking up releases and deployments failed, so the versions backing them cannot be protected: %w", m.releasesErr)
+	}
+	return nil
+}
+
+// inUse returns the local deployment manifests referencing v
+func (m Model) inUse(v github.PackageVersion) []refs.Reference {
+	if m.references == nil || m.selectedPkg == nil {
+		return nil
+	}
+	return m.references.Lookup(m.owner, m.selectedPkg.Name, v)
+}
+
+// selectable reports whether v may be selected for deletion
+func (m Model) selectable(v github.PackageVersion) bool {
+	return m.blockReason(v) == nil
+}
+
+// protection explains why v can never be deleted, or returns nil
+func (m Model) protection(v github.PackageVersion) error {
+	if err := policy.CheckTags(m.protectedTags, v); err != nil {
+		return err
+	}
+	return m.releases.Check(v)
+}
+
+// blockReason explains why v must not be deleted, or returns nil
+func (m Model) blockReason(v github.PackageVersion) error {
+	if err := m.protection(v); err != nil {
+		return err
+	}
+	if used := m.inUse(v); len(used) > 0 && !m.allowInUse {
+		return fmt.Errorf("in use by %s", used[0])
+	}
+	return nil
 }
 
 func (m *Model) sortVersions(versions []github.PackageVersion) {
@@ -184,7 +370,12 @@ func (m Model) viewVersions() string {
 
 	// Filter input
 	if m.filterActive {
-		s += FocusedInputStyle.Render(m.filterInput.View()) + "\n\n"
+		s += FocusedInputStyle.Render(m.filterInput.View()) + "\n"
+		s += viewFilterError(m.filterErr, m.filterInput.Value())
+		if m.filterPreviewed {
+			s += "  " + Muted(previewMatches(m.filterMatches, len(m.versions))) + "\n"
+		}
+		s += HelpStyle.Render("  enter: apply • tab: complete • ctrl+n/p: other completions • ↑/↓: history • esc: cancel") + "\n\n  "
 	} else if m.filterValue != "" {
 		s += "  " + Muted("Filter: ") + TagStyle.Render(m.filterValue) + "  "
 	} else {
@@ -208,23 +399,10 @@ func (m Model) viewVersions() string {
 		return s
 	}
 
-	// Version list (show max 15)
-	start := 0
-	end := len(m.filteredVersions)
-	maxVisible := 15
-
-	if len(m.filteredVersions) > maxVisible {
-		start = m.versionCursor - maxVisible/2
-		if start < 0 {
-			start = 0
-		}
-		end = start + maxVisible
-		if end > len(m.filteredVersions) {
-			end = len(m.filteredVersions)
-			start = end - maxVisible
-		}
-	}
+	// Version list, scrolled to keep the cursor visible
+	start, end := scrollWindow(m.versionCursor, len(m.filteredVersions), m.listHeight())
 
+	opts := m.filterOptions()
 	for i := start; i < end; i++ {
 		v := m.filteredVersions[i]
 		cursor := "  "
@@ -243,13 +421,17 @@ func (m Model) viewVersions() string {
 		if len(name) > 12 {
 			name = name[:12] + "…"
 		}
+		if m.protection(v) != nil {
+			checkbox = Locked()
+		}
 
 		// Tags
 		tags := v.TagsString()
 
-		// Age
-		ageStr := HumanizeTime(v.CreatedAt)
-		if time.Since(v.CreatedAt) > 30*24*time.Hour {
+		// Age of the timestamp filters compare against
+		ts := opts.Field.Of(v)
+		ageStr := HumanizeTime(ts.In(opts.Now.Location()))
+		if time.Since(ts) > m.cfg.OldAfter() {
 			ageStr = OldVersionStyle.Render(ageStr)
 		} else {
 			ageStr = DateStyle.Render(ageStr)
@@ -257,17 +439,57 @@ func (m Model) viewVersions() string {
 
 		// Format the row
 		row := fmt.Sprintf("%s%s %s  %s  %s", cursor, checkbox, name, TagStyle.Render(tags), ageStr)
+		if len(m.inUse(v)) > 0 {
+			row += "  " + WarningStyle.Render("● in use")
+		}
+		if err := m.releases.Check(v); err != nil {
+			row += "  " + Muted(err.Error())
+		}
 		s += row + "\n"
 	}
 
+	// Where the version under the cursor is deployed
+	if used := m.inUse(m.filteredVersions[m.versionCursor]); len(used) > 0 {
+		s += "\n  " + Muted("Used by: ") + used[0].String()
+		if len(used) > 1 {
+			s += Muted(fmt.Sprintf(" and %d more", len(used)-1))
+		}
+		s += "\n"
+	}
+
 	// Selection count
-	s += "\n  " + Muted(fmt.Sprintf("Selected: %d of %d", len(m.selectedVersions), len(m.filteredVersions))) + "\n"
+	count := fmt.Sprintf("Selected: %d of %d", len(m.selectedVersions), len(m.versions))
+	if hidden := m.hiddenSelected(); hidden > 0 {
+		count += fmt.Sprintf(" (%d hidden by filter)", hidden)
+	}
+	s += "\n  " + Muted(count) + "\n"
+
+	if m.allowInUse {
+		s += "  " + WarningStyle.Render("⚠ In-use protection overridden") + "\n"
+	}
+	if m.warnMsg != "" {
+		s += "\n  " + WarningStyle.Render("⚠ "+m.warnMsg) + "\n"
+	}
+	if m.scanningRefs {
+		s += "\n  " + Muted("Scanning deployment manifests, deleting waits until it finishes") + "\n"
+	} else if m.refsErr != nil {
+		s += "\n  " + ErrorStyle.Render("✗ Deleting is blocked: scanning deployment manifests failed: "+m.refsErr.Error()) + "\n"
+	}
+	if m.releasesErr != nil {
+		s += "\n  " + WarningStyle.Render("⚠ Deleting is blocked: failed to load releases and deployments: "+m.releasesErr.Error()) + "\n"
+		s += "  " + Muted("Turn protect.releases and protect.deployments off for tokens without access to the repository") + "\n"
+	}
 
 	if m.err != nil {
-		s += "\n  " + ErrorStyle.Render("✗ "+m.err.Error()) + "\n"
+		s += viewError(m.err)
 	}
 
-	s += "\n" + HelpStyle.Render("  space: toggle • a: all • n: none • /: filter • s: sort • c: clear • d: delete • esc: back") + "\n"
+	help := "  space: toggle • /: filter • c: clear filter • s: sort • d: delete • esc: back"
+	if len(m.references) > 0 {
+		help += " • O: override in-use"
+	}
+	s += "\n" + HelpStyle.Render(help) + "\n"
+	s += HelpStyle.Render("  shown → a: add • r: remove • i: intersect • v: invert • n: select none") + "\n"
 
 	return s
 }